@@ -38,6 +38,18 @@ var (
 		"debug", false, "use debug log level")
 
 	version = flag.Bool("version", false, "displays olm version")
+
+	installPlanHistoryLimit = flag.Int(
+		"installPlanHistoryLimit", -1, "number of Complete InstallPlans to keep per subscription, negative to keep all")
+
+	approvalWebhookAddr = flag.String(
+		"approvalWebhookAddr", "", "address to serve the InstallPlan approval webhook on, leave empty to disable it")
+
+	tlsCertPath = flag.String(
+		"tlsCert", "", "path to the approval webhook's serving certificate")
+
+	tlsKeyPath = flag.String(
+		"tlsKey", "", "path to the approval webhook's serving key")
 )
 
 func main() {
@@ -64,8 +76,17 @@ func main() {
 	})
 	go http.ListenAndServe(":8080", nil)
 
+	// Serve the InstallPlan approval webhook.
+	if *approvalWebhookAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/approve-installplan", catalog.NewApprovalWebhook())
+		go func() {
+			log.Fatal(http.ListenAndServeTLS(*approvalWebhookAddr, *tlsCertPath, *tlsKeyPath, mux))
+		}()
+	}
+
 	// Create a new instance of the operator.
-	catalogOperator, err := catalog.NewOperator(*kubeConfigPath, *wakeupInterval, *catalogNamespace, strings.Split(*watchedNamespaces, ",")...)
+	catalogOperator, err := catalog.NewOperator(*kubeConfigPath, *wakeupInterval, *catalogNamespace, *installPlanHistoryLimit, *approvalWebhookAddr != "", strings.Split(*watchedNamespaces, ",")...)
 	if err != nil {
 		log.Panicf("error configuring operator: %s", err.Error())
 	}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/signals"
+	olmversion "github.com/operator-framework/operator-lifecycle-manager/pkg/version"
+)
+
+const (
+	defaultDirectory = "/registry"
+	defaultPort      = 50051
+)
+
+// config flags defined globally so that they appear on the test binary as well
+var (
+	directory = flag.String(
+		"directory", defaultDirectory, "directory of catalog resources (CRDs, CSVs and package manifests) to serve")
+
+	port = flag.Int(
+		"port", defaultPort, "port to serve the registry on")
+
+	debug = flag.Bool(
+		"debug", false, "use debug log level")
+
+	version = flag.Bool("version", false, "displays olm version")
+)
+
+func main() {
+	stopCh := signals.SetupSignalHandler()
+
+	// Parse the command-line flags.
+	flag.Parse()
+
+	// Check if version flag was set
+	if *version {
+		fmt.Print(olmversion.String())
+
+		// Exit early
+		os.Exit(0)
+	}
+
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	// Load the catalog to serve.
+	catalog, err := registry.NewInMemoryFromDirectory(*directory)
+	if err != nil {
+		log.Fatalf("error loading catalog from %s: %s", *directory, err)
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Fatalf("failed to listen on port %d: %s", *port, err)
+	}
+
+	server := grpc.NewServer()
+	registry.RegisterRegistryServer(server, catalog)
+	go func() {
+		<-stopCh
+		server.GracefulStop()
+	}()
+
+	log.Infof("serving catalog %s on port %d", *directory, *port)
+	if err := server.Serve(lis); err != nil {
+		log.Fatalf("error serving registry: %s", err)
+	}
+}
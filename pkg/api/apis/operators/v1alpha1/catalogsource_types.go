@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -11,11 +12,35 @@ const (
 	CatalogSourceKind          = "CatalogSource"
 )
 
+const (
+	// SourceTypeInternal catalog sources are loaded from a ConfigMap in the catalog source's namespace
+	SourceTypeInternal = "internal"
+	// SourceTypeGRPC catalog sources are queried from a registry server at the catalog source's address
+	SourceTypeGRPC = "grpc"
+	// SourceTypeHTTP catalog sources are polled from a gzipped tar archive of a catalog directory at the catalog
+	// source's URL
+	SourceTypeHTTP = "http"
+)
+
 type CatalogSourceSpec struct {
 	SourceType string   `json:"sourceType"`
 	ConfigMap  string   `json:"configMap,omitempty"`
 	Secrets    []string `json:"secrets,omitempty"`
 
+	// Address is the host:port of the registry server for grpc catalog sources.
+	Address string `json:"address,omitempty"`
+
+	// URL is the location of the catalog archive for http catalog sources.
+	URL string `json:"url,omitempty"`
+
+	// PollInterval is how often http catalog sources check their URL for a new archive.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// Priority orders catalog sources during resolution. Sources with a higher priority are searched first, after
+	// the catalog source the InstallPlan or Subscription names.
+	Priority int `json:"priority,omitempty"`
+
 	// Metadata
 	DisplayName string `json:"displayName,omitempty"`
 	Description string `json:"description,omitempty"`
@@ -26,7 +51,115 @@ type CatalogSourceSpec struct {
 type CatalogSourceStatus struct {
 	ConfigMapResource *ConfigMapResourceReference `json:"configMapReference,omitempty"`
 	LastSync          metav1.Time                 `json:"lastSync,omitempty"`
+
+	// RegistryService describes the registry server contents were last loaded from.
+	// +optional
+	RegistryService *RegistryServiceStatus `json:"registryService,omitempty"`
+
+	// Archive describes the catalog archive contents were last loaded from.
+	// +optional
+	Archive *ArchiveStatus `json:"archive,omitempty"`
+
+	// Contents counts what was loaded from ConfigMapResource, RegistryService or Archive.
+	// +optional
+	Contents *CatalogSourceContents `json:"contents,omitempty"`
+
+	Conditions []CatalogSourceCondition `json:"conditions,omitempty"`
+}
+
+// CatalogSourceContents counts the entries of a loaded catalog source.
+type CatalogSourceContents struct {
+	Packages                  int `json:"packages"`
+	ClusterServiceVersions    int `json:"clusterServiceVersions"`
+	CustomResourceDefinitions int `json:"customResourceDefinitions"`
+}
+
+// CatalogSourceConditionType is a category of CatalogSource condition.
+type CatalogSourceConditionType string
+
+const (
+	// CatalogSourceReady is true when the catalog source's current contents are loaded and used for resolution.
+	CatalogSourceReady CatalogSourceConditionType = "Ready"
+	// CatalogSourceLoadFailed is true when the catalog source's current contents couldn't be loaded. Its message is
+	// the load error.
+	CatalogSourceLoadFailed CatalogSourceConditionType = "LoadFailed"
+)
+
+const (
+	CatalogSourceReasonLoaded            ConditionReason = "Loaded"
+	CatalogSourceReasonConfigMapNotFound ConditionReason = "ConfigMapNotFound"
+	CatalogSourceReasonInvalidConfigMap  ConditionReason = "InvalidConfigMap"
+	CatalogSourceReasonNoAddress         ConditionReason = "NoAddress"
+	CatalogSourceReasonRegistryError     ConditionReason = "RegistryError"
+	CatalogSourceReasonArchiveError      ConditionReason = "ArchiveError"
+)
+
+// CatalogSourceCondition represents the latest observation of one aspect of a CatalogSource's state.
+type CatalogSourceCondition struct {
+	Type               CatalogSourceConditionType `json:"type"`
+	Status             corev1.ConditionStatus     `json:"status"` // True, False, or Unknown
+	LastUpdateTime     metav1.Time                `json:"lastUpdateTime,omitempty"`
+	LastTransitionTime metav1.Time                `json:"lastTransitionTime,omitempty"`
+	Reason             ConditionReason            `json:"reason,omitempty"`
+	Message            string                     `json:"message,omitempty"`
+}
+
+// SetCondition adds or updates a condition, using `Type` as merge key
+func (s *CatalogSourceStatus) SetCondition(cond CatalogSourceCondition) CatalogSourceCondition {
+	updated := now()
+	cond.LastUpdateTime = updated
+	cond.LastTransitionTime = updated
+
+	for i, existing := range s.Conditions {
+		if existing.Type != cond.Type {
+			continue
+		}
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		s.Conditions[i] = cond
+		return cond
+	}
+	s.Conditions = append(s.Conditions, cond)
+	return cond
+}
+
+// GetCondition returns the condition of the given type, or an unknown condition if it hasn't been set
+func (s *CatalogSourceStatus) GetCondition(condType CatalogSourceConditionType) CatalogSourceCondition {
+	for _, cond := range s.Conditions {
+		if cond.Type == condType {
+			return cond
+		}
+	}
+	return CatalogSourceCondition{
+		Type:   condType,
+		Status: corev1.ConditionUnknown,
+	}
 }
+
+// RegistryServiceStatus identifies the contents loaded from a registry server.
+type RegistryServiceStatus struct {
+	Address string `json:"address"`
+
+	// Digest is a hash of the served packages and ClusterServiceVersions, used to detect changed contents.
+	Digest string `json:"digest"`
+}
+
+// ArchiveStatus identifies the catalog archive contents were loaded from.
+type ArchiveStatus struct {
+	URL string `json:"url"`
+
+	// ETag is the entity tag the server returned with the archive, sent with the next poll so an unchanged archive
+	// isn't downloaded again.
+	ETag string `json:"etag,omitempty"`
+
+	// Digest is the sha256 digest of the archive.
+	Digest string `json:"digest"`
+
+	// LastPoll is when the URL was last checked for a new archive.
+	LastPoll metav1.Time `json:"lastPoll,omitempty"`
+}
+
 type ConfigMapResourceReference struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
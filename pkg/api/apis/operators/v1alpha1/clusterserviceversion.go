@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -55,3 +57,34 @@ func (c *ClusterServiceVersion) IsObsolete() bool {
 	}
 	return false
 }
+
+// SetSubscriptionConfig records the given SubscriptionConfig in the CSV's annotations, removing it if the config is
+// empty. Returns true if the annotations were changed.
+func (c *ClusterServiceVersion) SetSubscriptionConfig(config SubscriptionConfig) (bool, error) {
+	annotations := c.GetAnnotations()
+	existing, ok := annotations[SubscriptionConfigAnnotationKey]
+
+	if config.IsEmpty() {
+		if !ok {
+			return false, nil
+		}
+		delete(annotations, SubscriptionConfigAnnotationKey)
+		c.SetAnnotations(annotations)
+		return true, nil
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return false, err
+	}
+	if ok && existing == string(raw) {
+		return false, nil
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[SubscriptionConfigAnnotationKey] = string(raw)
+	c.SetAnnotations(annotations)
+	return true, nil
+}
@@ -101,3 +101,56 @@ func TestIsObsolete(t *testing.T) {
 		})
 	}
 }
+
+func TestSetSubscriptionConfig(t *testing.T) {
+	config := SubscriptionConfig{NodeSelector: map[string]string{"node": "infra"}}
+	raw := `{"nodeSelector":{"node":"infra"}}`
+
+	tests := []struct {
+		annotations map[string]string
+		config      SubscriptionConfig
+		changed     bool
+		expected    map[string]string
+		description string
+	}{
+		{
+			annotations: nil,
+			config:      SubscriptionConfig{},
+			changed:     false,
+			expected:    nil,
+			description: "EmptyConfigNoAnnotation",
+		},
+		{
+			annotations: nil,
+			config:      config,
+			changed:     true,
+			expected:    map[string]string{SubscriptionConfigAnnotationKey: raw},
+			description: "AddConfig",
+		},
+		{
+			annotations: map[string]string{SubscriptionConfigAnnotationKey: raw},
+			config:      config,
+			changed:     false,
+			expected:    map[string]string{SubscriptionConfigAnnotationKey: raw},
+			description: "SameConfig",
+		},
+		{
+			annotations: map[string]string{SubscriptionConfigAnnotationKey: raw, "other": "value"},
+			config:      SubscriptionConfig{},
+			changed:     true,
+			expected:    map[string]string{"other": "value"},
+			description: "RemoveConfig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			csv := ClusterServiceVersion{}
+			csv.SetAnnotations(tt.annotations)
+
+			changed, err := csv.SetSubscriptionConfig(tt.config)
+			require.NoError(t, err)
+			require.Equal(t, tt.changed, changed)
+			require.Equal(t, tt.expected, csv.GetAnnotations())
+		})
+	}
+}
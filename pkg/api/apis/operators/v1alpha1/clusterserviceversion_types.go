@@ -122,6 +122,14 @@ type ClusterServiceVersionSpec struct {
 	// +optional
 	Replaces string `json:"replaces,omitempty"`
 
+	// The names of CSVs this one can upgrade from directly, in addition to the one it replaces.
+	// +optional
+	Skips []string `json:"skips,omitempty"`
+
+	// A semver range of CSV versions this one can upgrade from directly, e.g. ">=1.0.0 <1.2.0".
+	// +optional
+	SkipRange string `json:"skipRange,omitempty"`
+
 	// Map of string keys and values that can be used to organize and categorize
 	// (scope and select) objects.
 	// +optional
@@ -193,6 +201,7 @@ const (
 	CSVReasonComponentUnhealthy  ConditionReason = "ComponentUnhealthy"
 	CSVReasonBeingReplaced       ConditionReason = "BeingReplaced"
 	CSVReasonReplaced            ConditionReason = "Replaced"
+	CSVReasonNeedsReinstall      ConditionReason = "NeedsReinstall"
 )
 
 // Conditions appear in the status as a record of state transitions on the ClusterServiceVersion
@@ -28,6 +28,52 @@ type InstallPlanSpec struct {
 	ClusterServiceVersionNames []string `json:"clusterServiceVersionNames"`
 	Approval                   Approval `json:"approval"`
 	Approved                   bool     `json:"approved"`
+	// RollbackOnFailure deletes the resources created by the plan, in reverse order, if it fails to install.
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+	// DryRun compares the resolved plan against the cluster without installing it. The plan waits in
+	// RequiresApproval, refreshing the comparison on every sync, until DryRun is turned off, which clears the
+	// comparison from its steps.
+	DryRun bool `json:"dryRun,omitempty"`
+	// ApprovalPolicy requires the plan to be approved by named approvers through Approvals, instead of by
+	// setting Approved, whatever its Approval mode. Approvals are only honored when the catalog operator serves
+	// the InstallPlan approval webhook.
+	ApprovalPolicy *ApprovalPolicy `json:"approvalPolicy,omitempty"`
+	// Approvals records who approved the plan. Each approval is checked against the requesting user by the
+	// InstallPlan approval webhook.
+	Approvals []InstallPlanApproval `json:"approvals,omitempty"`
+}
+
+// ApprovalPolicy states how many approvals an InstallPlan needs and who may give them.
+type ApprovalPolicy struct {
+	// RequiredApprovals is the number of distinct approvers needed, at least one.
+	RequiredApprovals int `json:"requiredApprovals,omitempty"`
+	// ApproverGroups are the groups whose members may approve. Any user may approve if empty.
+	ApproverGroups []string `json:"approverGroups,omitempty"`
+}
+
+// InstallPlanApproval is the approval of an InstallPlan by a single user.
+type InstallPlanApproval struct {
+	Approver  string      `json:"approver"`
+	Timestamp metav1.Time `json:"timestamp"`
+	Comment   string      `json:"comment,omitempty"`
+}
+
+// IsApproved reports whether the plan has been approved. Plans with an approval policy are approved once enough
+// distinct approvers have approved them, regardless of Approved.
+func (s *InstallPlanSpec) IsApproved() bool {
+	if s.ApprovalPolicy == nil {
+		return s.Approved
+	}
+
+	approvers := map[string]struct{}{}
+	for _, approval := range s.Approvals {
+		approvers[approval.Approver] = struct{}{}
+	}
+	required := s.ApprovalPolicy.RequiredApprovals
+	if required < 1 {
+		required = 1
+	}
+	return len(approvers) >= required
 }
 
 // InstallPlanPhase is the current status of a InstallPlan as a whole.
@@ -46,18 +92,22 @@ const (
 type InstallPlanConditionType string
 
 const (
-	InstallPlanResolved  InstallPlanConditionType = "Resolved"
-	InstallPlanInstalled InstallPlanConditionType = "Installed"
+	InstallPlanResolved   InstallPlanConditionType = "Resolved"
+	InstallPlanInstalled  InstallPlanConditionType = "Installed"
+	InstallPlanRolledBack InstallPlanConditionType = "RolledBack"
+	InstallPlanApproved   InstallPlanConditionType = "Approved"
 )
 
 // ConditionReason is a camelcased reason for the state transition.
 type InstallPlanConditionReason string
 
 const (
-	InstallPlanReasonPlanUnknown        InstallPlanConditionReason = "PlanUnknown"
-	InstallPlanReasonInstallCheckFailed InstallPlanConditionReason = "InstallCheckFailed"
-	InstallPlanReasonDependencyConflict InstallPlanConditionReason = "DependenciesConflict"
-	InstallPlanReasonComponentFailed    InstallPlanConditionReason = "InstallComponentFailed"
+	InstallPlanReasonPlanUnknown         InstallPlanConditionReason = "PlanUnknown"
+	InstallPlanReasonInstallCheckFailed  InstallPlanConditionReason = "InstallCheckFailed"
+	InstallPlanReasonDependencyConflict  InstallPlanConditionReason = "DependenciesConflict"
+	InstallPlanReasonComponentFailed     InstallPlanConditionReason = "InstallComponentFailed"
+	InstallPlanReasonRollbackFailed      InstallPlanConditionReason = "RollbackFailed"
+	InstallPlanReasonApprovalsUnverified InstallPlanConditionReason = "ApprovalsUnverified"
 )
 
 // StepStatus is the current status of a particular resource an in
@@ -69,8 +119,56 @@ const (
 	StepStatusNotPresent StepStatus = "NotPresent"
 	StepStatusPresent    StepStatus = "Present"
 	StepStatusCreated    StepStatus = "Created"
+	StepStatusFailed     StepStatus = "Failed"
+)
+
+// StepRollbackStatus is the outcome of rolling back a step created by a
+// failed InstallPlan
+type StepRollbackStatus string
+
+const (
+	StepRollbackStatusDeleted StepRollbackStatus = "Deleted"
+	StepRollbackStatusFailed  StepRollbackStatus = "Failed"
 )
 
+// StepDryRunResult is how a resource of an InstallPlan compares to the
+// cluster before the plan is installed
+type StepDryRunResult string
+
+const (
+	StepDryRunResultUnknown          StepDryRunResult = "Unknown"
+	StepDryRunResultWouldCreate      StepDryRunResult = "WouldCreate"
+	StepDryRunResultPresentIdentical StepDryRunResult = "PresentIdentical"
+	StepDryRunResultPresentDifferent StepDryRunResult = "PresentDifferent"
+)
+
+// ResolutionTraceResult is the outcome of a step of resolving an InstallPlan
+type ResolutionTraceResult string
+
+const (
+	ResolutionTraceResultSelected    ResolutionTraceResult = "Selected"
+	ResolutionTraceResultRejected    ResolutionTraceResult = "Rejected"
+	ResolutionTraceResultNotFound    ResolutionTraceResult = "NotFound"
+	ResolutionTraceResultSearched    ResolutionTraceResult = "Searched"
+	ResolutionTraceResultSatisfied   ResolutionTraceResult = "Satisfied"
+	ResolutionTraceResultUnsatisfied ResolutionTraceResult = "Unsatisfied"
+)
+
+// ResolutionTraceEntry records a CSV or CRD requirement considered while
+// resolving an InstallPlan. Entries for CRD requirements name the CRD and
+// the CSV requiring it; entries for CSVs name the CSV and, for candidate
+// owners, the CRD they were considered for.
+type ResolutionTraceEntry struct {
+	// Depth is the number of CSVs selected when the entry was recorded.
+	Depth          int                   `json:"depth"`
+	CSV            string                `json:"csv,omitempty"`
+	CRD            string                `json:"crd,omitempty"`
+	RequiredBy     string                `json:"requiredBy,omitempty"`
+	CatalogSources []string              `json:"catalogSources,omitempty"`
+	Result         ResolutionTraceResult `json:"result"`
+	Message        string                `json:"message,omitempty"`
+}
+
 // ErrInvalidInstallPlan is the error returned by functions that operate on
 // InstallPlans when the InstallPlan does not contain totally valid data.
 var ErrInvalidInstallPlan = errors.New("the InstallPlan contains invalid data")
@@ -84,6 +182,10 @@ type InstallPlanStatus struct {
 	Conditions     []InstallPlanCondition `json:"conditions,omitempty"`
 	CatalogSources []string               `json:"catalogSources"`
 	Plan           []Step                 `json:"plan,omitempty"`
+
+	// ResolutionTrace records how resolution searched for the plan's CSVs
+	// when it failed.
+	ResolutionTrace []ResolutionTraceEntry `json:"resolutionTrace,omitempty"`
 }
 
 // InstallPlanCondition represents the overall status of the execution of
@@ -100,6 +202,19 @@ type InstallPlanCondition struct {
 // allow overwriting `now` function for deterministic tests
 var now = metav1.Now
 
+// GetCondition returns the condition of the given type, or an unknown condition if it hasn't been set
+func (s *InstallPlanStatus) GetCondition(condType InstallPlanConditionType) InstallPlanCondition {
+	for _, cond := range s.Conditions {
+		if cond.Type == condType {
+			return cond
+		}
+	}
+	return InstallPlanCondition{
+		Type:   condType,
+		Status: corev1.ConditionUnknown,
+	}
+}
+
 // SetCondition adds or updates a condition, using `Type` as merge key
 func (s *InstallPlanStatus) SetCondition(cond InstallPlanCondition) InstallPlanCondition {
 	updated := now()
@@ -138,9 +253,17 @@ func ConditionMet(cond InstallPlanConditionType) InstallPlanCondition {
 
 // Step represents the status of an individual step in an InstallPlan.
 type Step struct {
-	Resolving string       `json:"resolving"`
-	Resource  StepResource `json:"resource"`
-	Status    StepStatus   `json:"status"`
+	Resolving       string             `json:"resolving"`
+	Resource        StepResource       `json:"resource"`
+	Status          StepStatus         `json:"status"`
+	Message         string             `json:"message,omitempty"`
+	Attempts        int                `json:"attempts,omitempty"`
+	Rollback        StepRollbackStatus `json:"rollback,omitempty"`
+	RollbackMessage string             `json:"rollbackMessage,omitempty"`
+	DryRun          StepDryRunResult   `json:"dryRun,omitempty"`
+	DryRunDiff      string             `json:"dryRunDiff,omitempty"`
+	// DependsOn lists the steps that must complete before this one, as "<kind>/<name>".
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // StepResource represents the status of a resource to be tracked by an
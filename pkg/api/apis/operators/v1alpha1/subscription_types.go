@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -9,6 +10,30 @@ import (
 const (
 	SubscriptionKind          = "Subscription"
 	SubscriptionCRDAPIVersion = operators.GroupName + "/" + GroupVersion
+
+	// SubscriptionConfigAnnotationKey is the annotation used to carry a Subscription's config to the installed
+	// ClusterServiceVersion and to record the applied config on the operator's Deployments
+	SubscriptionConfigAnnotationKey = "alm-subscription-config"
+
+	// SubscriptionUninstallFinalizer holds the deletion of a Subscription until its installed components are removed
+	SubscriptionUninstallFinalizer = operators.GroupName + "/uninstall-subscription"
+
+	// SubscriptionUninstallCRDsAnnotationKey records the CRDs owned by a deleted Subscription's CSVs, so that they can
+	// be removed after the CSVs are gone
+	SubscriptionUninstallCRDsAnnotationKey = operators.GroupName + "/uninstall-crds"
+)
+
+// SubscriptionDeletionPolicy decides what happens to the installed operator when a Subscription is deleted
+type SubscriptionDeletionPolicy string
+
+const (
+	// SubscriptionDeletionPolicyOrphan leaves the installed operator in place. This is the default.
+	SubscriptionDeletionPolicyOrphan SubscriptionDeletionPolicy = "Orphan"
+	// SubscriptionDeletionPolicyUninstall removes the installed CSV and waits for its resources to be torn down.
+	SubscriptionDeletionPolicyUninstall SubscriptionDeletionPolicy = "Uninstall"
+	// SubscriptionDeletionPolicyUninstallWithCRDs also removes the CRDs owned by the installed CSV, and with them
+	// all custom resources of those kinds. CRDs still owned or required by other CSVs are kept.
+	SubscriptionDeletionPolicyUninstallWithCRDs SubscriptionDeletionPolicy = "UninstallWithCRDs"
 )
 
 // SubscriptionState tracks when updates are available, installing, or service is up to date
@@ -23,8 +48,33 @@ const (
 )
 
 const (
-	SubscriptionReasonInvalidCatalog   ConditionReason = "InvalidCatalog"
-	SubscriptionReasonUpgradeSucceeded ConditionReason = "UpgradeSucceeded"
+	SubscriptionReasonInvalidCatalog         ConditionReason = "InvalidCatalog"
+	SubscriptionReasonUpgradeSucceeded       ConditionReason = "UpgradeSucceeded"
+	SubscriptionReasonOutsideVersionRange    ConditionReason = "OutsideVersionRange"
+	SubscriptionReasonInvalidVersionRange    ConditionReason = "InvalidVersionRange"
+	SubscriptionReasonCSVNotFound            ConditionReason = "CSVNotFound"
+	SubscriptionReasonReplacementNotFound    ConditionReason = "ReplacementNotFound"
+	SubscriptionReasonInstallPlanNotFound    ConditionReason = "InstallPlanNotFound"
+	SubscriptionReasonInstallPlanNotCreated  ConditionReason = "InstallPlanNotCreated"
+	SubscriptionReasonNoUpgradePath          ConditionReason = "NoUpgradePath"
+	SubscriptionReasonInvalidUpgradeSchedule ConditionReason = "InvalidUpgradeSchedule"
+)
+
+// SubscriptionConditionType describes a problem a Subscription may have while it tracks a channel.
+type SubscriptionConditionType string
+
+const (
+	// SubscriptionCatalogSourcesUnhealthy is true when the subscription's CatalogSource can't be used.
+	SubscriptionCatalogSourcesUnhealthy SubscriptionConditionType = "CatalogSourcesUnhealthy"
+	// SubscriptionResolutionFailed is true when the CSV to install or upgrade to can't be found.
+	SubscriptionResolutionFailed SubscriptionConditionType = "ResolutionFailed"
+	// SubscriptionInstallPlanPending is true while the InstallPlan for the current CSV hasn't completed.
+	SubscriptionInstallPlanPending SubscriptionConditionType = "InstallPlanPending"
+	// SubscriptionInstallPlanFailed is true when the InstallPlan for the current CSV failed or couldn't be created.
+	SubscriptionInstallPlanFailed SubscriptionConditionType = "InstallPlanFailed"
+	// SubscriptionInstalledCSVUnhealthy is true when the installed CSV hasn't succeeded. Its reason and message
+	// mirror the CSV's.
+	SubscriptionInstalledCSVUnhealthy SubscriptionConditionType = "InstalledCSVUnhealthy"
 )
 
 // SubscriptionSpec defines an Application that can be installed
@@ -35,6 +85,76 @@ type SubscriptionSpec struct {
 	Channel                string   `json:"channel,omitempty"`
 	StartingCSV            string   `json:"startingCSV,omitempty"`
 	InstallPlanApproval    Approval `json:"installPlanApproval,omitempty"`
+
+	// VersionRange is a semver range (e.g. `>=1.2.0 <2.0.0`) that the versions of installed CSVs must satisfy.
+	// Upgrades to versions outside of the range are held back.
+	// +optional
+	VersionRange string `json:"versionRange,omitempty"`
+
+	// DeletionPolicy decides whether the installed operator is removed when the subscription is deleted.
+	// +optional
+	DeletionPolicy SubscriptionDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// UpgradeSchedule restricts when InstallPlans of an automatic subscription are approved.
+	// +optional
+	UpgradeSchedule *UpgradeSchedule `json:"upgradeSchedule,omitempty"`
+
+	// ApprovalPolicy is set on the InstallPlans of a manual subscription, which then need named approvers.
+	// +optional
+	ApprovalPolicy *ApprovalPolicy `json:"approvalPolicy,omitempty"`
+
+	// InstallPlanHistoryLimit is the number of Complete InstallPlans to keep, overriding the catalog operator's
+	// default. Older Complete InstallPlans are deleted.
+	// +optional
+	InstallPlanHistoryLimit *int32 `json:"installPlanHistoryLimit,omitempty"`
+
+	// Config overrides applied to every Deployment of the installed ClusterServiceVersion
+	// +optional
+	Config SubscriptionConfig `json:"config,omitempty"`
+}
+
+// UpgradeSchedule is a set of maintenance windows. InstallPlans created outside of a window require approval until
+// a window opens.
+type UpgradeSchedule struct {
+	Windows []MaintenanceWindow `json:"windows"`
+
+	// TimeZone is the IANA name of the time zone the windows' schedules are evaluated in. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period of time during which upgrades may be installed.
+type MaintenanceWindow struct {
+	// Schedule is a cron expression (minute hour day-of-month month day-of-week) for when the window opens.
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// SubscriptionConfig contains overrides that are merged into each Deployment of the operator's install strategy.
+type SubscriptionConfig struct {
+	// Env is a list of environment variables to set in every container. Variables with the same name as an
+	// existing variable replace it.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources replaces the compute resource requirements of every container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is merged into the node selector of every pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are appended to the tolerations of every pod.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// IsEmpty returns true if the config doesn't override anything
+func (c SubscriptionConfig) IsEmpty() bool {
+	return len(c.Env) == 0 && c.Resources == nil && len(c.NodeSelector) == 0 && len(c.Tolerations) == 0
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -52,9 +172,93 @@ type SubscriptionStatus struct {
 	InstalledCSV string                `json:"installedCSV, omitempty"`
 	Install      *InstallPlanReference `json:"installplan,omitempty"`
 
-	State       SubscriptionState `json:"state,omitempty"`
-	Reason      ConditionReason   `json:"reason,omitempty"`
-	LastUpdated metav1.Time       `json:"lastUpdated"`
+	// CurrentChannel is the channel CurrentCSV was resolved from. It differs from the spec's channel after the
+	// channel is switched, until a path from the installed CSV into the new channel is found.
+	// +optional
+	CurrentChannel string `json:"currentChannel,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was last synced for. A subscription whose spec
+	// changed is resolved again even if its catalog hasn't.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// NextMaintenanceWindow is when the subscription's current or next maintenance window opens.
+	// +optional
+	NextMaintenanceWindow *metav1.Time `json:"nextMaintenanceWindow,omitempty"`
+
+	// HeldBackCSV is the name of the newest CSV that wasn't installed because its version is outside of the
+	// subscription's version range
+	// +optional
+	HeldBackCSV string `json:"heldBackCSV,omitempty"`
+	// HeldBackVersion is the version of HeldBackCSV
+	// +optional
+	HeldBackVersion string `json:"heldBackVersion,omitempty"`
+
+	State       SubscriptionState       `json:"state,omitempty"`
+	Reason      ConditionReason         `json:"reason,omitempty"`
+	Conditions  []SubscriptionCondition `json:"conditions,omitempty"`
+	LastUpdated metav1.Time             `json:"lastUpdated"`
+}
+
+// SubscriptionCondition represents the latest observation of one aspect of a Subscription's state.
+type SubscriptionCondition struct {
+	Type               SubscriptionConditionType `json:"type"`
+	Status             corev1.ConditionStatus    `json:"status"` // True, False, or Unknown
+	LastUpdateTime     metav1.Time               `json:"lastUpdateTime,omitempty"`
+	LastTransitionTime metav1.Time               `json:"lastTransitionTime,omitempty"`
+	Reason             ConditionReason           `json:"reason,omitempty"`
+	Message            string                    `json:"message,omitempty"`
+}
+
+// SetCondition adds or updates a condition, using `Type` as merge key
+func (s *SubscriptionStatus) SetCondition(cond SubscriptionCondition) SubscriptionCondition {
+	updated := now()
+	cond.LastUpdateTime = updated
+	cond.LastTransitionTime = updated
+
+	for i, existing := range s.Conditions {
+		if existing.Type != cond.Type {
+			continue
+		}
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		s.Conditions[i] = cond
+		return cond
+	}
+	s.Conditions = append(s.Conditions, cond)
+	return cond
+}
+
+// GetCondition returns the condition of the given type, or an unknown condition if it hasn't been set
+func (s *SubscriptionStatus) GetCondition(condType SubscriptionConditionType) SubscriptionCondition {
+	for _, cond := range s.Conditions {
+		if cond.Type == condType {
+			return cond
+		}
+	}
+	return SubscriptionCondition{
+		Type:   condType,
+		Status: corev1.ConditionUnknown,
+	}
+}
+
+// SubscriptionConditionTrue returns a condition of the given type that is true for the given reason
+func SubscriptionConditionTrue(condType SubscriptionConditionType, reason ConditionReason, message string) SubscriptionCondition {
+	return SubscriptionCondition{
+		Type:    condType,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// SubscriptionConditionFalse returns a condition of the given type that is false
+func SubscriptionConditionFalse(condType SubscriptionConditionType) SubscriptionCondition {
+	return SubscriptionCondition{
+		Type:   condType,
+		Status: corev1.ConditionFalse,
+	}
 }
 
 type InstallPlanReference struct {
@@ -72,6 +276,15 @@ type SubscriptionList struct {
 	Items []Subscription `json:"items"`
 }
 
+// GetDeletionPolicy gets the configured deletion policy or the default
+func (s *Subscription) GetDeletionPolicy() SubscriptionDeletionPolicy {
+	switch s.Spec.DeletionPolicy {
+	case SubscriptionDeletionPolicyUninstall, SubscriptionDeletionPolicyUninstallWithCRDs:
+		return s.Spec.DeletionPolicy
+	}
+	return SubscriptionDeletionPolicyOrphan
+}
+
 // GetInstallPlanApproval gets the configured install plan approval or the default
 func (s *Subscription) GetInstallPlanApproval() Approval {
 	if s.Spec.InstallPlanApproval == ApprovalManual {
@@ -102,3 +102,38 @@ func TestOwnsCRD(t *testing.T) {
 		require.Equal(t, tt.expected, csv.OwnsCRD(tt.crdName))
 	}
 }
+
+func TestInstallPlanIsApproved(t *testing.T) {
+	approval := func(approver string) InstallPlanApproval {
+		return InstallPlanApproval{Approver: approver}
+	}
+
+	var table = []struct {
+		name     string
+		spec     InstallPlanSpec
+		expected bool
+	}{
+		{"NoPolicyApproved", InstallPlanSpec{Approved: true}, true},
+		{"NoPolicyNotApproved", InstallPlanSpec{}, false},
+		{"PolicyIgnoresApproved", InstallPlanSpec{Approved: true, ApprovalPolicy: &ApprovalPolicy{}}, false},
+		{"DefaultsToOneApproval", InstallPlanSpec{ApprovalPolicy: &ApprovalPolicy{}, Approvals: []InstallPlanApproval{approval("alice")}}, true},
+		{"NotEnoughApprovals", InstallPlanSpec{
+			ApprovalPolicy: &ApprovalPolicy{RequiredApprovals: 2},
+			Approvals:      []InstallPlanApproval{approval("alice")},
+		}, false},
+		{"DuplicateApprovers", InstallPlanSpec{
+			ApprovalPolicy: &ApprovalPolicy{RequiredApprovals: 2},
+			Approvals:      []InstallPlanApproval{approval("alice"), approval("alice")},
+		}, false},
+		{"EnoughApprovals", InstallPlanSpec{
+			ApprovalPolicy: &ApprovalPolicy{RequiredApprovals: 2},
+			Approvals:      []InstallPlanApproval{approval("alice"), approval("bob")},
+		}, true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.spec.IsApproved())
+		})
+	}
+}
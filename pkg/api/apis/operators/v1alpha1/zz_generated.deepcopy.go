@@ -23,6 +23,7 @@ package v1alpha1
 import (
 	json "encoding/json"
 
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -165,6 +166,44 @@ func (in *AppLink) DeepCopy() *AppLink {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalPolicy) DeepCopyInto(out *ApprovalPolicy) {
+	*out = *in
+	if in.ApproverGroups != nil {
+		in, out := &in.ApproverGroups, &out.ApproverGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalPolicy.
+func (in *ApprovalPolicy) DeepCopy() *ApprovalPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveStatus) DeepCopyInto(out *ArchiveStatus) {
+	*out = *in
+	in.LastPoll.DeepCopyInto(&out.LastPoll)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveStatus.
+func (in *ArchiveStatus) DeepCopy() *ArchiveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CRDDescription) DeepCopyInto(out *CRDDescription) {
 	*out = *in
@@ -235,6 +274,40 @@ func (in *CatalogSource) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CatalogSourceCondition) DeepCopyInto(out *CatalogSourceCondition) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogSourceCondition.
+func (in *CatalogSourceCondition) DeepCopy() *CatalogSourceCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(CatalogSourceCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CatalogSourceContents) DeepCopyInto(out *CatalogSourceContents) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogSourceContents.
+func (in *CatalogSourceContents) DeepCopy() *CatalogSourceContents {
+	if in == nil {
+		return nil
+	}
+	out := new(CatalogSourceContents)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CatalogSourceList) DeepCopyInto(out *CatalogSourceList) {
 	*out = *in
@@ -276,6 +349,15 @@ func (in *CatalogSourceSpec) DeepCopyInto(out *CatalogSourceSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
 	out.Icon = in.Icon
 	return
 }
@@ -303,6 +385,40 @@ func (in *CatalogSourceStatus) DeepCopyInto(out *CatalogSourceStatus) {
 		}
 	}
 	in.LastSync.DeepCopyInto(&out.LastSync)
+	if in.RegistryService != nil {
+		in, out := &in.RegistryService, &out.RegistryService
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RegistryServiceStatus)
+			**out = **in
+		}
+	}
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ArchiveStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Contents != nil {
+		in, out := &in.Contents, &out.Contents
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(CatalogSourceContents)
+			**out = **in
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CatalogSourceCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -423,6 +539,11 @@ func (in *ClusterServiceVersionSpec) DeepCopyInto(out *ClusterServiceVersionSpec
 		*out = make([]Icon, len(*in))
 		copy(*out, *in)
 	}
+	if in.Skips != nil {
+		in, out := &in.Skips, &out.Skips
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -597,6 +718,23 @@ func (in *InstallPlan) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallPlanApproval) DeepCopyInto(out *InstallPlanApproval) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallPlanApproval.
+func (in *InstallPlanApproval) DeepCopy() *InstallPlanApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallPlanApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstallPlanCondition) DeepCopyInto(out *InstallPlanCondition) {
 	*out = *in
@@ -672,6 +810,22 @@ func (in *InstallPlanSpec) DeepCopyInto(out *InstallPlanSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ApprovalPolicy != nil {
+		in, out := &in.ApprovalPolicy, &out.ApprovalPolicy
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ApprovalPolicy)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]InstallPlanApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -703,7 +857,16 @@ func (in *InstallPlanStatus) DeepCopyInto(out *InstallPlanStatus) {
 	if in.Plan != nil {
 		in, out := &in.Plan, &out.Plan
 		*out = make([]Step, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResolutionTrace != nil {
+		in, out := &in.ResolutionTrace, &out.ResolutionTrace
+		*out = make([]ResolutionTraceEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -734,6 +897,23 @@ func (in *Maintainer) DeepCopy() *Maintainer {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamedInstallStrategy) DeepCopyInto(out *NamedInstallStrategy) {
 	*out = *in
@@ -755,6 +935,22 @@ func (in *NamedInstallStrategy) DeepCopy() *NamedInstallStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryServiceStatus) DeepCopyInto(out *RegistryServiceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryServiceStatus.
+func (in *RegistryServiceStatus) DeepCopy() *RegistryServiceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryServiceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RequirementStatus) DeepCopyInto(out *RequirementStatus) {
 	*out = *in
@@ -776,6 +972,27 @@ func (in *RequirementStatus) DeepCopy() *RequirementStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolutionTraceEntry) DeepCopyInto(out *ResolutionTraceEntry) {
+	*out = *in
+	if in.CatalogSources != nil {
+		in, out := &in.CatalogSources, &out.CatalogSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolutionTraceEntry.
+func (in *ResolutionTraceEntry) DeepCopy() *ResolutionTraceEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolutionTraceEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpecDescriptor) DeepCopyInto(out *SpecDescriptor) {
 	*out = *in
@@ -848,6 +1065,11 @@ func (in *StatusDescriptor) DeepCopy() *StatusDescriptor {
 func (in *Step) DeepCopyInto(out *Step) {
 	*out = *in
 	out.Resource = in.Resource
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -888,7 +1110,7 @@ func (in *Subscription) DeepCopyInto(out *Subscription) {
 			*out = nil
 		} else {
 			*out = new(SubscriptionSpec)
-			**out = **in
+			(*in).DeepCopyInto(*out)
 		}
 	}
 	in.Status.DeepCopyInto(&out.Status)
@@ -946,9 +1168,101 @@ func (in *SubscriptionList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubscriptionCondition) DeepCopyInto(out *SubscriptionCondition) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubscriptionCondition.
+func (in *SubscriptionCondition) DeepCopy() *SubscriptionCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SubscriptionCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubscriptionConfig) DeepCopyInto(out *SubscriptionConfig) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(corev1.ResourceRequirements)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubscriptionConfig.
+func (in *SubscriptionConfig) DeepCopy() *SubscriptionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SubscriptionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubscriptionSpec) DeepCopyInto(out *SubscriptionSpec) {
 	*out = *in
+	if in.UpgradeSchedule != nil {
+		in, out := &in.UpgradeSchedule, &out.UpgradeSchedule
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UpgradeSchedule)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.ApprovalPolicy != nil {
+		in, out := &in.ApprovalPolicy, &out.ApprovalPolicy
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ApprovalPolicy)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.InstallPlanHistoryLimit != nil {
+		in, out := &in.InstallPlanHistoryLimit, &out.InstallPlanHistoryLimit
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	in.Config.DeepCopyInto(&out.Config)
 	return
 }
 
@@ -974,6 +1288,22 @@ func (in *SubscriptionStatus) DeepCopyInto(out *SubscriptionStatus) {
 			**out = **in
 		}
 	}
+	if in.NextMaintenanceWindow != nil {
+		in, out := &in.NextMaintenanceWindow, &out.NextMaintenanceWindow
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SubscriptionCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
 	return
 }
@@ -987,3 +1317,24 @@ func (in *SubscriptionStatus) DeepCopy() *SubscriptionStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeSchedule) DeepCopyInto(out *UpgradeSchedule) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSchedule.
+func (in *UpgradeSchedule) DeepCopy() *UpgradeSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/clientset/versioned"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 )
 
@@ -14,3 +15,12 @@ func NewClient(kubeconfig string) (client versioned.Interface, err error) {
 	}
 	return versioned.NewForConfig(config)
 }
+
+// NewDynamicClient creates a client that can interact with any resource in k8s api
+func NewDynamicClient(kubeconfig string) (dynamic.Interface, error) {
+	config, err := getConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
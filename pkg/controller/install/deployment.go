@@ -1,6 +1,7 @@
 package install
 
 import (
+	"encoding/json"
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
@@ -9,6 +10,7 @@ import (
 	rbac "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/wrappers"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
 )
@@ -103,9 +105,14 @@ func (i *StrategyDeploymentInstaller) installPermissions(perms []StrategyDeploym
 }
 
 func (i *StrategyDeploymentInstaller) installDeployments(deps []StrategyDeploymentSpec) error {
+	config, rawConfig, err := i.subscriptionConfig()
+	if err != nil {
+		return err
+	}
+
 	for _, d := range deps {
 		// Create or Update Deployment
-		dep := &appsv1.Deployment{Spec: d.Spec}
+		dep := &appsv1.Deployment{Spec: *d.Spec.DeepCopy()}
 		dep.SetName(d.Name)
 		dep.SetNamespace(i.owner.GetNamespace())
 		ownerutil.AddNonBlockingOwner(dep, i.owner)
@@ -114,6 +121,18 @@ func (i *StrategyDeploymentInstaller) installDeployments(deps []StrategyDeployme
 		}
 		dep.Labels["alm-owner-name"] = i.owner.GetName()
 		dep.Labels["alm-owner-namespace"] = i.owner.GetNamespace()
+
+		// Merge in any overrides from the owner's Subscription and record what was applied
+		if rawConfig != "" {
+			applySubscriptionConfig(&dep.Spec.Template.Spec, config)
+			annotations := dep.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[v1alpha1.SubscriptionConfigAnnotationKey] = rawConfig
+			dep.SetAnnotations(annotations)
+		}
+
 		if _, err := i.strategyClient.CreateOrUpdateDeployment(dep); err != nil {
 			return err
 		}
@@ -122,6 +141,67 @@ func (i *StrategyDeploymentInstaller) installDeployments(deps []StrategyDeployme
 	return nil
 }
 
+// subscriptionConfig returns the Subscription config recorded on the owner, along with its raw annotation value
+func (i *StrategyDeploymentInstaller) subscriptionConfig() (v1alpha1.SubscriptionConfig, string, error) {
+	var config v1alpha1.SubscriptionConfig
+	rawConfig := i.owner.GetAnnotations()[v1alpha1.SubscriptionConfigAnnotationKey]
+	if rawConfig == "" {
+		return config, "", nil
+	}
+	if err := json.Unmarshal([]byte(rawConfig), &config); err != nil {
+		return config, "", fmt.Errorf("invalid subscription config on %s: %s", i.owner.GetName(), err)
+	}
+	return config, rawConfig, nil
+}
+
+// applySubscriptionConfig merges the given Subscription config into a pod spec
+func applySubscriptionConfig(podSpec *corev1.PodSpec, config v1alpha1.SubscriptionConfig) {
+	for c := range podSpec.Containers {
+		container := &podSpec.Containers[c]
+
+		// Override env vars with the same name, append the rest
+		for _, env := range config.Env {
+			replaced := false
+			for e := range container.Env {
+				if container.Env[e].Name == env.Name {
+					container.Env[e] = env
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				container.Env = append(container.Env, env)
+			}
+		}
+
+		if config.Resources != nil {
+			container.Resources = *config.Resources.DeepCopy()
+		}
+	}
+
+	if len(config.NodeSelector) > 0 {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+		for k, v := range config.NodeSelector {
+			podSpec.NodeSelector[k] = v
+		}
+	}
+
+	for _, toleration := range config.Tolerations {
+		exists := false
+		for _, t := range podSpec.Tolerations {
+			if t.MatchToleration(&toleration) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+		}
+	}
+}
+
 func (i *StrategyDeploymentInstaller) cleanupPrevious(current *StrategyDetailsDeployment, previous *StrategyDetailsDeployment) error {
 	previousDeploymentsMap := map[string]struct{}{}
 	for _, d := range previous.DeploymentSpecs {
@@ -210,12 +290,21 @@ func (i *StrategyDeploymentInstaller) checkForDeployments(deploymentSpecs []Stra
 	for _, d := range existingDeployments {
 		existingMap[d.GetName()] = d
 	}
+	_, rawConfig, err := i.subscriptionConfig()
+	if err != nil {
+		return StrategyError{Reason: StrategyErrReasonInvalidStrategy, Message: err.Error()}
+	}
 	for _, spec := range deploymentSpecs {
 		dep, exists := existingMap[spec.Name]
 		if !exists {
 			log.Debugf("missing deployment with name=%s", spec.Name)
 			return StrategyError{Reason: StrategyErrReasonComponentMissing, Message: fmt.Sprintf("missing deployment with name=%s", spec.Name)}
 		}
+		// A deployment still annotated with a config that was since removed from the Subscription is outdated too
+		if dep.GetAnnotations()[v1alpha1.SubscriptionConfigAnnotationKey] != rawConfig {
+			log.Debugf("deployment %s doesn't match subscription config", spec.Name)
+			return StrategyError{Reason: StrategyErrReasonOutdated, Message: fmt.Sprintf("deployment %s doesn't match subscription config", spec.Name)}
+		}
 		reason, ready, err := DeploymentStatus(dep)
 		if err != nil {
 			log.Debugf("deployment %s not ready before timeout: %s", dep.Name, err.Error())
@@ -10,6 +10,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
@@ -584,3 +585,83 @@ func TestInstallStrategyDeploymentCheckInstallErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestApplySubscriptionConfig(t *testing.T) {
+	resources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}
+	config := v1alpha1.SubscriptionConfig{
+		Env:          []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "PROXY", Value: "proxy:3128"}},
+		Resources:    &resources,
+		NodeSelector: map[string]string{"node-role": "infra"},
+		Tolerations:  []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+	}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "operator", Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "WATCH_NAMESPACE", Value: "ns"}}},
+			{Name: "sidecar"},
+		},
+		NodeSelector: map[string]string{"beta.kubernetes.io/os": "linux"},
+		Tolerations:  []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+	}
+	applySubscriptionConfig(&podSpec, config)
+
+	require.Equal(t, []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "WATCH_NAMESPACE", Value: "ns"}, {Name: "PROXY", Value: "proxy:3128"}}, podSpec.Containers[0].Env)
+	require.Equal(t, config.Env, podSpec.Containers[1].Env)
+	require.Equal(t, resources, podSpec.Containers[0].Resources)
+	require.Equal(t, resources, podSpec.Containers[1].Resources)
+	require.Equal(t, map[string]string{"beta.kubernetes.io/os": "linux", "node-role": "infra"}, podSpec.NodeSelector)
+	require.Len(t, podSpec.Tolerations, 1)
+}
+
+func TestInstallStrategyDeploymentCheckInstalledSubscriptionConfig(t *testing.T) {
+	namespace := "alm-test-deployment"
+	rawConfig := `{"nodeSelector":{"node-role":"infra"}}`
+
+	mockOwner := v1alpha1.ClusterServiceVersion{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       v1alpha1.ClusterServiceVersionKind,
+			APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "clusterserviceversion-owner",
+			Namespace: namespace,
+		},
+	}
+
+	tests := []struct {
+		config      string
+		applied     string
+		outdated    bool
+		description string
+	}{
+		{config: rawConfig, applied: "", outdated: true, description: "ConfigNotApplied"},
+		{config: rawConfig, applied: `{"nodeSelector":{"node-role":"worker"}}`, outdated: true, description: "DifferentConfigApplied"},
+		{config: rawConfig, applied: rawConfig, outdated: false, description: "ConfigApplied"},
+		{config: "", applied: rawConfig, outdated: true, description: "ConfigRemoved"},
+		{config: "", applied: "", outdated: false, description: "NoConfig"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			mockOwner := *mockOwner.DeepCopy()
+			if tt.config != "" {
+				mockOwner.SetAnnotations(map[string]string{v1alpha1.SubscriptionConfigAnnotationKey: tt.config})
+			}
+			fakeClient := new(clientfakes.FakeInstallStrategyDeploymentInterface)
+			strategy := strategy(1, namespace, &mockOwner)
+			installer := NewStrategyDeploymentInstaller(fakeClient, &mockOwner, nil)
+
+			fakeClient.GetServiceAccountByNameReturns(testServiceAccount(strategy.Permissions[0].ServiceAccountName, &mockOwner), nil)
+			dep := testDeployment("alm-dep-1", namespace, &mockOwner)
+			if tt.applied != "" {
+				dep.SetAnnotations(map[string]string{v1alpha1.SubscriptionConfigAnnotationKey: tt.applied})
+			}
+			fakeClient.FindAnyDeploymentsMatchingNamesReturns([]*appsv1.Deployment{&dep}, nil)
+
+			installed, err := installer.CheckInstalled(strategy)
+			require.Equal(t, tt.outdated, IsErrorOutdated(err))
+			require.Equal(t, !tt.outdated, installed)
+		})
+	}
+}
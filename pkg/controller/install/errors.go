@@ -8,6 +8,7 @@ const (
 	StrategyErrReasonInvalidStrategy  = "InvalidStrategy"
 	StrategyErrReasonTimeout          = "Timeout"
 	StrategyErrReasonUnknown          = "Unknown"
+	StrategyErrReasonOutdated         = "Outdated"
 )
 
 // unrecoverableErrors are the set of errors that mean we can't recover an install strategy
@@ -38,6 +39,11 @@ func IsErrorUnrecoverable(err error) bool {
 	return ok
 }
 
+// IsErrorOutdated reports if a given strategy error means the installed components must be reinstalled
+func IsErrorOutdated(err error) bool {
+	return err != nil && reasonForError(err) == StrategyErrReasonOutdated
+}
+
 func reasonForError(err error) string {
 	switch t := err.(type) {
 	case StrategyError:
@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// approvalClockSkew is how far an approval's timestamp may be from the time it's admitted
+const approvalClockSkew = 5 * time.Minute
+
+var errApprovalsUnverified = errors.New("approvals can't be verified without the InstallPlan approval webhook")
+
+// NewApprovalWebhook returns the handler of the validating admission webhook for InstallPlans. It only admits
+// approvals made by the requesting user, and only if the plan's approval policy allows the user to approve.
+func NewApprovalWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := admissionv1beta1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+			http.Error(w, "invalid admission review", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = admitInstallPlan(review.Request, time.Now())
+		review.Response.UID = review.Request.UID
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			log.WithError(err).Warn("failed to write admission review")
+		}
+	})
+}
+
+// admitInstallPlan decides whether an InstallPlan create or update is allowed
+func admitInstallPlan(request *admissionv1beta1.AdmissionRequest, now time.Time) *admissionv1beta1.AdmissionResponse {
+	plan := &v1alpha1.InstallPlan{}
+	if err := json.Unmarshal(request.Object.Raw, plan); err != nil {
+		return denyAdmission(http.StatusBadRequest, err)
+	}
+
+	var old *v1alpha1.InstallPlan
+	if request.Operation == admissionv1beta1.Update {
+		old = &v1alpha1.InstallPlan{}
+		if err := json.Unmarshal(request.OldObject.Raw, old); err != nil {
+			return denyAdmission(http.StatusBadRequest, err)
+		}
+	}
+
+	if err := validateApprovals(old, plan, request.UserInfo, now); err != nil {
+		log.WithFields(log.Fields{
+			"ip":        request.Name,
+			"namespace": request.Namespace,
+			"user":      request.UserInfo.Username,
+		}).WithError(err).Info("denied InstallPlan approval")
+		return denyAdmission(http.StatusForbidden, err)
+	}
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+func denyAdmission(code int32, err error) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: err.Error(),
+		},
+	}
+}
+
+// validateApprovals checks the approvals added to a plan by a user. The approval mode and policy can't be changed
+// once the plan exists, nor can approved if the plan has a policy. Existing approvals can't be changed or removed,
+// and a user can only add a single approval of their own, if the policy allows them to approve.
+func validateApprovals(old, plan *v1alpha1.InstallPlan, user authenticationv1.UserInfo, now time.Time) error {
+	var existing []v1alpha1.InstallPlanApproval
+	if old != nil {
+		if old.Spec.Approval != plan.Spec.Approval {
+			return fmt.Errorf("approval can't be changed")
+		}
+		if !reflect.DeepEqual(old.Spec.ApprovalPolicy, plan.Spec.ApprovalPolicy) {
+			return fmt.Errorf("approvalPolicy can't be changed")
+		}
+		if plan.Spec.ApprovalPolicy != nil && old.Spec.Approved != plan.Spec.Approved {
+			return fmt.Errorf("approved can't be changed when an approvalPolicy is set")
+		}
+		existing = old.Spec.Approvals
+	}
+
+	if len(plan.Spec.Approvals) < len(existing) {
+		return fmt.Errorf("existing approvals can't be changed")
+	}
+	for i := range existing {
+		if !reflect.DeepEqual(existing[i], plan.Spec.Approvals[i]) {
+			return fmt.Errorf("existing approvals can't be changed")
+		}
+	}
+
+	added := plan.Spec.Approvals[len(existing):]
+	if len(added) == 0 {
+		return nil
+	}
+	if plan.Spec.ApprovalPolicy == nil {
+		return fmt.Errorf("approvals require an approvalPolicy")
+	}
+	if len(added) > 1 {
+		return fmt.Errorf("only one approval can be added at a time")
+	}
+
+	approval := added[0]
+	if approval.Approver != user.Username {
+		return fmt.Errorf("approver %q doesn't match requesting user %q", approval.Approver, user.Username)
+	}
+	for _, e := range existing {
+		if e.Approver == approval.Approver {
+			return fmt.Errorf("%s has already approved", approval.Approver)
+		}
+	}
+	if skew := now.Sub(approval.Timestamp.Time); skew > approvalClockSkew || skew < -approvalClockSkew {
+		return fmt.Errorf("approval timestamp %s is more than %s from now", approval.Timestamp.UTC().Format(time.RFC3339), approvalClockSkew)
+	}
+
+	if groups := plan.Spec.ApprovalPolicy.ApproverGroups; len(groups) > 0 {
+		for _, group := range groups {
+			for _, userGroup := range user.Groups {
+				if group == userGroup {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("%s isn't in an approver group", user.Username)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+func approvalPlan(policy *v1alpha1.ApprovalPolicy, approvals ...v1alpha1.InstallPlanApproval) *v1alpha1.InstallPlan {
+	return &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: "ns"},
+		Spec: v1alpha1.InstallPlanSpec{
+			Approval:       v1alpha1.ApprovalManual,
+			ApprovalPolicy: policy,
+			Approvals:      approvals,
+		},
+	}
+}
+
+func TestValidateApprovals(t *testing.T) {
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	approval := func(approver string, at time.Time) v1alpha1.InstallPlanApproval {
+		return v1alpha1.InstallPlanApproval{Approver: approver, Timestamp: metav1.NewTime(at)}
+	}
+	alice := authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated", "release-managers"}}
+	bob := authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated"}}
+	policy := &v1alpha1.ApprovalPolicy{RequiredApprovals: 2}
+	groupPolicy := &v1alpha1.ApprovalPolicy{RequiredApprovals: 2, ApproverGroups: []string{"release-managers"}}
+	automatic := func(plan *v1alpha1.InstallPlan) *v1alpha1.InstallPlan {
+		plan.Spec.Approval = v1alpha1.ApprovalAutomatic
+		return plan
+	}
+	approved := func(plan *v1alpha1.InstallPlan) *v1alpha1.InstallPlan {
+		plan.Spec.Approved = true
+		return plan
+	}
+
+	tests := []struct {
+		name    string
+		old     *v1alpha1.InstallPlan
+		plan    *v1alpha1.InstallPlan
+		user    authenticationv1.UserInfo
+		wantErr bool
+	}{
+		{"CreateWithoutApprovals", nil, approvalPlan(policy), alice, false},
+		{"CreateApproved", nil, approvalPlan(policy, approval("alice", now)), alice, false},
+		{"Approve", approvalPlan(policy), approvalPlan(policy, approval("alice", now)), alice, false},
+		{"SecondApprover", approvalPlan(policy, approval("alice", now)), approvalPlan(policy, approval("alice", now), approval("bob", now)), bob, false},
+		{"ApproverGroup", approvalPlan(groupPolicy), approvalPlan(groupPolicy, approval("alice", now)), alice, false},
+		{"ClockSkew", approvalPlan(policy), approvalPlan(policy, approval("alice", now.Add(-time.Minute))), alice, false},
+		{"UnrelatedUpdate", approvalPlan(policy, approval("alice", now)), approvalPlan(policy, approval("alice", now)), bob, false},
+		{"OtherApprover", approvalPlan(policy), approvalPlan(policy, approval("alice", now)), bob, true},
+		{"NotInApproverGroup", approvalPlan(groupPolicy), approvalPlan(groupPolicy, approval("bob", now)), bob, true},
+		{"AlreadyApproved", approvalPlan(policy, approval("alice", now)), approvalPlan(policy, approval("alice", now), approval("alice", now)), alice, true},
+		{"MultipleApprovals", approvalPlan(policy), approvalPlan(policy, approval("alice", now), approval("bob", now)), alice, true},
+		{"RemovedApproval", approvalPlan(policy, approval("alice", now)), approvalPlan(policy), bob, true},
+		{"ChangedApproval", approvalPlan(policy, approval("alice", now)), approvalPlan(policy, approval("bob", now)), bob, true},
+		{"ChangedPolicy", approvalPlan(policy), approvalPlan(groupPolicy), alice, true},
+		{"ChangedApprovalMode", approvalPlan(policy), automatic(approvalPlan(policy)), alice, true},
+		{"ChangedApprovalModeNoPolicy", approvalPlan(nil), automatic(approvalPlan(nil)), alice, true},
+		{"ChangedApproved", approvalPlan(policy), approved(approvalPlan(policy)), alice, true},
+		{"ChangedApprovedNoPolicy", approvalPlan(nil), approved(approvalPlan(nil)), alice, false},
+		{"NoPolicy", approvalPlan(nil), approvalPlan(nil, approval("alice", now)), alice, true},
+		{"StaleTimestamp", approvalPlan(policy), approvalPlan(policy, approval("alice", now.Add(-time.Hour))), alice, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateApprovals(tt.old, tt.plan, tt.user, now)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestApprovalWebhook(t *testing.T) {
+	server := httptest.NewServer(NewApprovalWebhook())
+	defer server.Close()
+
+	policy := &v1alpha1.ApprovalPolicy{RequiredApprovals: 1}
+	old := approvalPlan(policy)
+	approved := approvalPlan(policy, v1alpha1.InstallPlanApproval{
+		Approver:  "alice",
+		Timestamp: metav1.Now(),
+		Comment:   "change 42",
+	})
+
+	review := func(user string) *admissionv1beta1.AdmissionResponse {
+		raw := func(obj runtime.Object) runtime.RawExtension {
+			data, err := json.Marshal(obj)
+			require.NoError(t, err)
+			return runtime.RawExtension{Raw: data}
+		}
+		body, err := json.Marshal(admissionv1beta1.AdmissionReview{
+			Request: &admissionv1beta1.AdmissionRequest{
+				UID:       types.UID("review-" + user),
+				Name:      approved.GetName(),
+				Namespace: approved.GetNamespace(),
+				Operation: admissionv1beta1.Update,
+				UserInfo:  authenticationv1.UserInfo{Username: user},
+				Object:    raw(approved),
+				OldObject: raw(old),
+			},
+		})
+		require.NoError(t, err)
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		out := admissionv1beta1.AdmissionReview{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		require.NotNil(t, out.Response)
+		require.Equal(t, types.UID("review-"+user), out.Response.UID)
+		return out.Response
+	}
+
+	require.True(t, review("alice").Allowed)
+
+	denied := review("bob")
+	require.False(t, denied.Allowed)
+	require.Equal(t, int32(http.StatusForbidden), denied.Result.Code)
+	require.Contains(t, denied.Result.Message, "bob")
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte("{")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestTransitionInstallPlanApprovalPolicy(t *testing.T) {
+	policy := &v1alpha1.ApprovalPolicy{RequiredApprovals: 2}
+	approvals := []v1alpha1.InstallPlanApproval{{Approver: "alice"}, {Approver: "bob"}}
+
+	tests := []struct {
+		name      string
+		initial   v1alpha1.InstallPlanPhase
+		approval  v1alpha1.Approval
+		approved  bool
+		approvals []v1alpha1.InstallPlanApproval
+		expected  v1alpha1.InstallPlanPhase
+	}{
+		{"PlanningApproved", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalManual, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"PlanningApprovals", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalManual, false, approvals, v1alpha1.InstallPlanPhaseInstalling},
+		{"WaitingApproved", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"WaitingNotEnough", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, false, approvals[:1], v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"WaitingApprovals", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, false, approvals, v1alpha1.InstallPlanPhaseInstalling},
+
+		// The Automatic approval mode doesn't bypass the policy
+		{"PlanningAutomatic", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalAutomatic, false, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"WaitingAutomatic", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalAutomatic, false, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"WaitingAutomaticApprovals", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalAutomatic, false, approvals, v1alpha1.InstallPlanPhaseInstalling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := approvalPlan(policy, tt.approvals...)
+			plan.Spec.Approval = tt.approval
+			plan.Spec.Approved = tt.approved
+			plan.Status.Phase = tt.initial
+
+			out, err := transitionInstallPlanState(&mockTransitioner{}, *plan)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.Status.Phase)
+		})
+	}
+}
+
+func TestTransitionInstallPlanUnverifiedApprovals(t *testing.T) {
+	policy := &v1alpha1.ApprovalPolicy{RequiredApprovals: 1}
+	approvals := []v1alpha1.InstallPlanApproval{{Approver: "alice"}}
+
+	tests := []struct {
+		name      string
+		initial   v1alpha1.InstallPlanPhase
+		policy    *v1alpha1.ApprovalPolicy
+		approved  bool
+		approvals []v1alpha1.InstallPlanApproval
+		expected  v1alpha1.InstallPlanPhase
+		condition *v1alpha1.InstallPlanCondition
+	}{
+		{
+			name:      "PlanningApprovals",
+			initial:   v1alpha1.InstallPlanPhasePlanning,
+			policy:    policy,
+			approvals: approvals,
+			expected:  v1alpha1.InstallPlanPhaseRequiresApproval,
+			condition: &v1alpha1.InstallPlanCondition{
+				Type:    v1alpha1.InstallPlanApproved,
+				Status:  corev1.ConditionFalse,
+				Reason:  v1alpha1.InstallPlanReasonApprovalsUnverified,
+				Message: errApprovalsUnverified.Error(),
+			},
+		},
+		{
+			name:      "WaitingApprovals",
+			initial:   v1alpha1.InstallPlanPhaseRequiresApproval,
+			policy:    policy,
+			approvals: approvals,
+			expected:  v1alpha1.InstallPlanPhaseRequiresApproval,
+			condition: &v1alpha1.InstallPlanCondition{
+				Type:    v1alpha1.InstallPlanApproved,
+				Status:  corev1.ConditionFalse,
+				Reason:  v1alpha1.InstallPlanReasonApprovalsUnverified,
+				Message: errApprovalsUnverified.Error(),
+			},
+		},
+		{
+			// Plans without a policy don't need the webhook
+			name:     "WaitingApprovedNoPolicy",
+			initial:  v1alpha1.InstallPlanPhaseRequiresApproval,
+			approved: true,
+			expected: v1alpha1.InstallPlanPhaseInstalling,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := approvalPlan(tt.policy, tt.approvals...)
+			plan.Spec.Approved = tt.approved
+			plan.Status.Phase = tt.initial
+
+			out, err := transitionInstallPlanState(&mockTransitioner{unverifiedApprovals: true}, *plan)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.Status.Phase)
+			cond := out.Status.GetCondition(v1alpha1.InstallPlanApproved)
+			if tt.condition == nil {
+				require.Equal(t, corev1.ConditionUnknown, cond.Status)
+				return
+			}
+			require.Equal(t, tt.condition.Status, cond.Status)
+			require.Equal(t, tt.condition.Reason, cond.Reason)
+			require.Equal(t, tt.condition.Message, cond.Message)
+		})
+	}
+
+	// The operator only verifies approvals when it serves the webhook
+	op, err := NewFakeOperator(nil, nil, nil, nil, nil, "ns")
+	require.NoError(t, err)
+	require.False(t, op.ApprovalsVerified())
+	op.approvalWebhookEnabled = true
+	require.True(t, op.ApprovalsVerified())
+}
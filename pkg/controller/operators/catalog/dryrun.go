@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// serverMetadata are the metadata fields set by the API server or rewritten when a step is installed, which are
+// left out when comparing a step to the cluster
+var serverMetadata = []string{"namespace", "ownerReferences", "uid", "resourceVersion", "generation", "creationTimestamp", "selfLink"}
+
+// DryRunPlan compares each step of a resolved InstallPlan to the cluster without creating anything. Each step
+// records whether it would be created, is already present and identical, or is present but different, along with
+// the differing fields. Steps that can't be compared are recorded as Unknown with the reason.
+func (o *Operator) DryRunPlan(plan *v1alpha1.InstallPlan) error {
+	for i := range plan.Status.Plan {
+		step := &plan.Status.Plan[i]
+		result, stepDiff, err := o.dryRunStep(step.Resource, plan.GetNamespace())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"ip":        plan.GetName(),
+				"namespace": plan.GetNamespace(),
+				"kind":      step.Resource.Kind,
+				"name":      step.Resource.Name,
+			}).WithError(err).Warn("failed to compare step to the cluster")
+			result, stepDiff = v1alpha1.StepDryRunResultUnknown, err.Error()
+		}
+		step.DryRun = result
+		step.DryRunDiff = stepDiff
+	}
+	return nil
+}
+
+// dryRunStep compares a step to the cluster, returning the diff from the existing resource if they differ
+func (o *Operator) dryRunStep(resource v1alpha1.StepResource, namespace string) (v1alpha1.StepDryRunResult, string, error) {
+	if resource.Kind == secretKind {
+		return o.dryRunSecret(resource, namespace)
+	}
+
+	obj, client, err := o.stepObject(resource, namespace)
+	if err != nil {
+		return "", "", err
+	}
+
+	existingObj, err := client.Get(obj.GetName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return v1alpha1.StepDryRunResultWouldCreate, "", nil
+	} else if err != nil {
+		return "", "", err
+	}
+	existing := existingObj.Object
+
+	desired := obj.Object
+	delete(desired, "status")
+	if metadata, ok := desired["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverMetadata {
+			delete(metadata, field)
+		}
+	}
+
+	// Only the fields set by the step are compared, so that fields defaulted by the API server don't count as changes.
+	existingFields := commonFields(desired, existing)
+	if reflect.DeepEqual(desired, existingFields) {
+		return v1alpha1.StepDryRunResultPresentIdentical, "", nil
+	}
+	return v1alpha1.StepDryRunResultPresentDifferent, diff.ObjectReflectDiff(existingFields, desired), nil
+}
+
+// dryRunSecret compares a step's secret in the catalog source namespace to the copy in the InstallPlan namespace.
+// The diff only names the differing fields, to keep secret data out of the InstallPlan.
+func (o *Operator) dryRunSecret(resource v1alpha1.StepResource, namespace string) (v1alpha1.StepDryRunResult, string, error) {
+	secret, err := o.catalogSecret(resource)
+	if err != nil {
+		return "", "", err
+	}
+
+	existing, err := o.OpClient.KubernetesInterface().CoreV1().Secrets(namespace).Get(resource.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return v1alpha1.StepDryRunResultWouldCreate, "", nil
+	} else if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case existing.Type != secret.Type:
+		return v1alpha1.StepDryRunResultPresentDifferent, "type differs", nil
+	case !reflect.DeepEqual(existing.Data, secret.Data):
+		return v1alpha1.StepDryRunResultPresentDifferent, "data differs", nil
+	default:
+		return v1alpha1.StepDryRunResultPresentIdentical, "", nil
+	}
+}
+
+// commonFields returns the parts of an existing object that are set in the desired object. Lists of the same length
+// are compared element by element, while other lists are returned whole.
+func commonFields(desired, existing interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return existing
+		}
+		common := map[string]interface{}{}
+		for key, value := range d {
+			if existingValue, ok := e[key]; ok {
+				common[key] = commonFields(value, existingValue)
+			} else if value == nil {
+				// An unset field matches an explicit null.
+				common[key] = nil
+			}
+		}
+		return common
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok || len(e) != len(d) {
+			return existing
+		}
+		common := make([]interface{}, len(e))
+		for i := range e {
+			common[i] = commonFields(d[i], e[i])
+		}
+		return common
+	default:
+		return existing
+	}
+}
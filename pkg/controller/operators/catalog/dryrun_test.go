@@ -0,0 +1,244 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	namespace := "ns"
+	catalogNamespace := "olm"
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: catalogNamespace},
+		Data:       map[string][]byte{"token": []byte("abc")},
+	}
+	copiedSecret := pullSecret.DeepCopy()
+	copiedSecret.SetNamespace(namespace)
+	staleSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "stale-secret", Namespace: catalogNamespace},
+		Data:       map[string][]byte{"token": []byte("new")},
+	}
+	staleCopy := staleSecret.DeepCopy()
+	staleCopy.SetNamespace(namespace)
+	staleCopy.Data["token"] = []byte("old")
+	newSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "new-secret", Namespace: catalogNamespace}}
+
+	op, err := NewFakeOperator(nil, []runtime.Object{pullSecret, copiedSecret, staleSecret, staleCopy, newSecret}, nil, nil, nil, namespace)
+	require.NoError(t, err)
+
+	server := newAPIServer(t)
+	defer server.Close()
+	server.serve(t, op)
+	server.add("/api/v1/namespaces/ns/configmaps/etcd-config", map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "etcd-config",
+			"namespace":         namespace,
+			"uid":               "abc",
+			"resourceVersion":   "3",
+			"creationTimestamp": "2018-10-01T00:00:00Z",
+		},
+		"data": map[string]interface{}{"size": "3"},
+	})
+	server.add("/api/v1/namespaces/ns/configmaps/etcd-flags", map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "etcd-flags", "namespace": namespace},
+		"data":       map[string]interface{}{"debug": "false", "extra": "true"},
+	})
+
+	configMap := func(name string, data map[string]interface{}) v1alpha1.StepResource {
+		return v1alpha1.StepResource{
+			Version: "v1", Kind: "ConfigMap", Name: name,
+			Manifest: manifest(t, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": name, "namespace": "other", "creationTimestamp": nil},
+				"data":       data,
+			}),
+		}
+	}
+	secret := func(name string) v1alpha1.StepResource {
+		return v1alpha1.StepResource{Version: "v1", Kind: secretKind, Name: name, CatalogSourceNamespace: catalogNamespace}
+	}
+
+	plan := &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+		Status: v1alpha1.InstallPlanStatus{
+			Phase: v1alpha1.InstallPlanPhasePlanning,
+			Plan: []v1alpha1.Step{
+				{Resource: configMap("etcd-new", map[string]interface{}{"size": "3"}), Status: v1alpha1.StepStatusUnknown},
+				{Resource: configMap("etcd-config", map[string]interface{}{"size": "3"}), Status: v1alpha1.StepStatusUnknown},
+				{Resource: configMap("etcd-flags", map[string]interface{}{"debug": "true"}), Status: v1alpha1.StepStatusUnknown},
+				{Resource: secret(pullSecret.GetName()), Status: v1alpha1.StepStatusUnknown},
+				{Resource: secret(staleSecret.GetName()), Status: v1alpha1.StepStatusUnknown},
+				{Resource: secret(newSecret.GetName()), Status: v1alpha1.StepStatusUnknown},
+				{
+					Resource: v1alpha1.StepResource{
+						Group: "example.com", Version: "v1", Kind: "Widget", Name: "etcd-widget",
+						Manifest: manifest(t, map[string]interface{}{
+							"apiVersion": "example.com/v1",
+							"kind":       "Widget",
+							"metadata":   map[string]interface{}{"name": "etcd-widget"},
+						}),
+					},
+					Status: v1alpha1.StepStatusUnknown,
+				},
+			},
+		},
+	}
+
+	require.NoError(t, op.DryRunPlan(plan))
+
+	expected := []v1alpha1.StepDryRunResult{
+		v1alpha1.StepDryRunResultWouldCreate,
+		v1alpha1.StepDryRunResultPresentIdentical,
+		v1alpha1.StepDryRunResultPresentDifferent,
+		v1alpha1.StepDryRunResultPresentIdentical,
+		v1alpha1.StepDryRunResultPresentDifferent,
+		v1alpha1.StepDryRunResultWouldCreate,
+		v1alpha1.StepDryRunResultUnknown,
+	}
+	for i, step := range plan.Status.Plan {
+		require.Equal(t, expected[i], step.DryRun, "step %d", i)
+		require.Equal(t, v1alpha1.StepStatusUnknown, step.Status)
+	}
+
+	// Fields that aren't in the step aren't part of the diff.
+	require.Contains(t, plan.Status.Plan[2].DryRunDiff, "debug")
+	require.NotContains(t, plan.Status.Plan[2].DryRunDiff, "extra")
+
+	// Secret data is kept out of the diff.
+	require.Equal(t, "data differs", plan.Status.Plan[4].DryRunDiff)
+	require.NotEmpty(t, plan.Status.Plan[6].DryRunDiff)
+
+	// Nothing was created.
+	require.Len(t, server.created, 2)
+	_, err = op.OpClient.KubernetesInterface().CoreV1().Secrets(namespace).Get(newSecret.GetName(), metav1.GetOptions{})
+	require.Error(t, err)
+}
+
+func TestTransitionInstallPlanDryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		initial    v1alpha1.InstallPlanPhase
+		approval   v1alpha1.Approval
+		approved   bool
+		dryRun     bool
+		transError error
+		expected   v1alpha1.InstallPlanPhase
+	}{
+		{"PlanningAutomatic", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalAutomatic, false, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"PlanningApproved", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalManual, true, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"PlanningError", v1alpha1.InstallPlanPhasePlanning, v1alpha1.ApprovalManual, false, true, errors.New("dry run error"), v1alpha1.InstallPlanPhaseFailed},
+		{"WaitingApproved", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, true, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"WaitingAutomatic", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalAutomatic, false, true, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"DisabledApproved", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, true, false, nil, v1alpha1.InstallPlanPhaseInstalling},
+		{"DisabledNotApproved", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalManual, false, false, nil, v1alpha1.InstallPlanPhaseRequiresApproval},
+		{"DisabledAutomatic", v1alpha1.InstallPlanPhaseRequiresApproval, v1alpha1.ApprovalAutomatic, false, false, nil, v1alpha1.InstallPlanPhaseInstalling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := v1alpha1.InstallPlan{
+				Spec: v1alpha1.InstallPlanSpec{
+					Approval: tt.approval,
+					Approved: tt.approved,
+					DryRun:   tt.dryRun,
+				},
+				Status: v1alpha1.InstallPlanStatus{
+					Phase: tt.initial,
+				},
+			}
+
+			out, _ := transitionInstallPlanState(&mockTransitioner{err: tt.transError}, plan)
+			require.Equal(t, tt.expected, out.Status.Phase)
+		})
+	}
+}
+
+func TestTransitionInstallPlanRefreshDryRun(t *testing.T) {
+	errMsg := "dry run test error"
+	dryRunStep := v1alpha1.Step{
+		Resource:   v1alpha1.StepResource{Kind: serviceAccountKind, Name: "sa"},
+		Status:     v1alpha1.StepStatusUnknown,
+		DryRun:     v1alpha1.StepDryRunResultPresentDifferent,
+		DryRunDiff: "diff",
+	}
+	clearedStep := dryRunStep
+	clearedStep.DryRun = ""
+	clearedStep.DryRunDiff = ""
+
+	tests := []struct {
+		name            string
+		dryRun          bool
+		approved        bool
+		transError      error
+		err             string
+		expectedDryRuns int
+		expected        v1alpha1.InstallPlanPhase
+		expectedStep    v1alpha1.Step
+	}{
+		{
+			name:            "RefreshesDryRun",
+			dryRun:          true,
+			approved:        true,
+			expectedDryRuns: 1,
+			expected:        v1alpha1.InstallPlanPhaseRequiresApproval,
+			expectedStep:    dryRunStep,
+		},
+		{
+			name:            "RefreshFails",
+			dryRun:          true,
+			transError:      errors.New(errMsg),
+			err:             errMsg,
+			expectedDryRuns: 1,
+			expected:        v1alpha1.InstallPlanPhaseRequiresApproval,
+			expectedStep:    dryRunStep,
+		},
+		{
+			name:         "DryRunOffNotApproved",
+			expected:     v1alpha1.InstallPlanPhaseRequiresApproval,
+			expectedStep: clearedStep,
+		},
+		{
+			name:         "DryRunOffApproved",
+			approved:     true,
+			expected:     v1alpha1.InstallPlanPhaseInstalling,
+			expectedStep: clearedStep,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := v1alpha1.InstallPlan{
+				Spec: v1alpha1.InstallPlanSpec{
+					Approval: v1alpha1.ApprovalManual,
+					Approved: tt.approved,
+					DryRun:   tt.dryRun,
+				},
+				Status: v1alpha1.InstallPlanStatus{
+					Phase: v1alpha1.InstallPlanPhaseRequiresApproval,
+					Plan:  []v1alpha1.Step{dryRunStep},
+				},
+			}
+
+			transitioner := &mockTransitioner{err: tt.transError}
+			out, err := transitionInstallPlanState(transitioner, plan)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedDryRuns, transitioner.dryRuns)
+			require.Equal(t, tt.expected, out.Status.Phase)
+			require.Equal(t, []v1alpha1.Step{tt.expectedStep}, out.Status.Plan)
+		})
+	}
+}
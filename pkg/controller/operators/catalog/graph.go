@@ -0,0 +1,185 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// maxParallelSteps bounds the number of InstallPlan steps that are executed at once
+const maxParallelSteps = 4
+
+// stepKey identifies a step in the dependencies of other steps
+func stepKey(resource v1alpha1.StepResource) string {
+	return resource.Kind + "/" + resource.Name
+}
+
+// setStepDependencies records on each step the steps of the same InstallPlan that must complete before it:
+//   - CRDs before the CSVs that own or require them
+//   - CSVs before the resources they own
+//   - ServiceAccounts, Roles and ClusterRoles before the bindings that reference them
+func setStepDependencies(steps []v1alpha1.Step) error {
+	keys := map[string]struct{}{}
+	for _, step := range steps {
+		keys[stepKey(step.Resource)] = struct{}{}
+	}
+
+	for i := range steps {
+		dependencies, err := stepDependencies(steps[i].Resource)
+		if err != nil {
+			return fmt.Errorf("%s %s: %s", steps[i].Resource.Kind, steps[i].Resource.Name, err)
+		}
+
+		// Only steps of the plan are dependencies, resources outside of it are expected to exist already.
+		set := map[string]struct{}{}
+		for _, key := range dependencies {
+			if _, ok := keys[key]; ok && key != stepKey(steps[i].Resource) {
+				set[key] = struct{}{}
+			}
+		}
+
+		steps[i].DependsOn = nil
+		for key := range set {
+			steps[i].DependsOn = append(steps[i].DependsOn, key)
+		}
+		sort.Strings(steps[i].DependsOn)
+	}
+	return nil
+}
+
+// stepDependencies returns the keys of the steps a step's resource depends on
+func stepDependencies(resource v1alpha1.StepResource) ([]string, error) {
+	if resource.Manifest == "" {
+		return nil, nil
+	}
+
+	var obj struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(resource.Manifest), &obj); err != nil {
+		return nil, err
+	}
+
+	var dependencies []string
+	for _, owner := range obj.Metadata.OwnerReferences {
+		if owner.Kind == v1alpha1.ClusterServiceVersionKind {
+			dependencies = append(dependencies, stepKey(v1alpha1.StepResource{Kind: owner.Kind, Name: owner.Name}))
+		}
+	}
+
+	switch resource.Kind {
+	case v1alpha1.ClusterServiceVersionKind:
+		var csv v1alpha1.ClusterServiceVersion
+		if err := json.Unmarshal([]byte(resource.Manifest), &csv); err != nil {
+			return nil, err
+		}
+		crds := append(csv.Spec.CustomResourceDefinitions.Owned, csv.Spec.CustomResourceDefinitions.Required...)
+		for _, crd := range crds {
+			dependencies = append(dependencies, stepKey(v1alpha1.StepResource{Kind: crdKind, Name: crd.Name}))
+		}
+
+	case roleBindingKind, clusterRoleBindingKind:
+		// RoleBindings and ClusterRoleBindings have the same subjects and role reference.
+		var binding rbacv1.RoleBinding
+		if err := json.Unmarshal([]byte(resource.Manifest), &binding); err != nil {
+			return nil, err
+		}
+		for _, subject := range binding.Subjects {
+			if subject.Kind == rbacv1.ServiceAccountKind {
+				dependencies = append(dependencies, stepKey(v1alpha1.StepResource{Kind: serviceAccountKind, Name: subject.Name}))
+			}
+		}
+		dependencies = append(dependencies, stepKey(v1alpha1.StepResource{Kind: binding.RoleRef.Kind, Name: binding.RoleRef.Name}))
+	}
+
+	return dependencies, nil
+}
+
+// readySteps returns the indexes of the incomplete steps whose dependencies have completed, along with the number of
+// incomplete steps
+func readySteps(steps []v1alpha1.Step) ([]int, int, error) {
+	complete := map[string]bool{}
+	for _, step := range steps {
+		complete[stepKey(step.Resource)] = step.Status == v1alpha1.StepStatusPresent || step.Status == v1alpha1.StepStatusCreated
+	}
+
+	var ready []int
+	incomplete := 0
+	for i, step := range steps {
+		switch step.Status {
+		case v1alpha1.StepStatusPresent, v1alpha1.StepStatusCreated:
+			continue
+		case v1alpha1.StepStatusUnknown, v1alpha1.StepStatusNotPresent, v1alpha1.StepStatusFailed:
+		default:
+			return nil, 0, v1alpha1.ErrInvalidInstallPlan
+		}
+
+		incomplete++
+		isReady := true
+		for _, key := range step.DependsOn {
+			if !complete[key] {
+				isReady = false
+				break
+			}
+		}
+		if isReady {
+			ready = append(ready, i)
+		}
+	}
+	return ready, incomplete, nil
+}
+
+// rollbackOrder returns the indexes of the steps in the order they are rolled back, which puts every step after the
+// steps that depend on it. Steps that don't depend on each other are rolled back in reverse order, and so are steps
+// that form a dependency cycle.
+func rollbackOrder(steps []v1alpha1.Step) []int {
+	indexes := map[string]int{}
+	for i, step := range steps {
+		indexes[stepKey(step.Resource)] = i
+	}
+
+	// dependents counts the steps depending on each step that haven't been ordered yet
+	dependents := make([]int, len(steps))
+	for _, step := range steps {
+		for _, key := range step.DependsOn {
+			if j, ok := indexes[key]; ok {
+				dependents[j]++
+			}
+		}
+	}
+
+	order := make([]int, 0, len(steps))
+	ordered := make([]bool, len(steps))
+	for len(order) < len(steps) {
+		next, cycle := -1, -1
+		for i := len(steps) - 1; i >= 0; i-- {
+			if ordered[i] {
+				continue
+			}
+			if cycle < 0 {
+				cycle = i
+			}
+			if dependents[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			next = cycle
+		}
+
+		ordered[next] = true
+		order = append(order, next)
+		for _, key := range steps[next].DependsOn {
+			if j, ok := indexes[key]; ok {
+				dependents[j]--
+			}
+		}
+	}
+	return order
+}
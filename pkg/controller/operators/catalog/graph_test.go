@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
+)
+
+// etcdSteps returns the steps of an InstallPlan for a CSV that owns a CRD and binds a ClusterRole to its
+// ServiceAccount, in an order that doesn't satisfy their dependencies
+func etcdSteps(t *testing.T, namespace string) []v1alpha1.Step {
+	etcdCRD := crd("etcdclusters.etcd.database.coreos.com")
+	etcdCSV := csv("etcdoperator.v0.9.2", []string{etcdCRD.GetName()}, nil)
+	etcdCSV.SetNamespace(namespace)
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator", Namespace: namespace}}
+	role := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator"}}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: sa.GetName(), Namespace: namespace}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: clusterRoleKind, Name: role.GetName()},
+	}
+	for _, obj := range []metav1.Object{sa, role, binding} {
+		ownerutil.AddNonBlockingOwner(obj, &etcdCSV)
+	}
+
+	var steps []v1alpha1.Step
+	for _, obj := range []struct {
+		obj  metav1.Object
+		kind string
+	}{
+		{binding, clusterRoleBindingKind},
+		{sa, serviceAccountKind},
+		{role, clusterRoleKind},
+		{&etcdCSV, v1alpha1.ClusterServiceVersionKind},
+		{&etcdCRD, crdKind},
+	} {
+		manifest, err := json.Marshal(obj.obj)
+		require.NoError(t, err)
+		steps = append(steps, v1alpha1.Step{
+			Resource: v1alpha1.StepResource{Kind: obj.kind, Name: obj.obj.GetName(), Manifest: string(manifest)},
+			Status:   v1alpha1.StepStatusUnknown,
+		})
+	}
+	steps = append(steps, v1alpha1.Step{
+		Resource: v1alpha1.StepResource{Kind: secretKind, Name: "pull-secret"},
+		Status:   v1alpha1.StepStatusPresent,
+	})
+	return steps
+}
+
+func TestSetStepDependencies(t *testing.T) {
+	steps := etcdSteps(t, "ns")
+	require.NoError(t, setStepDependencies(steps))
+
+	dependencies := map[string][]string{}
+	for _, step := range steps {
+		dependencies[stepKey(step.Resource)] = step.DependsOn
+	}
+	require.Equal(t, map[string][]string{
+		"ClusterRoleBinding/etcd-operator": {
+			"ClusterRole/etcd-operator",
+			"ClusterServiceVersion/etcdoperator.v0.9.2",
+			"ServiceAccount/etcd-operator",
+		},
+		"ServiceAccount/etcd-operator":                                   {"ClusterServiceVersion/etcdoperator.v0.9.2"},
+		"ClusterRole/etcd-operator":                                      {"ClusterServiceVersion/etcdoperator.v0.9.2"},
+		"ClusterServiceVersion/etcdoperator.v0.9.2":                      {"CustomResourceDefinition/etcdclusters.etcd.database.coreos.com"},
+		"CustomResourceDefinition/etcdclusters.etcd.database.coreos.com": nil,
+		"Secret/pull-secret":                                             nil,
+	}, dependencies)
+}
+
+func TestReadySteps(t *testing.T) {
+	steps := etcdSteps(t, "ns")
+	require.NoError(t, setStepDependencies(steps))
+
+	// Only the CRD can be created first.
+	ready, incomplete, err := readySteps(steps)
+	require.NoError(t, err)
+	require.Equal(t, []int{4}, ready)
+	require.Equal(t, 5, incomplete)
+
+	steps[4].Status = v1alpha1.StepStatusCreated
+	steps[3].Status = v1alpha1.StepStatusPresent
+	ready, incomplete, err = readySteps(steps)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, ready)
+	require.Equal(t, 3, incomplete)
+
+	// Failed steps are ready to be retried.
+	steps[1].Status = v1alpha1.StepStatusFailed
+	steps[2].Status = v1alpha1.StepStatusCreated
+	ready, incomplete, err = readySteps(steps)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ready)
+	require.Equal(t, 2, incomplete)
+
+	// Circular dependencies leave nothing ready.
+	steps[1].DependsOn = []string{stepKey(steps[0].Resource)}
+	ready, incomplete, err = readySteps(steps)
+	require.NoError(t, err)
+	require.Empty(t, ready)
+	require.Equal(t, 2, incomplete)
+
+	steps[0].Status = "Bogus"
+	_, _, err = readySteps(steps)
+	require.Equal(t, v1alpha1.ErrInvalidInstallPlan, err)
+}
+
+func TestRollbackOrder(t *testing.T) {
+	// Without dependencies, steps are rolled back in reverse order.
+	steps := etcdSteps(t, "ns")
+	require.Equal(t, []int{5, 4, 3, 2, 1, 0}, rollbackOrder(steps))
+
+	// Steps are rolled back before the steps they depend on.
+	require.NoError(t, setStepDependencies(steps))
+	require.Equal(t, []int{5, 0, 2, 1, 3, 4}, rollbackOrder(steps))
+
+	// Circular dependencies fall back to reverse order.
+	steps[4].DependsOn = []string{stepKey(steps[3].Resource)}
+	require.Equal(t, []int{5, 0, 2, 1, 4, 3}, rollbackOrder(steps))
+}
+
+func TestExecutePlanDependencyOrder(t *testing.T) {
+	namespace := "ns"
+	plan := &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+		Spec:       v1alpha1.InstallPlanSpec{ClusterServiceVersionNames: []string{"etcdoperator.v0.9.2"}},
+		Status: v1alpha1.InstallPlanStatus{
+			Phase: v1alpha1.InstallPlanPhaseInstalling,
+			Plan:  etcdSteps(t, namespace),
+		},
+	}
+
+	op, err := NewFakeOperator([]runtime.Object{plan}, nil, nil, nil, nil, namespace)
+	require.NoError(t, err)
+
+	// The owned resources can only be created once their owner CSV exists.
+	require.NoError(t, op.ExecutePlan(plan))
+	for _, step := range plan.Status.Plan {
+		require.Contains(t, []v1alpha1.StepStatus{v1alpha1.StepStatusCreated, v1alpha1.StepStatusPresent}, step.Status)
+	}
+	_, err = op.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Get("etcdclusters.etcd.database.coreos.com", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	var created []string
+	for _, action := range op.OpClient.KubernetesInterface().(*k8sfake.Clientset).Actions() {
+		if action.GetVerb() == "create" {
+			created = append(created, action.(k8stesting.CreateAction).GetResource().Resource)
+		}
+	}
+	require.Equal(t, "clusterrolebindings", created[len(created)-1])
+
+	// The dependency graph is recorded in the status.
+	saved, err := op.client.OperatorsV1alpha1().InstallPlans(namespace).Get(plan.GetName(), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"CustomResourceDefinition/etcdclusters.etcd.database.coreos.com"}, saved.Status.Plan[3].DependsOn)
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+)
+
+// syncGRPCCatalogSource connects a grpc catalog source to its registry server. The connection is kept in the catalog
+// snapshot and reused across syncs; a new snapshot is only published when the address or the served contents change.
+func (o *Operator) syncGRPCCatalogSource(catsrc *v1alpha1.CatalogSource) error {
+	if catsrc.Spec.Address == "" {
+		err := fmt.Errorf("catalog source %s has no registry address", catsrc.GetName())
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonNoAddress, err)
+	}
+
+	sourceKey := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
+	catalogs := o.catalogs()
+	src, ok := catalogs.sources[sourceKey].(*registry.GRPCSource)
+	connected := ok && src.Address() == catsrc.Spec.Address
+	if !connected {
+		var err error
+		src, err = registry.NewGRPCSource(catsrc.Spec.Address)
+		if err != nil {
+			return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonRegistryError, err)
+		}
+	}
+
+	contents, digest, err := registryContents(src)
+	if err != nil {
+		if !connected {
+			src.Close()
+		}
+		err = fmt.Errorf("failed to load catalog source from registry at %s: %s", catsrc.Spec.Address, err)
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonRegistryError, err)
+	}
+
+	// Check for catalog source changes
+	service := catsrc.Status.RegistryService
+	if connected && service != nil && service.Address == catsrc.Spec.Address && service.Digest == digest {
+		if catalogs.priorities[sourceKey] != catsrc.Spec.Priority {
+			o.updateCatalog(sourceKey, nil, catsrc.Spec.Priority)
+		}
+		return nil
+	}
+
+	// Update status subresource only after a successful load, so a failed version is retried
+	out := catsrc.DeepCopy()
+	out.Status.RegistryService = &v1alpha1.RegistryServiceStatus{
+		Address: catsrc.Spec.Address,
+		Digest:  digest,
+	}
+	out.Status.LastSync = timeNow()
+	out.Status.Contents = contents
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:    v1alpha1.CatalogSourceReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1alpha1.CatalogSourceReasonLoaded,
+		Message: fmt.Sprintf("loaded registry at %s with digest %s", catsrc.Spec.Address, digest),
+	})
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:   v1alpha1.CatalogSourceLoadFailed,
+		Status: corev1.ConditionFalse,
+	})
+
+	_, err = o.client.OperatorsV1alpha1().CatalogSources(out.GetNamespace()).UpdateStatus(out)
+	if err != nil {
+		if !connected {
+			src.Close()
+		}
+		return fmt.Errorf("failed to update catalog source %s status: %s", out.GetName(), err)
+	}
+
+	// Swap in a snapshot with the new source, which counts as a catalog update even if the connection is reused
+	o.updateCatalog(sourceKey, src, catsrc.Spec.Priority)
+
+	return nil
+}
+
+// registryContents counts the entries served by a registry and computes a digest of its packages,
+// ClusterServiceVersions and CustomResourceDefinitions, so that changes to any of their content are noticed.
+func registryContents(src *registry.GRPCSource) (*v1alpha1.CatalogSourceContents, string, error) {
+	packages, err := src.ListPackages()
+	if err != nil {
+		return nil, "", err
+	}
+	csvs, err := src.ListServices()
+	if err != nil {
+		return nil, "", err
+	}
+	crds, err := src.ListCRDs()
+	if err != nil {
+		return nil, "", err
+	}
+	contents := &v1alpha1.CatalogSourceContents{
+		Packages:                  len(packages),
+		ClusterServiceVersions:    len(csvs),
+		CustomResourceDefinitions: len(crds),
+	}
+
+	// Sort so that the digest doesn't depend on the order the registry serves entries in
+	sort.Slice(csvs, func(i, j int) bool {
+		return csvs[i].GetName() < csvs[j].GetName()
+	})
+	sort.Slice(crds, func(i, j int) bool {
+		return crds[i].GetName() < crds[j].GetName()
+	})
+	served, err := json.Marshal(struct {
+		Packages                  map[string]registry.PackageManifest `json:"packages"`
+		ClusterServiceVersions    []v1alpha1.ClusterServiceVersion    `json:"clusterServiceVersions"`
+		CustomResourceDefinitions []v1beta1.CustomResourceDefinition  `json:"customResourceDefinitions"`
+	}{packages, csvs, crds})
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, fmt.Sprintf("%x", sha256.Sum256(served)), nil
+}
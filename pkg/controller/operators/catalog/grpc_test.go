@@ -0,0 +1,166 @@
+package catalog
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
+)
+
+func grpcCatalogSource(name, address string) *v1alpha1.CatalogSource {
+	return &v1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: v1alpha1.CatalogSourceSpec{
+			SourceType: v1alpha1.SourceTypeGRPC,
+			Address:    address,
+		},
+	}
+}
+
+func TestSyncGRPCCatalogSource(t *testing.T) {
+	catalog, err := registry.NewInMemoryFromDirectory("../../../../deploy/chart/catalog_resources/rh-operators")
+	require.NoError(t, err)
+	csvs, err := catalog.ListServices()
+	require.NoError(t, err)
+	crds, err := catalog.ListCRDs()
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	registry.RegisterRegistryServer(server, catalog)
+	go server.Serve(lis)
+	defer server.Stop()
+	address := lis.Addr().String()
+
+	unavailable, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unavailableAddress := unavailable.Addr().String()
+	require.NoError(t, unavailable.Close())
+
+	catsrc := grpcCatalogSource("registry", address)
+	op, err := NewFakeOperator([]runtime.Object{
+		catsrc,
+		grpcCatalogSource("no-address", ""),
+		grpcCatalogSource("unavailable", unavailableAddress),
+	}, nil, nil, nil, &resolver.ConstraintResolver{}, "ns")
+	require.NoError(t, err)
+
+	// Load the registry's contents
+	require.NoError(t, op.syncCatalogSources(catsrc))
+	updated, err := op.client.OperatorsV1alpha1().CatalogSources("ns").Get("registry", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, updated.Status.RegistryService)
+	require.Equal(t, address, updated.Status.RegistryService.Address)
+	require.NotEmpty(t, updated.Status.RegistryService.Digest)
+	require.Equal(t, &v1alpha1.CatalogSourceContents{
+		Packages:                  len(catalog.AllPackages()),
+		ClusterServiceVersions:    len(csvs),
+		CustomResourceDefinitions: len(crds),
+	}, updated.Status.Contents)
+	require.Equal(t, corev1.ConditionTrue, updated.Status.GetCondition(v1alpha1.CatalogSourceReady).Status)
+	require.Equal(t, corev1.ConditionFalse, updated.Status.GetCondition(v1alpha1.CatalogSourceLoadFailed).Status)
+
+	key := registry.ResourceKey{Name: "registry", Namespace: "ns"}
+	loaded := op.catalogs()
+	source, ok := loaded.sources[key].(*registry.GRPCSource)
+	require.True(t, ok, "registry not loaded")
+	csv, err := source.FindCSVForPackageNameUnderChannel("etcd", "alpha")
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.2", csv.GetName())
+
+	// Unchanged contents keep the connection and the snapshot
+	require.NoError(t, op.syncCatalogSources(updated))
+	require.True(t, loaded == op.catalogs(), "unchanged registry published a new snapshot")
+
+	// Failures are reported in the catalog source's conditions
+	for name, reason := range map[string]v1alpha1.ConditionReason{
+		"no-address":  v1alpha1.CatalogSourceReasonNoAddress,
+		"unavailable": v1alpha1.CatalogSourceReasonRegistryError,
+	} {
+		failing, err := op.client.OperatorsV1alpha1().CatalogSources("ns").Get(name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Error(t, op.syncCatalogSources(failing))
+
+		failed, err := op.client.OperatorsV1alpha1().CatalogSources("ns").Get(name, metav1.GetOptions{})
+		require.NoError(t, err)
+		cond := failed.Status.GetCondition(v1alpha1.CatalogSourceLoadFailed)
+		require.Equal(t, corev1.ConditionTrue, cond.Status, name)
+		require.Equal(t, reason, cond.Reason, name)
+		_, ok := op.catalogs().sources[registry.ResourceKey{Name: name, Namespace: "ns"}]
+		require.False(t, ok, "%s loaded into memory", name)
+	}
+}
+
+func TestRegistryContentsDigest(t *testing.T) {
+	// digest serves a catalog with the given CSV and CRD and returns its digest
+	digest := func(t *testing.T, csv v1alpha1.ClusterServiceVersion, crd v1beta1.CustomResourceDefinition) string {
+		catalog := registry.NewInMem()
+		require.NoError(t, catalog.SetCRDDefinition(crd))
+		catalog.AddOrReplaceService(csv)
+		require.NoError(t, catalog.AddPackageManifest(registry.PackageManifest{
+			PackageName: "rainbows",
+			Channels:    []registry.PackageChannel{{Name: "magical", CurrentCSVName: csv.GetName()}},
+		}))
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server := grpc.NewServer()
+		registry.RegisterRegistryServer(server, catalog)
+		go server.Serve(lis)
+		defer server.Stop()
+
+		src, err := registry.NewGRPCSource(lis.Addr().String())
+		require.NoError(t, err)
+		defer src.Close()
+
+		_, digest, err := registryContents(src)
+		require.NoError(t, err)
+		return digest
+	}
+
+	tests := []struct {
+		name    string
+		change  func(csv *v1alpha1.ClusterServiceVersion, crd *v1beta1.CustomResourceDefinition)
+		changed bool
+	}{
+		{
+			name:    "Unchanged",
+			change:  func(csv *v1alpha1.ClusterServiceVersion, crd *v1beta1.CustomResourceDefinition) {},
+			changed: false,
+		},
+		{
+			name: "CSVContentChanged",
+			change: func(csv *v1alpha1.ClusterServiceVersion, crd *v1beta1.CustomResourceDefinition) {
+				csv.Spec.Description = "changed"
+			},
+			changed: true,
+		},
+		{
+			name: "CRDContentChanged",
+			change: func(csv *v1alpha1.ClusterServiceVersion, crd *v1beta1.CustomResourceDefinition) {
+				crd.Spec.Scope = v1beta1.ClusterScoped
+			},
+			changed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crd := crd("rainbows")
+			csv := csv("rainbows.v1", []string{"rainbows"}, nil)
+			before := digest(t, csv, crd)
+
+			tt.change(&csv, &crd)
+			require.Equal(t, tt.changed, before != digest(t, csv, crd))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+)
+
+// defaultCatalogPollInterval is how often http catalog sources without a poll interval are polled
+const defaultCatalogPollInterval = 5 * time.Minute
+
+// catalogPollInterval returns how often an http catalog source is polled
+func catalogPollInterval(catsrc *v1alpha1.CatalogSource) time.Duration {
+	if catsrc.Spec.PollInterval == nil || catsrc.Spec.PollInterval.Duration <= 0 {
+		return defaultCatalogPollInterval
+	}
+	return catsrc.Spec.PollInterval.Duration
+}
+
+// syncHTTPCatalogSource polls an http catalog source's URL once its poll interval has passed since the last poll, and
+// loads the archive if it changed. The catalog source is requeued for its next poll.
+func (o *Operator) syncHTTPCatalogSource(catsrc *v1alpha1.CatalogSource) error {
+	if catsrc.Spec.URL == "" {
+		err := fmt.Errorf("catalog source %s has no archive URL", catsrc.GetName())
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonNoAddress, err)
+	}
+
+	sourceKey := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
+	catalogs := o.catalogs()
+	_, loaded := catalogs.sources[sourceKey]
+	interval := catalogPollInterval(catsrc)
+
+	// Only an archive from the current URL can be kept
+	previous := catsrc.Status.Archive
+	if !loaded || previous == nil || previous.URL != catsrc.Spec.URL {
+		previous = nil
+	}
+
+	// Wait for the next poll
+	if previous != nil {
+		if wait := previous.LastPoll.Add(interval).Sub(timeNow().Time); wait > 0 {
+			if catalogs.priorities[sourceKey] != catsrc.Spec.Priority {
+				o.updateCatalog(sourceKey, nil, catsrc.Spec.Priority)
+			}
+			o.requeueCatalogSource(catsrc, wait)
+			return nil
+		}
+	}
+
+	etag := ""
+	if previous != nil {
+		etag = previous.ETag
+	}
+	archive, err := registry.FetchArchive(catsrc.Spec.URL, etag)
+	if err != nil {
+		err = fmt.Errorf("failed to fetch catalog archive from %s: %s", catsrc.Spec.URL, err)
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonArchiveError, err)
+	}
+
+	// An unchanged archive only records the poll
+	if archive.NotModified || (previous != nil && archive.Digest == previous.Digest) {
+		out := catsrc.DeepCopy()
+		out.Status.Archive.ETag = archive.ETag
+		out.Status.Archive.LastPoll = timeNow()
+		if _, err := o.client.OperatorsV1alpha1().CatalogSources(out.GetNamespace()).UpdateStatus(out); err != nil {
+			return fmt.Errorf("failed to update catalog source %s status: %s", out.GetName(), err)
+		}
+		if catalogs.priorities[sourceKey] != catsrc.Spec.Priority {
+			o.updateCatalog(sourceKey, nil, catsrc.Spec.Priority)
+		}
+		o.requeueCatalogSource(catsrc, interval)
+		return nil
+	}
+
+	src, err := registry.NewInMemoryFromArchive(bytes.NewReader(archive.Body))
+	if err != nil {
+		err = fmt.Errorf("failed to create catalog source from archive %s: %s", catsrc.Spec.URL, err)
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonArchiveError, err)
+	}
+	contents, err := catalogSourceContents(src)
+	if err != nil {
+		return err
+	}
+
+	// Update status subresource only after a successful load, so a failed archive is retried
+	now := timeNow()
+	out := catsrc.DeepCopy()
+	out.Status.Archive = &v1alpha1.ArchiveStatus{
+		URL:      catsrc.Spec.URL,
+		ETag:     archive.ETag,
+		Digest:   archive.Digest,
+		LastPoll: now,
+	}
+	out.Status.LastSync = now
+	out.Status.Contents = contents
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:    v1alpha1.CatalogSourceReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1alpha1.CatalogSourceReasonLoaded,
+		Message: fmt.Sprintf("loaded archive %s with digest %s", catsrc.Spec.URL, archive.Digest),
+	})
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:   v1alpha1.CatalogSourceLoadFailed,
+		Status: corev1.ConditionFalse,
+	})
+
+	_, err = o.client.OperatorsV1alpha1().CatalogSources(out.GetNamespace()).UpdateStatus(out)
+	if err != nil {
+		return fmt.Errorf("failed to update catalog source %s status: %s", out.GetName(), err)
+	}
+
+	// Swap in a snapshot with the new source
+	o.updateCatalog(sourceKey, src, catsrc.Spec.Priority)
+	o.requeueCatalogSource(catsrc, interval)
+
+	return nil
+}
+
+// requeueCatalogSource syncs the catalog source again after the given delay
+func (o *Operator) requeueCatalogSource(catsrc *v1alpha1.CatalogSource, after time.Duration) {
+	key, err := cache.MetaNamespaceKeyFunc(catsrc)
+	if err != nil {
+		return
+	}
+	o.catsrcQueue.AddAfter(key, after)
+}
@@ -0,0 +1,189 @@
+package catalog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
+)
+
+// catalogDirectoryArchive returns a gzipped tar archive of a catalog directory
+func catalogDirectoryArchive(t *testing.T, directory string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(directory, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tw.Write(contents)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// archiveServer serves a catalog archive with an ETag and counts the full downloads
+type archiveServer struct {
+	mu        sync.Mutex
+	archive   []byte
+	etag      string
+	downloads int
+}
+
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.etag != "" && r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.downloads++
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	w.Write(s.archive)
+}
+
+func (s *archiveServer) serve(archive []byte, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = archive
+	s.etag = etag
+}
+
+func (s *archiveServer) downloadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads
+}
+
+func TestSyncHTTPCatalogSource(t *testing.T) {
+	now := metav1.NewTime(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC))
+	timeNow = func() metav1.Time { return now }
+	defer func() {
+		timeNow = func() metav1.Time { return metav1.NewTime(time.Now().UTC()) }
+	}()
+
+	directory := "../../../../deploy/chart/catalog_resources/rh-operators"
+	catalog, err := registry.NewInMemoryFromDirectory(directory)
+	require.NoError(t, err)
+	archive := catalogDirectoryArchive(t, directory)
+
+	server := &archiveServer{}
+	server.serve(archive, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	catsrc := &v1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: "ns"},
+		Spec: v1alpha1.CatalogSourceSpec{
+			SourceType:   v1alpha1.SourceTypeHTTP,
+			URL:          ts.URL + "/catalog.tar.gz",
+			PollInterval: &metav1.Duration{Duration: time.Minute},
+		},
+	}
+	op, err := NewFakeOperator([]runtime.Object{catsrc}, nil, nil, nil, &resolver.ConstraintResolver{}, "ns")
+	require.NoError(t, err)
+	key := registry.ResourceKey{Name: "archive", Namespace: "ns"}
+	get := func() *v1alpha1.CatalogSource {
+		updated, err := op.client.OperatorsV1alpha1().CatalogSources("ns").Get("archive", metav1.GetOptions{})
+		require.NoError(t, err)
+		return updated
+	}
+
+	// The first sync downloads and loads the archive
+	require.NoError(t, op.syncCatalogSources(catsrc))
+	updated := get()
+	require.NotNil(t, updated.Status.Archive)
+	require.Equal(t, catsrc.Spec.URL, updated.Status.Archive.URL)
+	require.Equal(t, `"v1"`, updated.Status.Archive.ETag)
+	require.NotEmpty(t, updated.Status.Archive.Digest)
+	require.Equal(t, now, updated.Status.Archive.LastPoll)
+	require.Equal(t, len(catalog.AllPackages()), updated.Status.Contents.Packages)
+	require.Equal(t, corev1.ConditionTrue, updated.Status.GetCondition(v1alpha1.CatalogSourceReady).Status)
+	require.Equal(t, 1, server.downloadCount())
+	loaded := op.catalogs()
+	require.Contains(t, loaded.sources, key)
+	digest := updated.Status.Archive.Digest
+
+	// Syncs before the poll interval has passed don't poll
+	now = metav1.NewTime(now.Add(30 * time.Second))
+	require.NoError(t, op.syncCatalogSources(updated))
+	require.Equal(t, 1, server.downloadCount())
+	require.Equal(t, updated.Status.Archive, get().Status.Archive)
+
+	// An unchanged archive isn't downloaded again
+	now = metav1.NewTime(now.Add(time.Minute))
+	require.NoError(t, op.syncCatalogSources(get()))
+	require.Equal(t, 1, server.downloadCount())
+	updated = get()
+	require.Equal(t, now, updated.Status.Archive.LastPoll)
+	require.Equal(t, digest, updated.Status.Archive.Digest)
+	require.True(t, loaded == op.catalogs(), "unchanged archive published a new snapshot")
+
+	// A new ETag with the same contents is downloaded but not loaded again
+	server.serve(archive, `"v2"`)
+	now = metav1.NewTime(now.Add(time.Minute))
+	require.NoError(t, op.syncCatalogSources(get()))
+	require.Equal(t, 2, server.downloadCount())
+	updated = get()
+	require.Equal(t, `"v2"`, updated.Status.Archive.ETag)
+	require.Equal(t, digest, updated.Status.Archive.Digest)
+	require.True(t, loaded == op.catalogs(), "archive with the same digest published a new snapshot")
+
+	// A changed archive is loaded
+	otherDirectory := "../../../../deploy/chart/catalog_resources/certified-operators"
+	other, err := registry.NewInMemoryFromDirectory(otherDirectory)
+	require.NoError(t, err)
+	server.serve(catalogDirectoryArchive(t, otherDirectory), `"v3"`)
+	now = metav1.NewTime(now.Add(time.Minute))
+	require.NoError(t, op.syncCatalogSources(get()))
+	require.Equal(t, 3, server.downloadCount())
+	updated = get()
+	require.NotEqual(t, digest, updated.Status.Archive.Digest)
+	require.Equal(t, len(other.AllPackages()), updated.Status.Contents.Packages)
+	loaded = op.catalogs()
+	require.Equal(t, other.AllPackages(), loaded.sources[key].AllPackages())
+	digest = updated.Status.Archive.Digest
+
+	// An invalid archive is reported and the loaded catalog is kept
+	server.serve([]byte("not an archive"), `"v4"`)
+	now = metav1.NewTime(now.Add(time.Minute))
+	require.Error(t, op.syncCatalogSources(get()))
+	updated = get()
+	cond := updated.Status.GetCondition(v1alpha1.CatalogSourceLoadFailed)
+	require.Equal(t, corev1.ConditionTrue, cond.Status)
+	require.Equal(t, v1alpha1.CatalogSourceReasonArchiveError, cond.Reason)
+	require.Equal(t, digest, updated.Status.Archive.Digest)
+	require.True(t, loaded == op.catalogs(), "invalid archive replaced the loaded catalog")
+}
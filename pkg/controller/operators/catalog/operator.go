@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,8 +12,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	v1beta1ext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
@@ -36,6 +41,13 @@ const (
 	serviceAccountKind     = "ServiceAccount"
 	roleKind               = "Role"
 	roleBindingKind        = "RoleBinding"
+
+	// subscriptionCSVIndex indexes subscriptions by the namespaced names of their installed and current CSVs
+	subscriptionCSVIndex = "csv"
+	// catalogSourceConfigMapIndex indexes catalog sources by the namespaced name of the ConfigMap they load
+	catalogSourceConfigMapIndex = "configmap"
+	// installPlanSubscriptionIndex indexes InstallPlans by the namespaced names of the subscriptions that own them
+	installPlanSubscriptionIndex = "subscription"
 )
 
 //for test stubbing and for ensuring standardization of timezones to UTC
@@ -47,15 +59,26 @@ type Operator struct {
 	*queueinformer.Operator
 	client             versioned.Interface
 	namespace          string
-	sources            map[registry.ResourceKey]registry.Source
+	sources            *catalogSnapshot
 	sourcesLock        sync.RWMutex
-	sourcesLastUpdate  metav1.Time
 	dependencyResolver resolver.DependencyResolver
 	subQueue           workqueue.RateLimitingInterface
+	catsrcQueue        workqueue.RateLimitingInterface
+	dynamicClient      dynamic.Interface
+	restMapper         *restmapper.DeferredDiscoveryRESTMapper
+	subInformers       []cache.SharedIndexInformer
+	catsrcInformers    []cache.SharedIndexInformer
+	ipInformers        []cache.SharedIndexInformer
+
+	// installPlanHistoryLimit is the number of Complete InstallPlans kept per Subscription, negative to keep all
+	installPlanHistoryLimit int
+
+	// approvalWebhookEnabled is true if the approval webhook verifies the approvals added to InstallPlans
+	approvalWebhookEnabled bool
 }
 
 // NewOperator creates a new Catalog Operator.
-func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNamespace string, watchedNamespaces ...string) (*Operator, error) {
+func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNamespace string, installPlanHistoryLimit int, approvalWebhookEnabled bool, watchedNamespaces ...string) (*Operator, error) {
 	// Default to watching all namespaces.
 	if watchedNamespaces == nil {
 		watchedNamespaces = []string{metav1.NamespaceAll}
@@ -67,20 +90,41 @@ func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNa
 		return nil, err
 	}
 
+	// Create a client for the kinds of InstallPlan steps without a typed client
+	dynamicClient, err := client.NewDynamicClient(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create an informer for each watched namespace.
 	ipSharedIndexInformers := []cache.SharedIndexInformer{}
 	subSharedIndexInformers := []cache.SharedIndexInformer{}
+	csvSharedIndexInformers := []cache.SharedIndexInformer{}
 	for _, namespace := range watchedNamespaces {
 		nsInformerFactory := externalversions.NewSharedInformerFactoryWithOptions(crClient, wakeupInterval, externalversions.WithNamespace(namespace))
-		ipSharedIndexInformers = append(ipSharedIndexInformers, nsInformerFactory.Operators().V1alpha1().InstallPlans().Informer())
-		subSharedIndexInformers = append(subSharedIndexInformers, nsInformerFactory.Operators().V1alpha1().Subscriptions().Informer())
+		ipInformer := nsInformerFactory.Operators().V1alpha1().InstallPlans().Informer()
+		if err := ipInformer.AddIndexers(cache.Indexers{installPlanSubscriptionIndex: installPlanSubscriptionIndexFunc}); err != nil {
+			return nil, err
+		}
+		ipSharedIndexInformers = append(ipSharedIndexInformers, ipInformer)
+		subInformer := nsInformerFactory.Operators().V1alpha1().Subscriptions().Informer()
+		if err := subInformer.AddIndexers(cache.Indexers{subscriptionCSVIndex: subscriptionCSVIndexFunc}); err != nil {
+			return nil, err
+		}
+		subSharedIndexInformers = append(subSharedIndexInformers, subInformer)
+		csvSharedIndexInformers = append(csvSharedIndexInformers, nsInformerFactory.Operators().V1alpha1().ClusterServiceVersions().Informer())
 	}
 
-	// Create an informer for each catalog namespace
+	// Create an informer for each catalog namespace. Catalogs in the operator namespace are global, catalogs in the
+	// watched namespaces are only available to that namespace.
 	catsrcSharedIndexInformers := []cache.SharedIndexInformer{}
-	for _, namespace := range []string{operatorNamespace} {
+	for _, namespace := range catalogNamespaces(operatorNamespace, watchedNamespaces) {
 		nsInformerFactory := externalversions.NewSharedInformerFactoryWithOptions(crClient, wakeupInterval, externalversions.WithNamespace(namespace))
-		catsrcSharedIndexInformers = append(catsrcSharedIndexInformers, nsInformerFactory.Operators().V1alpha1().CatalogSources().Informer())
+		catsrcInformer := nsInformerFactory.Operators().V1alpha1().CatalogSources().Informer()
+		if err := catsrcInformer.AddIndexers(cache.Indexers{catalogSourceConfigMapIndex: catalogSourceConfigMapIndexFunc}); err != nil {
+			return nil, err
+		}
+		catsrcSharedIndexInformers = append(catsrcSharedIndexInformers, catsrcInformer)
 	}
 
 	// Create a new queueinformer-based operator.
@@ -89,22 +133,37 @@ func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNa
 		return nil, err
 	}
 
+	// Create a ConfigMap informer for each catalog namespace, so that catalog sources don't wait for a resync to see
+	// changes to their ConfigMap.
+	configMapSharedIndexInformers := []cache.SharedIndexInformer{}
+	for _, namespace := range catalogNamespaces(operatorNamespace, watchedNamespaces) {
+		nsInformerFactory := informers.NewSharedInformerFactoryWithOptions(queueOperator.OpClient.KubernetesInterface(), wakeupInterval, informers.WithNamespace(namespace))
+		configMapSharedIndexInformers = append(configMapSharedIndexInformers, nsInformerFactory.Core().V1().ConfigMaps().Informer())
+	}
+
 	// Allocate the new instance of an Operator.
 	op := &Operator{
-		Operator:           queueOperator,
-		client:             crClient,
-		namespace:          operatorNamespace,
-		sources:            make(map[registry.ResourceKey]registry.Source),
-		dependencyResolver: &resolver.MultiSourceResolver{},
+		Operator:                queueOperator,
+		client:                  crClient,
+		namespace:               operatorNamespace,
+		sources:                 newCatalogSnapshot(),
+		dependencyResolver:      &resolver.ConstraintResolver{},
+		dynamicClient:           dynamicClient,
+		restMapper:              newRESTMapper(queueOperator.OpClient.KubernetesInterface().Discovery()),
+		subInformers:            subSharedIndexInformers,
+		catsrcInformers:         catsrcSharedIndexInformers,
+		ipInformers:             ipSharedIndexInformers,
+		installPlanHistoryLimit: installPlanHistoryLimit,
+		approvalWebhookEnabled:  approvalWebhookEnabled,
 	}
 
 	// Register CatalogSource informers.
-	catsrcQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources")
+	op.catsrcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources")
 	catsrcQueueInformer := queueinformer.New(
-		catsrcQueue,
+		op.catsrcQueue,
 		catsrcSharedIndexInformers,
 		op.syncCatalogSources,
-		nil,
+		op.catalogSourceEventHandlers(op.catsrcQueue),
 		"catsrc",
 		metrics.NewMetricsCatalogSource(op.Operator.OpClient),
 	)
@@ -112,13 +171,27 @@ func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNa
 		op.RegisterQueueInformer(informer)
 	}
 
+	// Register ConfigMap informers.
+	configMapQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmaps")
+	configMapQueueInformers := queueinformer.New(
+		configMapQueue,
+		configMapSharedIndexInformers,
+		op.syncConfigMaps,
+		op.configMapEventHandlers(configMapQueue),
+		"configmap",
+		metrics.NewMetricsNil(),
+	)
+	for _, informer := range configMapQueueInformers {
+		op.RegisterQueueInformer(informer)
+	}
+
 	// Register InstallPlan informers.
 	ipQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "installplans")
 	ipQueueInformers := queueinformer.New(
 		ipQueue,
 		ipSharedIndexInformers,
 		op.syncInstallPlans,
-		nil,
+		op.installPlanEventHandlers(ipQueue),
 		"installplan",
 		metrics.NewMetricsInstallPlan(op.Operator.OpClient),
 	)
@@ -141,6 +214,20 @@ func NewOperator(kubeconfigPath string, wakeupInterval time.Duration, operatorNa
 		op.RegisterQueueInformer(informer)
 	}
 
+	// Register ClusterServiceVersion informers, so that subscriptions see changes to their installed CSV.
+	csvQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "clusterserviceversions")
+	csvQueueInformers := queueinformer.New(
+		csvQueue,
+		csvSharedIndexInformers,
+		op.syncClusterServiceVersions,
+		nil,
+		"csv",
+		metrics.NewMetricsNil(),
+	)
+	for _, informer := range csvQueueInformers {
+		op.RegisterQueueInformer(informer)
+	}
+
 	return op, nil
 }
 
@@ -151,23 +238,44 @@ func (o *Operator) syncCatalogSources(obj interface{}) (syncError error) {
 		return fmt.Errorf("casting CatalogSource failed")
 	}
 
+	switch catsrc.Spec.SourceType {
+	case v1alpha1.SourceTypeGRPC:
+		return o.syncGRPCCatalogSource(catsrc)
+	case v1alpha1.SourceTypeHTTP:
+		return o.syncHTTPCatalogSource(catsrc)
+	}
+
 	// Get the catalog source's config map
 	configMap, err := o.OpClient.KubernetesInterface().CoreV1().ConfigMaps(catsrc.GetNamespace()).Get(catsrc.Spec.ConfigMap, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to get catalog config map %s when updating status: %s", catsrc.Spec.ConfigMap, err)
+		err = fmt.Errorf("failed to get catalog config map %s when updating status: %s", catsrc.Spec.ConfigMap, err)
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonConfigMapNotFound, err)
 	}
 
-	o.sourcesLock.Lock()
-	defer o.sourcesLock.Unlock()
 	sourceKey := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
-	_, ok = o.sources[sourceKey]
+	catalogs := o.catalogs()
+	_, ok = catalogs.sources[sourceKey]
 
 	// Check for catalog source changes
 	if ok && catsrc.Status.ConfigMapResource != nil && catsrc.Status.ConfigMapResource.Name == configMap.GetName() && catsrc.Status.ConfigMapResource.ResourceVersion == configMap.GetResourceVersion() {
+		if catalogs.priorities[sourceKey] != catsrc.Spec.Priority {
+			o.updateCatalog(sourceKey, nil, catsrc.Spec.Priority)
+		}
 		return nil
 	}
 
-	// Update status subresource
+	// Create a new in-mem registry
+	src, err := registry.NewInMemoryFromConfigMap(o.OpClient, catsrc.GetNamespace(), catsrc.Spec.ConfigMap)
+	if err != nil {
+		err = fmt.Errorf("failed to create catalog source from ConfigMap %s: %s", catsrc.Spec.ConfigMap, err)
+		return o.reportCatalogSourceLoadFailed(catsrc, v1alpha1.CatalogSourceReasonInvalidConfigMap, err)
+	}
+	contents, err := catalogSourceContents(src)
+	if err != nil {
+		return err
+	}
+
+	// Update status subresource only after a successful load, so a failed version is retried
 	out := catsrc.DeepCopy()
 	out.Status.ConfigMapResource = &v1alpha1.ConfigMapResourceReference{
 		Name:            configMap.GetName(),
@@ -176,23 +284,72 @@ func (o *Operator) syncCatalogSources(obj interface{}) (syncError error) {
 		ResourceVersion: configMap.GetResourceVersion(),
 	}
 	out.Status.LastSync = timeNow()
+	out.Status.Contents = contents
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:    v1alpha1.CatalogSourceReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1alpha1.CatalogSourceReasonLoaded,
+		Message: fmt.Sprintf("loaded ConfigMap %s at resource version %s", configMap.GetName(), configMap.GetResourceVersion()),
+	})
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:   v1alpha1.CatalogSourceLoadFailed,
+		Status: corev1.ConditionFalse,
+	})
 
 	_, err = o.client.OperatorsV1alpha1().CatalogSources(out.GetNamespace()).UpdateStatus(out)
 	if err != nil {
 		return fmt.Errorf("failed to update catalog source %s status: %s", out.GetName(), err)
 	}
 
-	// Create a new in-mem registry
-	src, err := registry.NewInMemoryFromConfigMap(o.OpClient, out.GetNamespace(), out.Spec.ConfigMap)
-	if err != nil {
-		return fmt.Errorf("failed to create catalog source from ConfigMap %s: %s", out.Spec.ConfigMap, err)
+	// Swap in a snapshot with the new source
+	o.updateCatalog(sourceKey, src, catsrc.Spec.Priority)
+
+	return nil
+}
+
+// reportCatalogSourceLoadFailed records a failed load in the catalog source's conditions and returns the load error.
+// The rest of the status is left alone, so that it still describes the last successful load. Unchanged conditions
+// aren't written, to avoid requeueing the catalog source with every failed attempt.
+func (o *Operator) reportCatalogSourceLoadFailed(catsrc *v1alpha1.CatalogSource, reason v1alpha1.ConditionReason, loadErr error) error {
+	existing := catsrc.Status.GetCondition(v1alpha1.CatalogSourceLoadFailed)
+	if existing.Status == corev1.ConditionTrue && existing.Reason == reason && existing.Message == loadErr.Error() {
+		return loadErr
 	}
 
-	// Update sources map
-	o.sources[sourceKey] = src
-	o.sourcesLastUpdate = timeNow()
+	out := catsrc.DeepCopy()
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:    v1alpha1.CatalogSourceReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: loadErr.Error(),
+	})
+	out.Status.SetCondition(v1alpha1.CatalogSourceCondition{
+		Type:    v1alpha1.CatalogSourceLoadFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: loadErr.Error(),
+	})
+	if _, err := o.client.OperatorsV1alpha1().CatalogSources(out.GetNamespace()).UpdateStatus(out); err != nil {
+		return fmt.Errorf("%s and failed to update catalog source %s status: %s", loadErr, out.GetName(), err)
+	}
+	return loadErr
+}
 
-	return nil
+// catalogSourceContents counts the entries of a loaded catalog source
+func catalogSourceContents(src *registry.InMem) (*v1alpha1.CatalogSourceContents, error) {
+	csvs, err := src.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	crds, err := src.ListCRDs()
+	if err != nil {
+		return nil, err
+	}
+	return &v1alpha1.CatalogSourceContents{
+		Packages:                  len(src.AllPackages()),
+		ClusterServiceVersions:    len(csvs),
+		CustomResourceDefinitions: len(crds),
+	}, nil
 }
 
 func (o *Operator) syncSubscriptions(obj interface{}) (syncError error) {
@@ -212,10 +369,29 @@ func (o *Operator) syncSubscriptions(obj interface{}) (syncError error) {
 
 	logger.Infof("syncing")
 
+	if sub.GetDeletionTimestamp() != nil {
+		return o.uninstallSubscription(sub)
+	}
+	if updated, err := o.ensureUninstallFinalizer(sub); updated || err != nil {
+		return err
+	}
+
+	// Pruning runs on every sync, including resyncs, so that a lowered history limit takes effect and failed deletes
+	// are retried. Its error doesn't stop the subscription from syncing.
+	pruneErr := o.pruneInstallPlans(sub)
+	if pruneErr != nil {
+		logger.WithError(pruneErr).Warn("error pruning InstallPlans")
+	}
+	defer func() {
+		if syncError == nil {
+			syncError = pruneErr
+		}
+	}()
+
 	var updatedSub *v1alpha1.Subscription
 	updatedSub, syncError = o.syncSubscription(sub)
 
-	if updatedSub == nil || updatedSub.Status.State == sub.Status.State {
+	if updatedSub == nil || !subscriptionStatusChanged(&sub.Status, &updatedSub.Status) {
 		return
 	}
 	if syncError != nil {
@@ -238,6 +414,28 @@ func (o *Operator) syncSubscriptions(obj interface{}) (syncError error) {
 	return
 }
 
+// subscriptionStatusChanged reports whether a sync changed a subscription's status in a way that should be written.
+// Condition timestamps are ignored, since they are refreshed on every sync.
+func subscriptionStatusChanged(old, updated *v1alpha1.SubscriptionStatus) bool {
+	if old.State != updated.State || old.Reason != updated.Reason || old.HeldBackCSV != updated.HeldBackCSV || old.CurrentChannel != updated.CurrentChannel ||
+		old.ObservedGeneration != updated.ObservedGeneration {
+		return true
+	}
+	if !old.NextMaintenanceWindow.Equal(updated.NextMaintenanceWindow) {
+		return true
+	}
+	if len(old.Conditions) != len(updated.Conditions) {
+		return true
+	}
+	for _, cond := range updated.Conditions {
+		existing := old.GetCondition(cond.Type)
+		if existing.Status != cond.Status || existing.Reason != cond.Reason || existing.Message != cond.Message {
+			return true
+		}
+	}
+	return false
+}
+
 func (o *Operator) requeueInstallPlan(name, namespace string) {
 	// we can build the key directly, will need to change if queue uses different key scheme
 	key := fmt.Sprintf("%s/%s", namespace, name)
@@ -245,6 +443,238 @@ func (o *Operator) requeueInstallPlan(name, namespace string) {
 	return
 }
 
+// syncClusterServiceVersions requeues the subscriptions that installed, or are upgrading to, the CSV
+func (o *Operator) syncClusterServiceVersions(obj interface{}) error {
+	csv, ok := obj.(*v1alpha1.ClusterServiceVersion)
+	if !ok {
+		log.Debugf("wrong type: %#v", obj)
+		return fmt.Errorf("casting ClusterServiceVersion failed")
+	}
+
+	key := fmt.Sprintf("%s/%s", csv.GetNamespace(), csv.GetName())
+	for _, informer := range o.subInformers {
+		subs, err := informer.GetIndexer().ByIndex(subscriptionCSVIndex, key)
+		if err != nil {
+			return fmt.Errorf("error listing subscriptions for CSV %s: %v", csv.GetName(), err)
+		}
+		for _, obj := range subs {
+			sub := obj.(*v1alpha1.Subscription)
+			log.Debugf("requeueing subscription %s for changes to CSV %s", sub.GetName(), csv.GetName())
+			o.subQueue.Add(fmt.Sprintf("%s/%s", sub.GetNamespace(), sub.GetName()))
+		}
+	}
+	return nil
+}
+
+// subscriptionCSVIndexFunc indexes a subscription by its installed CSV and the CSV it's upgrading to
+func subscriptionCSVIndexFunc(obj interface{}) ([]string, error) {
+	sub, ok := obj.(*v1alpha1.Subscription)
+	if !ok {
+		return nil, fmt.Errorf("casting Subscription failed")
+	}
+	keys := []string{}
+	for _, name := range subscriptionCSVNames(sub) {
+		keys = append(keys, fmt.Sprintf("%s/%s", sub.GetNamespace(), name))
+	}
+	return keys, nil
+}
+
+// installPlanSubscriptionIndexFunc indexes an InstallPlan by the subscriptions that own it
+func installPlanSubscriptionIndexFunc(obj interface{}) ([]string, error) {
+	plan, ok := obj.(*v1alpha1.InstallPlan)
+	if !ok {
+		return nil, fmt.Errorf("casting InstallPlan failed")
+	}
+	keys := []string{}
+	for _, owner := range plan.GetOwnerReferences() {
+		if owner.Kind == v1alpha1.SubscriptionKind {
+			keys = append(keys, fmt.Sprintf("%s/%s", plan.GetNamespace(), owner.Name))
+		}
+	}
+	return keys, nil
+}
+
+// enqueueFunc returns a func that adds an object's key to the queue
+func enqueueFunc(queue workqueue.RateLimitingInterface) func(obj interface{}) {
+	return func(obj interface{}) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			log.Infof("creating key failed: %s", err)
+			return
+		}
+		queue.Add(key)
+	}
+}
+
+// catalogSourceEventHandlers queues catalog sources like the default handlers. A deleted catalog source can't be
+// synced from the queue, so it's evicted right away.
+func (o *Operator) catalogSourceEventHandlers(queue workqueue.RateLimitingInterface) *cache.ResourceEventHandlerFuncs {
+	enqueue := enqueueFunc(queue)
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enqueue(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			catsrc, ok := obj.(*v1alpha1.CatalogSource)
+			if !ok {
+				log.Debugf("wrong type: %#v", obj)
+				return
+			}
+			queue.Forget(fmt.Sprintf("%s/%s", catsrc.GetNamespace(), catsrc.GetName()))
+			if err := o.removeCatalogSource(catsrc); err != nil {
+				log.Warn(err)
+			}
+		},
+	}
+}
+
+// removeCatalogSource evicts a deleted catalog source and requeues the subscriptions that used it, so that they
+// report the missing catalog source.
+func (o *Operator) removeCatalogSource(catsrc *v1alpha1.CatalogSource) error {
+	key := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
+	log.Infof("removing deleted catalog source %s in namespace %s", key.Name, key.Namespace)
+	o.removeCatalog(key)
+
+	for _, informer := range o.subInformers {
+		var subs []interface{}
+		if key.Namespace == o.namespace {
+			// Global catalog sources are available to every namespace
+			subs = informer.GetStore().List()
+		} else {
+			var err error
+			subs, err = informer.GetIndexer().ByIndex(cache.NamespaceIndex, key.Namespace)
+			if err != nil {
+				return fmt.Errorf("error listing subscriptions for catalog source %s: %v", key.Name, err)
+			}
+		}
+		for _, obj := range subs {
+			sub := obj.(*v1alpha1.Subscription)
+			if sub.Spec == nil || sub.Spec.CatalogSource != key.Name {
+				continue
+			}
+			if sub.Spec.CatalogSourceNamespace != "" && sub.Spec.CatalogSourceNamespace != key.Namespace {
+				continue
+			}
+			log.Debugf("requeueing subscription %s for deleted catalog source %s", sub.GetName(), key.Name)
+			o.subQueue.Add(fmt.Sprintf("%s/%s", sub.GetNamespace(), sub.GetName()))
+		}
+	}
+	return nil
+}
+
+// installPlanEventHandlers queues InstallPlans like the default handlers. When a plan owned by a subscription changes
+// phase, the subscription is queued too, so that its sync prunes its old InstallPlans.
+func (o *Operator) installPlanEventHandlers(queue workqueue.RateLimitingInterface) *cache.ResourceEventHandlerFuncs {
+	enqueue := enqueueFunc(queue)
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enqueue(newObj)
+			oldPlan, ok := oldObj.(*v1alpha1.InstallPlan)
+			if !ok {
+				return
+			}
+			plan, ok := newObj.(*v1alpha1.InstallPlan)
+			if !ok || oldPlan.Status.Phase == plan.Status.Phase {
+				return
+			}
+			for _, owner := range plan.GetOwnerReferences() {
+				if owner.Kind == v1alpha1.SubscriptionKind {
+					o.subQueue.Add(fmt.Sprintf("%s/%s", plan.GetNamespace(), owner.Name))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				log.Infof("creating key failed: %s", err)
+				return
+			}
+			queue.Forget(key)
+		},
+	}
+}
+
+// configMapEventHandlers queues changed ConfigMaps, skipping resyncs. A deleted ConfigMap can't be synced from the
+// queue, so the catalog sources that load it are requeued right away to report it missing.
+func (o *Operator) configMapEventHandlers(queue workqueue.RateLimitingInterface) *cache.ResourceEventHandlerFuncs {
+	enqueue := enqueueFunc(queue)
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldConfigMap, ok := oldObj.(*corev1.ConfigMap)
+			if ok && oldConfigMap.GetResourceVersion() == newObj.(*corev1.ConfigMap).GetResourceVersion() {
+				return
+			}
+			enqueue(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			configMap, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				log.Debugf("wrong type: %#v", obj)
+				return
+			}
+			if err := o.requeueCatalogSourcesForConfigMap(configMap); err != nil {
+				log.Warn(err)
+			}
+		},
+	}
+}
+
+func (o *Operator) syncConfigMaps(obj interface{}) error {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		log.Debugf("wrong type: %#v", obj)
+		return fmt.Errorf("casting ConfigMap failed")
+	}
+	return o.requeueCatalogSourcesForConfigMap(configMap)
+}
+
+// requeueCatalogSourcesForConfigMap requeues the catalog sources that are loaded from the given ConfigMap
+func (o *Operator) requeueCatalogSourcesForConfigMap(configMap *corev1.ConfigMap) error {
+	key := fmt.Sprintf("%s/%s", configMap.GetNamespace(), configMap.GetName())
+	for _, informer := range o.catsrcInformers {
+		catsrcs, err := informer.GetIndexer().ByIndex(catalogSourceConfigMapIndex, key)
+		if err != nil {
+			return fmt.Errorf("error listing catalog sources for ConfigMap %s: %v", configMap.GetName(), err)
+		}
+		for _, obj := range catsrcs {
+			catsrc := obj.(*v1alpha1.CatalogSource)
+			log.Debugf("requeueing catalog source %s for changes to ConfigMap %s", catsrc.GetName(), configMap.GetName())
+			o.catsrcQueue.Add(fmt.Sprintf("%s/%s", catsrc.GetNamespace(), catsrc.GetName()))
+		}
+	}
+	return nil
+}
+
+// catalogSourceConfigMapIndexFunc indexes a catalog source by the ConfigMap it's loaded from, if any
+func catalogSourceConfigMapIndexFunc(obj interface{}) ([]string, error) {
+	catsrc, ok := obj.(*v1alpha1.CatalogSource)
+	if !ok {
+		return nil, fmt.Errorf("casting CatalogSource failed")
+	}
+	if !loadsConfigMap(catsrc, catsrc.Spec.ConfigMap) {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("%s/%s", catsrc.GetNamespace(), catsrc.Spec.ConfigMap)}, nil
+}
+
+// loadsConfigMap returns true if the catalog source is loaded from the named ConfigMap in its namespace
+func loadsConfigMap(catsrc *v1alpha1.CatalogSource, name string) bool {
+	switch catsrc.Spec.SourceType {
+	case v1alpha1.SourceTypeGRPC, v1alpha1.SourceTypeHTTP:
+		return false
+	}
+	return catsrc.Spec.ConfigMap == name
+}
+
 func (o *Operator) syncInstallPlans(obj interface{}) (syncError error) {
 	plan, ok := obj.(*v1alpha1.InstallPlan)
 	if !ok {
@@ -266,7 +696,7 @@ func (o *Operator) syncInstallPlans(obj interface{}) (syncError error) {
 	}
 
 	// no changes in status, don't update
-	if outInstallPlan.Status.Phase == plan.Status.Phase {
+	if !installPlanStatusChanged(&plan.Status, &outInstallPlan.Status) {
 		return
 	}
 
@@ -290,9 +720,30 @@ func (o *Operator) syncInstallPlans(obj interface{}) (syncError error) {
 	return
 }
 
+// installPlanStatusChanged reports whether a sync changed an InstallPlan's status in a way that should be written.
+// Condition timestamps are ignored, so that retrying a failed rollback doesn't trigger another sync right away.
+func installPlanStatusChanged(old, updated *v1alpha1.InstallPlanStatus) bool {
+	if old.Phase != updated.Phase || !equality.Semantic.DeepEqual(old.Plan, updated.Plan) {
+		return true
+	}
+	if len(old.Conditions) != len(updated.Conditions) {
+		return true
+	}
+	for _, cond := range updated.Conditions {
+		existing := old.GetCondition(cond.Type)
+		if existing.Status != cond.Status || existing.Reason != cond.Reason || existing.Message != cond.Message {
+			return true
+		}
+	}
+	return false
+}
+
 type installPlanTransitioner interface {
 	ResolvePlan(*v1alpha1.InstallPlan) error
+	DryRunPlan(*v1alpha1.InstallPlan) error
 	ExecutePlan(*v1alpha1.InstallPlan) error
+	RollbackPlan(*v1alpha1.InstallPlan) error
+	ApprovalsVerified() bool
 }
 
 var _ installPlanTransitioner = &Operator{}
@@ -315,14 +766,27 @@ func transitionInstallPlanState(transitioner installPlanTransitioner, in v1alpha
 	case v1alpha1.InstallPlanPhasePlanning:
 		logger.Debug("attempting to resolve")
 		if err := transitioner.ResolvePlan(out); err != nil {
-			out.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanResolved,
-				v1alpha1.InstallPlanReasonInstallCheckFailed, err))
+			reason := v1alpha1.InstallPlanReasonInstallCheckFailed
+			if resolver.IsResolutionError(err) {
+				reason = v1alpha1.InstallPlanReasonDependencyConflict
+			}
+			out.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanResolved, reason, err))
 			out.Status.Phase = v1alpha1.InstallPlanPhaseFailed
 			return out, err
 		}
 		out.Status.SetCondition(v1alpha1.ConditionMet(v1alpha1.InstallPlanResolved))
 
-		if out.Spec.Approval == v1alpha1.ApprovalManual && out.Spec.Approved != true {
+		if out.Spec.DryRun {
+			logger.Debug("attempting dry run")
+			if err := transitioner.DryRunPlan(out); err != nil {
+				out.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanResolved,
+					v1alpha1.InstallPlanReasonInstallCheckFailed, err))
+				out.Status.Phase = v1alpha1.InstallPlanPhaseFailed
+				return out, err
+			}
+		}
+
+		if out.Spec.DryRun || !installPlanApproved(transitioner, out) {
 			out.Status.Phase = v1alpha1.InstallPlanPhaseRequiresApproval
 		} else {
 			out.Status.Phase = v1alpha1.InstallPlanPhaseInstalling
@@ -330,7 +794,14 @@ func transitionInstallPlanState(transitioner installPlanTransitioner, in v1alpha
 		return out, nil
 
 	case v1alpha1.InstallPlanPhaseRequiresApproval:
-		if out.Spec.Approved {
+		if out.Spec.DryRun {
+			// The cluster may have changed since the last dry run
+			logger.Debug("refreshing dry run")
+			return out, transitioner.DryRunPlan(out)
+		}
+
+		clearDryRun(out)
+		if installPlanApproved(transitioner, out) {
 			logger.Debugf("approved, setting to %s", v1alpha1.InstallPlanPhasePlanning)
 			out.Status.Phase = v1alpha1.InstallPlanPhaseInstalling
 		} else {
@@ -341,35 +812,92 @@ func transitionInstallPlanState(transitioner installPlanTransitioner, in v1alpha
 	case v1alpha1.InstallPlanPhaseInstalling:
 		logger.Debug("attempting to install")
 		if err := transitioner.ExecutePlan(out); err != nil {
+			if _, ok := err.(*retryableStepError); ok {
+				logger.WithField("error", err).Debug("step failed, retrying")
+				return out, err
+			}
 			out.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanInstalled,
 				v1alpha1.InstallPlanReasonComponentFailed, err))
 			out.Status.Phase = v1alpha1.InstallPlanPhaseFailed
+
+			if out.Spec.RollbackOnFailure {
+				logger.Debug("attempting to roll back")
+				rollbackPlan(transitioner, out)
+			}
 			return out, err
 		}
 		out.Status.SetCondition(v1alpha1.ConditionMet(v1alpha1.InstallPlanInstalled))
 		out.Status.Phase = v1alpha1.InstallPlanPhaseComplete
 		return out, nil
+
+	case v1alpha1.InstallPlanPhaseFailed:
+		// A failed rollback is retried until every resource created by the plan has been deleted
+		if !out.Spec.RollbackOnFailure || out.Status.GetCondition(v1alpha1.InstallPlanRolledBack).Status != corev1.ConditionFalse {
+			return out, nil
+		}
+		logger.Debug("retrying roll back")
+		return out, rollbackPlan(transitioner, out)
+
 	default:
 		return out, nil
 	}
 }
 
+// installPlanApproved reports whether a plan may be installed. A plan with an approval policy must be approved
+// through it, whatever its approval mode. Without the approval webhook, anyone who can update the plan could add
+// approvals under any name, so they aren't honored and the plan's Approved condition says why it's waiting.
+func installPlanApproved(transitioner installPlanTransitioner, plan *v1alpha1.InstallPlan) bool {
+	if plan.Spec.ApprovalPolicy == nil {
+		return plan.Spec.Approval != v1alpha1.ApprovalManual || plan.Spec.Approved
+	}
+	if !transitioner.ApprovalsVerified() {
+		plan.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanApproved,
+			v1alpha1.InstallPlanReasonApprovalsUnverified, errApprovalsUnverified))
+		return false
+	}
+	if !plan.Spec.IsApproved() {
+		return false
+	}
+	plan.Status.SetCondition(v1alpha1.ConditionMet(v1alpha1.InstallPlanApproved))
+	return true
+}
+
+// ApprovalsVerified reports whether the approvals of InstallPlans are verified by the approval webhook
+func (o *Operator) ApprovalsVerified() bool {
+	return o.approvalWebhookEnabled
+}
+
+// clearDryRun removes the dry run results from the steps of a plan that is no longer a dry run
+func clearDryRun(plan *v1alpha1.InstallPlan) {
+	for i := range plan.Status.Plan {
+		plan.Status.Plan[i].DryRun = ""
+		plan.Status.Plan[i].DryRunDiff = ""
+	}
+}
+
+// rollbackPlan rolls back a failed InstallPlan and records the outcome in its RolledBack condition
+func rollbackPlan(transitioner installPlanTransitioner, plan *v1alpha1.InstallPlan) error {
+	if err := transitioner.RollbackPlan(plan); err != nil {
+		plan.Status.SetCondition(v1alpha1.ConditionFailed(v1alpha1.InstallPlanRolledBack,
+			v1alpha1.InstallPlanReasonRollbackFailed, err))
+		return err
+	}
+	plan.Status.SetCondition(v1alpha1.ConditionMet(v1alpha1.InstallPlanRolledBack))
+	return nil
+}
+
 // ResolvePlan modifies an InstallPlan to contain a Plan in its Status field.
 func (o *Operator) ResolvePlan(plan *v1alpha1.InstallPlan) error {
 	if plan.Status.Phase != v1alpha1.InstallPlanPhasePlanning {
 		panic("attempted to create a plan that wasn't in the planning phase")
 	}
 
-	if len(o.sources) == 0 {
+	if len(o.catalogs().sources) == 0 {
 		return fmt.Errorf("cannot resolve InstallPlan without any Catalog Sources")
 	}
 
-	// Take a snapshot of the included catalog sources
-	includedNamespaces := map[string]struct{}{
-		o.namespace:    {},
-		plan.Namespace: {},
-	}
-	sourcesSnapshot := o.getSourcesSnapshot(plan, includedNamespaces)
+	// Take a snapshot of the catalog sources available to the plan
+	sourcesSnapshot := o.getSourcesSnapshot(plan)
 
 	// Take a snapshot of the existing CRD owners
 	existingCRDOwners, err := o.getExistingCRDOwners(plan.Namespace)
@@ -379,12 +907,20 @@ func (o *Operator) ResolvePlan(plan *v1alpha1.InstallPlan) error {
 
 	// Attempt to resolve the InstallPlan
 	steps, usedSources, err := o.dependencyResolver.ResolveInstallPlan(sourcesSnapshot, existingCRDOwners, CatalogLabel, plan)
+	if resErr, ok := err.(*resolver.ResolutionError); ok {
+		plan.Status.ResolutionTrace = resErr.Trace
+	} else {
+		plan.Status.ResolutionTrace = nil
+	}
 	if err != nil {
 		return err
 	}
 
 	// Set the resolved steps
 	plan.Status.Plan = steps
+	if err := setStepDependencies(plan.Status.Plan); err != nil {
+		return err
+	}
 	plan.Status.CatalogSources = []string{}
 
 	// Add secrets for each used catalog source
@@ -414,10 +950,12 @@ func (o *Operator) ResolvePlan(plan *v1alpha1.InstallPlan) error {
 			plan.Status.Plan = append([]v1alpha1.Step{{
 				Resolving: "",
 				Resource: v1alpha1.StepResource{
-					Name:    secretName,
-					Kind:    "Secret",
-					Group:   "",
-					Version: "v1",
+					CatalogSource:          sourceKey.Name,
+					CatalogSourceNamespace: sourceKey.Namespace,
+					Name:                   secretName,
+					Kind:                   "Secret",
+					Group:                  "",
+					Version:                "v1",
 				},
 				Status: status,
 			}}, plan.Status.Plan...)
@@ -427,7 +965,21 @@ func (o *Operator) ResolvePlan(plan *v1alpha1.InstallPlan) error {
 	return nil
 }
 
-// ExecutePlan applies a planned InstallPlan to a namespace.
+// maxStepAttempts is the number of times a step is attempted before its InstallPlan fails
+const maxStepAttempts = 3
+
+// retryableStepError is returned by ExecutePlan when a step failed but can be attempted again
+type retryableStepError struct {
+	err error
+}
+
+func (e *retryableStepError) Error() string {
+	return e.err.Error()
+}
+
+// ExecutePlan applies a planned InstallPlan to a namespace. Steps are executed in parallel once the steps they depend
+// on have completed. The status of the steps is saved as they complete or fail, so that an InstallPlan that is synced
+// again resumes from its incomplete steps.
 func (o *Operator) ExecutePlan(plan *v1alpha1.InstallPlan) error {
 	if plan.Status.Phase != v1alpha1.InstallPlanPhaseInstalling {
 		panic("attempted to install a plan that wasn't in the installing phase")
@@ -441,308 +993,438 @@ func (o *Operator) ExecutePlan(plan *v1alpha1.InstallPlan) error {
 		return err
 	}
 
-	for i, step := range plan.Status.Plan {
-		switch step.Status {
-		case v1alpha1.StepStatusPresent, v1alpha1.StepStatusCreated:
-			continue
-
-		case v1alpha1.StepStatusUnknown, v1alpha1.StepStatusNotPresent:
-			log.Debugf("resource kind: %s", step.Resource.Kind)
-			log.Debugf("resource name: %s", step.Resource.Name)
-			switch step.Resource.Kind {
-			case crdKind:
-				// Marshal the manifest into a CRD instance.
-				var crd v1beta1ext.CustomResourceDefinition
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &crd)
-				if err != nil {
-					return err
-				}
+	// Order the steps by their dependencies
+	if err := setStepDependencies(plan.Status.Plan); err != nil {
+		return err
+	}
 
-				// TODO: check that names are accepted
-				// Attempt to create the CRD.
-				_, err = o.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Create(&crd)
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-					continue
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occured, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-					continue
-				}
+	for {
+		ready, incomplete, err := readySteps(plan.Status.Plan)
+		if err != nil {
+			return err
+		}
+		if incomplete == 0 {
+			break
+		}
+		if len(ready) == 0 {
+			return fmt.Errorf("%d steps have circular dependencies", incomplete)
+		}
 
-			case v1alpha1.ClusterServiceVersionKind:
-				// Marshal the manifest into a CSV instance.
-				var csv v1alpha1.ClusterServiceVersion
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &csv)
-				if err != nil {
-					return err
+		// Execute the steps whose dependencies have completed, in parallel.
+		errs := make([]error, len(ready))
+		sem := make(chan struct{}, maxParallelSteps)
+		var wg sync.WaitGroup
+		for j, i := range ready {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(j int, step *v1alpha1.Step) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				log.Debugf("resource kind: %s", step.Resource.Kind)
+				log.Debugf("resource name: %s", step.Resource.Name)
+				step.Attempts++
+				if err := o.executeStep(plan, step, initialCSVNames, existingCRDOwners); err != nil {
+					step.Status = v1alpha1.StepStatusFailed
+					step.Message = err.Error()
+					errs[j] = fmt.Errorf("%s %s: %s", step.Resource.Kind, step.Resource.Name, err)
+					return
 				}
+				step.Message = ""
+			}(j, &plan.Status.Plan[i])
+		}
+		wg.Wait()
+		o.checkpointPlan(plan)
 
-				// Check if the resolved CSV is in the initial set
-				if _, ok := initialCSVNames[csv.GetName()]; !ok {
-					// Check for pre-existing CSVs that own the same CRDs
-					competingOwners, err := competingCRDOwnersExist(plan.GetNamespace(), &csv, existingCRDOwners)
-					if err != nil {
-						return err
-					}
-
-					// TODO: decide on fail/continue logic for pre-existing dependent CSVs that own the same CRD(s)
-					if competingOwners {
-						// For now, error out
-						return fmt.Errorf("Pre-existing CRD owners found for owned CRD(s) of dependent CSV %s", csv.GetName())
-					}
-				}
+		for j, err := range errs {
+			if err == nil {
+				continue
+			}
+			if plan.Status.Plan[ready[j]].Attempts < maxStepAttempts {
+				return &retryableStepError{err: err}
+			}
+			return err
+		}
+	}
 
-				// Attempt to create the CSV.
-				_, err = o.client.OperatorsV1alpha1().ClusterServiceVersions(csv.GetNamespace()).Create(&csv)
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+	// Loop over one final time to check and see if everything is good.
+	for _, step := range plan.Status.Plan {
+		switch step.Status {
+		case v1alpha1.StepStatusCreated, v1alpha1.StepStatusPresent:
+		default:
+			return nil
+		}
+	}
 
-			case secretKind:
-				// Get the pre-existing secret.
-				secret, err := o.OpClient.KubernetesInterface().CoreV1().Secrets(o.namespace).Get(step.Resource.Name, metav1.GetOptions{})
-				if k8serrors.IsNotFound(err) {
-					return fmt.Errorf("secret %s does not exist", step.Resource.Name)
-				} else if err != nil {
-					return err
-				}
+	return nil
+}
 
-				// Set the namespace to the InstallPlan's namespace and attempt to
-				// create a new secret.
-				secret.Namespace = plan.Namespace
-				_, err = o.OpClient.KubernetesInterface().CoreV1().Secrets(plan.Namespace).Create(&corev1.Secret{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      secret.Name,
-						Namespace: plan.Namespace,
-					},
-					Data: secret.Data,
-					Type: secret.Type,
-				})
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occured, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+// executeStep creates the resource of a step and sets the step's status to Created, or to Present if it already existed
+func (o *Operator) executeStep(plan *v1alpha1.InstallPlan, step *v1alpha1.Step, initialCSVNames map[string]struct{}, existingCRDOwners map[string][]string) error {
+	switch step.Resource.Kind {
+	case crdKind:
+		// Marshal the manifest into a CRD instance.
+		var crd v1beta1ext.CustomResourceDefinition
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &crd)
+		if err != nil {
+			return err
+		}
 
-			case clusterRoleKind:
-				// Marshal the manifest into a ClusterRole instance.
-				var cr rbacv1.ClusterRole
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &cr)
-				if err != nil {
-					return err
-				}
+		// TODO: check that names are accepted
+		// Attempt to create the CRD.
+		_, err = o.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Create(&crd)
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+			return nil
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occured, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+			return nil
+		}
 
-				// Update UIDs on all CSV OwnerReferences
-				updated, err := o.getUpdatedOwnerReferences(cr.OwnerReferences, plan.Namespace)
-				if err != nil {
-					return err
-				}
-				cr.OwnerReferences = updated
-
-				// Attempt to create the ClusterRole.
-				_, err = o.OpClient.KubernetesInterface().RbacV1().ClusterRoles().Create(&cr)
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
-			case clusterRoleBindingKind:
-				// Marshal the manifest into a RoleBinding instance.
-				var rb rbacv1.ClusterRoleBinding
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &rb)
-				if err != nil {
-					return err
-				}
+	case v1alpha1.ClusterServiceVersionKind:
+		// Marshal the manifest into a CSV instance.
+		var csv v1alpha1.ClusterServiceVersion
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &csv)
+		if err != nil {
+			return err
+		}
 
-				// Update UIDs on all CSV OwnerReferences
-				updated, err := o.getUpdatedOwnerReferences(rb.OwnerReferences, plan.Namespace)
-				if err != nil {
-					return err
-				}
-				rb.OwnerReferences = updated
-
-				// Attempt to create the ClusterRoleBinding.
-				_, err = o.OpClient.KubernetesInterface().RbacV1().ClusterRoleBindings().Create(&rb)
-				if k8serrors.IsAlreadyExists(err) {
-					rb.SetNamespace(plan.Namespace)
-					_, err = o.OpClient.UpdateClusterRoleBinding(&rb)
-					if err != nil {
-						return err
-					}
-
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+		// Check if the resolved CSV is in the initial set
+		if _, ok := initialCSVNames[csv.GetName()]; ok {
+			// Carry over the config of the Subscription that requested the CSV
+			if err := o.setSubscriptionConfig(plan, &csv); err != nil {
+				return err
+			}
+		} else {
+			// Check for pre-existing CSVs that own the same CRDs
+			competingOwners, err := competingCRDOwnersExist(plan.GetNamespace(), &csv, existingCRDOwners)
+			if err != nil {
+				return err
+			}
 
-			case roleKind:
-				// Marshal the manifest into a Role instance.
-				var r rbacv1.Role
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &r)
-				if err != nil {
-					return err
-				}
+			// TODO: decide on fail/continue logic for pre-existing dependent CSVs that own the same CRD(s)
+			if competingOwners {
+				// For now, error out
+				return fmt.Errorf("Pre-existing CRD owners found for owned CRD(s) of dependent CSV %s", csv.GetName())
+			}
+		}
 
-				// Update UIDs on all CSV OwnerReferences
-				updated, err := o.getUpdatedOwnerReferences(r.OwnerReferences, plan.Namespace)
-				if err != nil {
-					return err
-				}
-				r.OwnerReferences = updated
-
-				// Attempt to create the Role.
-				_, err = o.OpClient.KubernetesInterface().RbacV1().Roles(plan.Namespace).Create(&r)
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already existed, mark the step as Present.
-					r.SetNamespace(plan.Namespace)
-					_, err = o.OpClient.UpdateRole(&r)
-					if err != nil {
-						return err
-					}
-
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+		// Attempt to create the CSV.
+		_, err = o.client.OperatorsV1alpha1().ClusterServiceVersions(csv.GetNamespace()).Create(&csv)
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
 
-			case roleBindingKind:
-				// Marshal the manifest into a RoleBinding instance.
-				var rb rbacv1.RoleBinding
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &rb)
-				if err != nil {
-					return err
-				}
+	case secretKind:
+		// Get the pre-existing secret from the namespace of the catalog source that requires it.
+		secret, err := o.catalogSecret(step.Resource)
+		if err != nil {
+			return err
+		}
 
-				// Update UIDs on all CSV OwnerReferences
-				updated, err := o.getUpdatedOwnerReferences(rb.OwnerReferences, plan.Namespace)
-				if err != nil {
-					return err
-				}
-				rb.OwnerReferences = updated
-
-				// Attempt to create the RoleBinding.
-				_, err = o.OpClient.KubernetesInterface().RbacV1().RoleBindings(plan.Namespace).Create(&rb)
-				if k8serrors.IsAlreadyExists(err) {
-					rb.SetNamespace(plan.Namespace)
-					_, err = o.OpClient.UpdateRoleBinding(&rb)
-					if err != nil {
-						return err
-					}
-
-					// If it already existed, mark the step as Present.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+		// Set the namespace to the InstallPlan's namespace and attempt to
+		// create a new secret.
+		secret.Namespace = plan.Namespace
+		_, err = o.OpClient.KubernetesInterface().CoreV1().Secrets(plan.Namespace).Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secret.Name,
+				Namespace: plan.Namespace,
+			},
+			Data: secret.Data,
+			Type: secret.Type,
+		})
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occured, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
 
-			case serviceAccountKind:
-				// Marshal the manifest into a ServiceAccount instance.
-				var sa corev1.ServiceAccount
-				err := json.Unmarshal([]byte(step.Resource.Manifest), &sa)
-				if err != nil {
-					return err
-				}
+	case clusterRoleKind:
+		// Marshal the manifest into a ClusterRole instance.
+		var cr rbacv1.ClusterRole
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &cr)
+		if err != nil {
+			return err
+		}
 
-				// Update UIDs on all CSV OwnerReferences
-				updated, err := o.getUpdatedOwnerReferences(sa.OwnerReferences, plan.Namespace)
-				if err != nil {
-					return err
-				}
-				sa.OwnerReferences = updated
-
-				// Attempt to create the ServiceAccount.
-				_, err = o.OpClient.KubernetesInterface().CoreV1().ServiceAccounts(plan.Namespace).Create(&sa)
-				if k8serrors.IsAlreadyExists(err) {
-					// If it already exists we need to patch the existing SA with the new OwnerReferences
-					sa.SetNamespace(plan.Namespace)
-					_, err = o.OpClient.UpdateServiceAccount(&sa)
-					if err != nil {
-						return err
-					}
-
-					// Mark as present
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusPresent
-				} else if err != nil {
-					return err
-				} else {
-					// If no error occurred, mark the step as Created.
-					plan.Status.Plan[i].Status = v1alpha1.StepStatusCreated
-				}
+		// Update UIDs on all CSV OwnerReferences
+		updated, err := o.getUpdatedOwnerReferences(cr.OwnerReferences, plan.Namespace)
+		if err != nil {
+			return err
+		}
+		cr.OwnerReferences = updated
+
+		// Attempt to create the ClusterRole.
+		_, err = o.OpClient.KubernetesInterface().RbacV1().ClusterRoles().Create(&cr)
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
+	case clusterRoleBindingKind:
+		// Marshal the manifest into a RoleBinding instance.
+		var rb rbacv1.ClusterRoleBinding
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &rb)
+		if err != nil {
+			return err
+		}
+
+		// Update UIDs on all CSV OwnerReferences
+		updated, err := o.getUpdatedOwnerReferences(rb.OwnerReferences, plan.Namespace)
+		if err != nil {
+			return err
+		}
+		rb.OwnerReferences = updated
 
-			default:
-				return v1alpha1.ErrInvalidInstallPlan
+		// Attempt to create the ClusterRoleBinding.
+		_, err = o.OpClient.KubernetesInterface().RbacV1().ClusterRoleBindings().Create(&rb)
+		if k8serrors.IsAlreadyExists(err) {
+			rb.SetNamespace(plan.Namespace)
+			_, err = o.OpClient.UpdateClusterRoleBinding(&rb)
+			if err != nil {
+				return err
 			}
 
-		default:
-			return v1alpha1.ErrInvalidInstallPlan
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
 		}
-	}
 
-	// Loop over one final time to check and see if everything is good.
-	for _, step := range plan.Status.Plan {
-		switch step.Status {
-		case v1alpha1.StepStatusCreated, v1alpha1.StepStatusPresent:
-		default:
-			return nil
+	case roleKind:
+		// Marshal the manifest into a Role instance.
+		var r rbacv1.Role
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &r)
+		if err != nil {
+			return err
+		}
+
+		// Update UIDs on all CSV OwnerReferences
+		updated, err := o.getUpdatedOwnerReferences(r.OwnerReferences, plan.Namespace)
+		if err != nil {
+			return err
+		}
+		r.OwnerReferences = updated
+
+		// Attempt to create the Role.
+		_, err = o.OpClient.KubernetesInterface().RbacV1().Roles(plan.Namespace).Create(&r)
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already existed, mark the step as Present.
+			r.SetNamespace(plan.Namespace)
+			_, err = o.OpClient.UpdateRole(&r)
+			if err != nil {
+				return err
+			}
+
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
+
+	case roleBindingKind:
+		// Marshal the manifest into a RoleBinding instance.
+		var rb rbacv1.RoleBinding
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &rb)
+		if err != nil {
+			return err
+		}
+
+		// Update UIDs on all CSV OwnerReferences
+		updated, err := o.getUpdatedOwnerReferences(rb.OwnerReferences, plan.Namespace)
+		if err != nil {
+			return err
+		}
+		rb.OwnerReferences = updated
+
+		// Attempt to create the RoleBinding.
+		_, err = o.OpClient.KubernetesInterface().RbacV1().RoleBindings(plan.Namespace).Create(&rb)
+		if k8serrors.IsAlreadyExists(err) {
+			rb.SetNamespace(plan.Namespace)
+			_, err = o.OpClient.UpdateRoleBinding(&rb)
+			if err != nil {
+				return err
+			}
+
+			// If it already existed, mark the step as Present.
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
+
+	case serviceAccountKind:
+		// Marshal the manifest into a ServiceAccount instance.
+		var sa corev1.ServiceAccount
+		err := json.Unmarshal([]byte(step.Resource.Manifest), &sa)
+		if err != nil {
+			return err
+		}
+
+		// Update UIDs on all CSV OwnerReferences
+		updated, err := o.getUpdatedOwnerReferences(sa.OwnerReferences, plan.Namespace)
+		if err != nil {
+			return err
+		}
+		sa.OwnerReferences = updated
+
+		// Attempt to create the ServiceAccount.
+		_, err = o.OpClient.KubernetesInterface().CoreV1().ServiceAccounts(plan.Namespace).Create(&sa)
+		if k8serrors.IsAlreadyExists(err) {
+			// If it already exists we need to patch the existing SA with the new OwnerReferences
+			sa.SetNamespace(plan.Namespace)
+			_, err = o.OpClient.UpdateServiceAccount(&sa)
+			if err != nil {
+				return err
+			}
+
+			// Mark as present
+			step.Status = v1alpha1.StepStatusPresent
+		} else if err != nil {
+			return err
+		} else {
+			// If no error occurred, mark the step as Created.
+			step.Status = v1alpha1.StepStatusCreated
+		}
+
+	default:
+		// Create resources of any other kind through discovery.
+		status, err := o.createResource(step.Resource, plan.Namespace)
+		if err != nil {
+			return err
 		}
+		step.Status = status
 	}
 
 	return nil
 }
 
-func (o *Operator) getSourcesSnapshot(plan *v1alpha1.InstallPlan, includedNamespaces map[string]struct{}) []registry.SourceRef {
-	o.sourcesLock.RLock()
-	defer o.sourcesLock.RUnlock()
+// checkpointPlan saves the status of an InstallPlan's steps while it is being installed. Failures are only logged,
+// since the status is saved again once the InstallPlan changes phase.
+func (o *Operator) checkpointPlan(plan *v1alpha1.InstallPlan) {
+	updated, err := o.client.OperatorsV1alpha1().InstallPlans(plan.GetNamespace()).UpdateStatus(plan)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ip":        plan.GetName(),
+			"namespace": plan.GetNamespace(),
+		}).WithError(err).Warn("failed to save InstallPlan progress")
+		return
+	}
+	plan.SetResourceVersion(updated.GetResourceVersion())
+}
+
+// catalogSecret returns the secret of a step from the namespace of the catalog source that requires it
+func (o *Operator) catalogSecret(resource v1alpha1.StepResource) (*corev1.Secret, error) {
+	secretNamespace := resource.CatalogSourceNamespace
+	if secretNamespace == "" {
+		secretNamespace = o.namespace
+	}
+	secret, err := o.OpClient.KubernetesInterface().CoreV1().Secrets(secretNamespace).Get(resource.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("secret %s does not exist", resource.Name)
+	}
+	return secret, err
+}
+
+// setSubscriptionConfig sets the config of the Subscription owning the given InstallPlan, if any, on the given CSV
+func (o *Operator) setSubscriptionConfig(plan *v1alpha1.InstallPlan, csv *v1alpha1.ClusterServiceVersion) error {
+	if !ownerutil.IsOwnedByKind(plan, v1alpha1.SubscriptionKind) {
+		return nil
+	}
+
+	oref := ownerutil.GetOwnerByKind(plan, v1alpha1.SubscriptionKind)
+	sub, err := o.client.OperatorsV1alpha1().Subscriptions(plan.GetNamespace()).Get(oref.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if sub.Spec == nil {
+		return nil
+	}
+
+	_, err = csv.SetSubscriptionConfig(sub.Spec.Config)
+	return err
+}
+
+// getSourcesSnapshot returns the catalog sources available to the plan in the order they should be searched: the
+// plan's own catalog source first, then by descending priority, then by name. Catalog sources in the operator
+// namespace are global, others are only available to plans in the same namespace.
+func (o *Operator) getSourcesSnapshot(plan *v1alpha1.InstallPlan) []registry.SourceRef {
+	catalogs := o.catalogs()
 	sourcesSnapshot := []registry.SourceRef{}
 
-	for key, source := range o.sources {
-		// Only copy catalog sources in included namespaces
-		if _, ok := includedNamespaces[key.Namespace]; ok {
-			ref := registry.SourceRef{
+	for key, source := range catalogs.sources {
+		// Only copy catalog sources the plan is allowed to use
+		if key.Namespace == o.namespace || key.Namespace == plan.GetNamespace() {
+			sourcesSnapshot = append(sourcesSnapshot, registry.SourceRef{
 				Source:    source,
 				SourceKey: key,
-			}
-			if key.Name == plan.Spec.CatalogSource && key.Namespace == plan.Spec.CatalogSourceNamespace {
-				// Prepend preffered catalog source
-				sourcesSnapshot = append([]registry.SourceRef{ref}, sourcesSnapshot...)
-			} else {
-				// Append the catalog source
-				sourcesSnapshot = append(sourcesSnapshot, ref)
-			}
+			})
 		}
 	}
 
+	sort.Slice(sourcesSnapshot, func(i, j int) bool {
+		a, b := sourcesSnapshot[i].SourceKey, sourcesSnapshot[j].SourceKey
+		if rankA, rankB := o.preferredSourceRank(plan, a), o.preferredSourceRank(plan, b); rankA != rankB {
+			return rankA < rankB
+		}
+		if catalogs.priorities[a] != catalogs.priorities[b] {
+			return catalogs.priorities[a] > catalogs.priorities[b]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	})
+
 	return sourcesSnapshot
 }
 
+// preferredSourceRank ranks the plan's own catalog source before all others. A plan without a catalog source
+// namespace prefers a catalog source in its own namespace over a global one, like a subscription does.
+func (o *Operator) preferredSourceRank(plan *v1alpha1.InstallPlan, key registry.ResourceKey) int {
+	switch {
+	case key.Name != plan.Spec.CatalogSource:
+		return 3
+	case key.Namespace == plan.Spec.CatalogSourceNamespace:
+		return 0
+	case plan.Spec.CatalogSourceNamespace == "" && key.Namespace == plan.GetNamespace():
+		return 1
+	case plan.Spec.CatalogSourceNamespace == "" && key.Namespace == o.namespace:
+		return 2
+	default:
+		return 3
+	}
+}
+
 // getExistingCRDOwners creates a map of CRD names to existing owner CSVs in the given namespace
 func (o *Operator) getExistingCRDOwners(namespace string) (map[string][]string, error) {
 	// Get a list of CSV CRs in the namespace
@@ -808,3 +1490,18 @@ func getCSVNameSet(plan *v1alpha1.InstallPlan) map[string]struct{} {
 
 	return csvNameSet
 }
+
+// catalogNamespaces returns the namespaces to watch for catalog sources: the operator namespace and every watched
+// namespace.
+func catalogNamespaces(operatorNamespace string, watchedNamespaces []string) []string {
+	namespaces := []string{}
+	for _, namespace := range watchedNamespaces {
+		if namespace == metav1.NamespaceAll {
+			return []string{metav1.NamespaceAll}
+		}
+		if namespace != operatorNamespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return append([]string{operatorNamespace}, namespaces...)
+}
@@ -2,7 +2,9 @@ package catalog
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 
@@ -14,10 +16,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	apiregistrationfake "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/fake"
 
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/clientset/versioned/fake"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/informers/externalversions"
 	olmerrors "github.com/operator-framework/operator-lifecycle-manager/pkg/controller/errors"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
@@ -26,7 +31,10 @@ import (
 )
 
 type mockTransitioner struct {
-	err error
+	err                 error
+	rollbackErr         error
+	dryRuns             int
+	unverifiedApprovals bool
 }
 
 var _ installPlanTransitioner = &mockTransitioner{}
@@ -35,10 +43,23 @@ func (m *mockTransitioner) ResolvePlan(plan *v1alpha1.InstallPlan) error {
 	return m.err
 }
 
+func (m *mockTransitioner) DryRunPlan(plan *v1alpha1.InstallPlan) error {
+	m.dryRuns++
+	return m.err
+}
+
 func (m *mockTransitioner) ExecutePlan(plan *v1alpha1.InstallPlan) error {
 	return m.err
 }
 
+func (m *mockTransitioner) RollbackPlan(plan *v1alpha1.InstallPlan) error {
+	return m.rollbackErr
+}
+
+func (m *mockTransitioner) ApprovalsVerified() bool {
+	return !m.unverifiedApprovals
+}
+
 func TestTransitionInstallPlan(t *testing.T) {
 
 	errMsg := "transition test error"
@@ -107,7 +128,7 @@ func TestTransitionInstallPlan(t *testing.T) {
 		}
 
 		// Create a transitioner that returns the provided error.
-		transitioner := &mockTransitioner{tt.transError}
+		transitioner := &mockTransitioner{err: tt.transError}
 
 		// Attempt to transition phases.
 		out, _ := transitionInstallPlanState(transitioner, *plan)
@@ -128,8 +149,102 @@ func TestTransitionInstallPlan(t *testing.T) {
 	}
 }
 
+func TestExecutePlanCheckpoints(t *testing.T) {
+	namespace := "ns"
+	step := func(kind, name string, status v1alpha1.StepStatus) v1alpha1.Step {
+		return v1alpha1.Step{
+			Resource: v1alpha1.StepResource{
+				Kind: kind,
+				Name: name,
+				Manifest: fmt.Sprintf(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":%q,"metadata":{"name":%q}}`,
+					kind, name),
+			},
+			Status: status,
+		}
+	}
+	widget := step("Widget", "etcd-widget", v1alpha1.StepStatusUnknown)
+	widget.Resource.Group, widget.Resource.Version = "example.com", "v1"
+	plan := &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+		Status: v1alpha1.InstallPlanStatus{
+			Phase: v1alpha1.InstallPlanPhaseInstalling,
+			Plan: []v1alpha1.Step{
+				step(serviceAccountKind, "etcd-operator", v1alpha1.StepStatusCreated),
+				step(roleKind, "etcd-operator", v1alpha1.StepStatusUnknown),
+				widget,
+			},
+		},
+	}
+
+	op, err := NewFakeOperator([]runtime.Object{plan}, nil, nil, nil, nil, namespace)
+	require.NoError(t, err)
+	server := newAPIServer(t)
+	defer server.Close()
+	server.serve(t, op)
+
+	for attempt := 1; attempt <= maxStepAttempts; attempt++ {
+		err = op.ExecutePlan(plan)
+		require.Error(t, err)
+		_, retryable := err.(*retryableStepError)
+		require.Equal(t, attempt < maxStepAttempts, retryable, "attempt %d", attempt)
+
+		// The progress of the plan is saved as each step completes or fails.
+		saved, err := op.client.OperatorsV1alpha1().InstallPlans(namespace).Get(plan.GetName(), metav1.GetOptions{})
+		require.NoError(t, err)
+		for _, p := range []*v1alpha1.InstallPlan{plan, saved} {
+			require.Equal(t, v1alpha1.StepStatusCreated, p.Status.Plan[0].Status)
+			require.Equal(t, 0, p.Status.Plan[0].Attempts)
+			require.Equal(t, v1alpha1.StepStatusCreated, p.Status.Plan[1].Status)
+			require.Equal(t, 1, p.Status.Plan[1].Attempts)
+			require.Empty(t, p.Status.Plan[1].Message)
+			require.Equal(t, v1alpha1.StepStatusFailed, p.Status.Plan[2].Status)
+			require.Equal(t, attempt, p.Status.Plan[2].Attempts)
+			require.NotEmpty(t, p.Status.Plan[2].Message)
+		}
+	}
+
+	// Completed steps aren't executed again.
+	_, err = op.OpClient.KubernetesInterface().CoreV1().ServiceAccounts(namespace).Get("etcd-operator", metav1.GetOptions{})
+	require.Error(t, err)
+}
+
+func TestTransitionInstallPlanRetryableStep(t *testing.T) {
+	plan := v1alpha1.InstallPlan{
+		Spec:   v1alpha1.InstallPlanSpec{Approval: v1alpha1.ApprovalAutomatic},
+		Status: v1alpha1.InstallPlanStatus{Phase: v1alpha1.InstallPlanPhaseInstalling},
+	}
+
+	out, err := transitionInstallPlanState(&mockTransitioner{err: &retryableStepError{err: errors.New("step failed")}}, plan)
+	require.Error(t, err)
+	require.Equal(t, v1alpha1.InstallPlanPhaseInstalling, out.Status.Phase)
+	require.Empty(t, out.Status.Conditions)
+}
+
+func TestTransitionInstallPlanResolutionError(t *testing.T) {
+	plan := v1alpha1.InstallPlan{
+		Spec:   v1alpha1.InstallPlanSpec{Approval: v1alpha1.ApprovalAutomatic},
+		Status: v1alpha1.InstallPlanStatus{Phase: v1alpha1.InstallPlanPhasePlanning},
+	}
+	resErr := &resolver.ResolutionError{
+		CSVNames: []string{"etcdoperator.v0.9.2"},
+		Trace: []v1alpha1.ResolutionTraceEntry{{
+			CSV:            "etcdoperator.v0.9.2",
+			CatalogSources: []string{"ns/catalog"},
+			Result:         v1alpha1.ResolutionTraceResultNotFound,
+		}},
+		Err: errors.New("not found: ClusterServiceVersion etcdoperator.v0.9.2"),
+	}
+
+	out, err := transitionInstallPlanState(&mockTransitioner{err: resErr}, plan)
+	require.Equal(t, resErr, err)
+	require.Equal(t, v1alpha1.InstallPlanPhaseFailed, out.Status.Phase)
+	require.Len(t, out.Status.Conditions, 1)
+	require.Equal(t, v1alpha1.InstallPlanReasonDependencyConflict, out.Status.Conditions[0].Reason)
+	require.Equal(t, "unable to resolve [etcdoperator.v0.9.2]: CSV etcdoperator.v0.9.2 not found in catalogs [ns/catalog]", out.Status.Conditions[0].Message)
+}
+
 func TestSyncCatalogSources(t *testing.T) {
-	resolver := &resolver.MultiSourceResolver{}
+	resolver := &resolver.ConstraintResolver{}
 
 	tests := []struct {
 		testName          string
@@ -168,6 +283,21 @@ func TestSyncCatalogSources(t *testing.T) {
 					UID:             types.UID("configmap-uid"),
 					ResourceVersion: "resource-version",
 				},
+				Contents: &v1alpha1.CatalogSourceContents{
+					CustomResourceDefinitions: 1,
+				},
+				Conditions: []v1alpha1.CatalogSourceCondition{
+					{
+						Type:    v1alpha1.CatalogSourceReady,
+						Status:  corev1.ConditionTrue,
+						Reason:  v1alpha1.CatalogSourceReasonLoaded,
+						Message: "loaded ConfigMap cool-configmap at resource version resource-version",
+					},
+					{
+						Type:   v1alpha1.CatalogSourceLoadFailed,
+						Status: corev1.ConditionFalse,
+					},
+				},
 			},
 			expectedError: nil,
 		},
@@ -208,6 +338,21 @@ func TestSyncCatalogSources(t *testing.T) {
 					UID:             types.UID("configmap-uid"),
 					ResourceVersion: "resource-version",
 				},
+				Contents: &v1alpha1.CatalogSourceContents{
+					CustomResourceDefinitions: 1,
+				},
+				Conditions: []v1alpha1.CatalogSourceCondition{
+					{
+						Type:    v1alpha1.CatalogSourceReady,
+						Status:  corev1.ConditionTrue,
+						Reason:  v1alpha1.CatalogSourceReasonLoaded,
+						Message: "loaded ConfigMap cool-configmap at resource version resource-version",
+					},
+					{
+						Type:   v1alpha1.CatalogSourceLoadFailed,
+						Status: corev1.ConditionFalse,
+					},
+				},
 			},
 			expectedError: nil,
 		},
@@ -233,8 +378,77 @@ func TestSyncCatalogSources(t *testing.T) {
 				},
 				Data: map[string]string{},
 			},
-			expectedStatus: nil,
-			expectedError:  errors.New("failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found"),
+			expectedStatus: &v1alpha1.CatalogSourceStatus{
+				Conditions: []v1alpha1.CatalogSourceCondition{
+					{
+						Type:    v1alpha1.CatalogSourceReady,
+						Status:  corev1.ConditionFalse,
+						Reason:  v1alpha1.CatalogSourceReasonInvalidConfigMap,
+						Message: "failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found",
+					},
+					{
+						Type:    v1alpha1.CatalogSourceLoadFailed,
+						Status:  corev1.ConditionTrue,
+						Reason:  v1alpha1.CatalogSourceReasonInvalidConfigMap,
+						Message: "failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found",
+					},
+				},
+			},
+			expectedError: errors.New("failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found"),
+		},
+		{
+			testName:          "CatalogSourceWithInvalidConfigMapUpdate",
+			operatorNamespace: "cool-namespace",
+			catalogSource: &v1alpha1.CatalogSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cool-catalog",
+					Namespace: "cool-namespace",
+					UID:       types.UID("catalog-uid"),
+				},
+				Spec: v1alpha1.CatalogSourceSpec{
+					ConfigMap: "cool-configmap",
+				},
+				Status: v1alpha1.CatalogSourceStatus{
+					ConfigMapResource: &v1alpha1.ConfigMapResourceReference{
+						Name:            "cool-configmap",
+						Namespace:       "cool-namespace",
+						UID:             types.UID("configmap-uid"),
+						ResourceVersion: "old-resource-version",
+					},
+				},
+			},
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "cool-configmap",
+					Namespace:       "cool-namespace",
+					UID:             types.UID("configmap-uid"),
+					ResourceVersion: "resource-version",
+				},
+				Data: map[string]string{},
+			},
+			expectedStatus: &v1alpha1.CatalogSourceStatus{
+				ConfigMapResource: &v1alpha1.ConfigMapResourceReference{
+					Name:            "cool-configmap",
+					Namespace:       "cool-namespace",
+					UID:             types.UID("configmap-uid"),
+					ResourceVersion: "old-resource-version",
+				},
+				Conditions: []v1alpha1.CatalogSourceCondition{
+					{
+						Type:    v1alpha1.CatalogSourceReady,
+						Status:  corev1.ConditionFalse,
+						Reason:  v1alpha1.CatalogSourceReasonInvalidConfigMap,
+						Message: "failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found",
+					},
+					{
+						Type:    v1alpha1.CatalogSourceLoadFailed,
+						Status:  corev1.ConditionTrue,
+						Reason:  v1alpha1.CatalogSourceReasonInvalidConfigMap,
+						Message: "failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found",
+					},
+				},
+			},
+			expectedError: errors.New("failed to create catalog source from ConfigMap cool-configmap: error parsing ConfigMap cool-configmap: no valid resources found"),
 		},
 		{
 			testName:          "CatalogSourceWithMissingConfigMap",
@@ -249,9 +463,24 @@ func TestSyncCatalogSources(t *testing.T) {
 					ConfigMap: "cool-configmap",
 				},
 			},
-			configMap:      &corev1.ConfigMap{},
-			expectedStatus: nil,
-			expectedError:  errors.New("failed to get catalog config map cool-configmap when updating status: configmaps \"cool-configmap\" not found"),
+			configMap: &corev1.ConfigMap{},
+			expectedStatus: &v1alpha1.CatalogSourceStatus{
+				Conditions: []v1alpha1.CatalogSourceCondition{
+					{
+						Type:    v1alpha1.CatalogSourceReady,
+						Status:  corev1.ConditionFalse,
+						Reason:  v1alpha1.CatalogSourceReasonConfigMapNotFound,
+						Message: "failed to get catalog config map cool-configmap when updating status: configmaps \"cool-configmap\" not found",
+					},
+					{
+						Type:    v1alpha1.CatalogSourceLoadFailed,
+						Status:  corev1.ConditionTrue,
+						Reason:  v1alpha1.CatalogSourceReasonConfigMapNotFound,
+						Message: "failed to get catalog config map cool-configmap when updating status: configmaps \"cool-configmap\" not found",
+					},
+				},
+			},
+			expectedError: errors.New("failed to get catalog config map cool-configmap when updating status: configmaps \"cool-configmap\" not found"),
 		},
 	}
 	for _, tt := range tests {
@@ -279,16 +508,301 @@ func TestSyncCatalogSources(t *testing.T) {
 
 			if tt.expectedStatus != nil {
 				require.NotEmpty(t, updated.Status)
-				require.Equal(t, *tt.expectedStatus.ConfigMapResource, *updated.Status.ConfigMapResource)
+				require.Equal(t, tt.expectedStatus.ConfigMapResource, updated.Status.ConfigMapResource)
+				require.Equal(t, tt.expectedStatus.Contents, updated.Status.Contents)
+				for i := range updated.Status.Conditions {
+					updated.Status.Conditions[i].LastUpdateTime = metav1.Time{}
+					updated.Status.Conditions[i].LastTransitionTime = metav1.Time{}
+				}
+				require.Equal(t, tt.expectedStatus.Conditions, updated.Status.Conditions)
+			}
+
+			// Ensure that the catalog source has been loaded into memory only if it is valid
+			_, ok := op.catalogs().sources[registry.ResourceKey{Name: tt.catalogSource.GetName(), Namespace: tt.catalogSource.GetNamespace()}]
+			require.Equal(t, tt.expectedError == nil, ok, "catalog loaded into memory: %t", ok)
+		})
+	}
+}
+
+func TestSyncClusterServiceVersions(t *testing.T) {
+	namespace := "ns"
+	subscription := func(name, installed, current string) *v1alpha1.Subscription {
+		return &v1alpha1.Subscription{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       &v1alpha1.SubscriptionSpec{Package: name},
+			Status:     v1alpha1.SubscriptionStatus{InstalledCSV: installed, CurrentCSV: current},
+		}
+	}
+	clusterServiceVersion := csv("csv.v1", nil, nil)
+	clusterServiceVersion.SetNamespace(namespace)
+
+	op, err := NewFakeOperator([]runtime.Object{
+		subscription("installed", "csv.v1", "csv.v1"),
+		subscription("upgrading", "csv.v0", "csv.v1"),
+		subscription("unrelated", "other.v1", "other.v1"),
+	}, nil, nil, nil, &resolver.ConstraintResolver{}, namespace)
+	require.NoError(t, err)
+
+	require.NoError(t, op.syncClusterServiceVersions(&clusterServiceVersion))
+
+	keys := []string{}
+	for op.subQueue.Len() > 0 {
+		key, _ := op.subQueue.Get()
+		keys = append(keys, key.(string))
+		op.subQueue.Done(key)
+	}
+	require.ElementsMatch(t, []string{"ns/installed", "ns/upgrading"}, keys)
+}
+
+func TestSyncConfigMaps(t *testing.T) {
+	namespace := "ns"
+	catalogSource := func(name, namespace, sourceType, configMap string) *v1alpha1.CatalogSource {
+		return &v1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1alpha1.CatalogSourceSpec{SourceType: sourceType, ConfigMap: configMap},
+		}
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: namespace, ResourceVersion: "1"}}
+
+	op, err := NewFakeOperator([]runtime.Object{
+		catalogSource("internal", namespace, v1alpha1.SourceTypeInternal, "catalog"),
+		catalogSource("untyped", namespace, "", "catalog"),
+		catalogSource("grpc", namespace, v1alpha1.SourceTypeGRPC, "catalog"),
+		catalogSource("other", namespace, v1alpha1.SourceTypeInternal, "other"),
+		catalogSource("elsewhere", "other-ns", v1alpha1.SourceTypeInternal, "catalog"),
+	}, nil, nil, nil, &resolver.ConstraintResolver{}, namespace)
+	require.NoError(t, err)
+
+	requeued := func() []string {
+		keys := []string{}
+		for op.catsrcQueue.Len() > 0 {
+			key, _ := op.catsrcQueue.Get()
+			keys = append(keys, key.(string))
+			op.catsrcQueue.Done(key)
+		}
+		return keys
+	}
+
+	require.NoError(t, op.syncConfigMaps(configMap))
+	require.ElementsMatch(t, []string{"ns/internal", "ns/untyped"}, requeued())
+
+	// Deleted ConfigMaps requeue their catalog sources without going through the queue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmaps")
+	handlers := op.configMapEventHandlers(queue)
+	handlers.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/catalog", Obj: configMap})
+	require.ElementsMatch(t, []string{"ns/internal", "ns/untyped"}, requeued())
+	require.Equal(t, 0, queue.Len())
+
+	// Resyncs aren't queued
+	handlers.OnUpdate(configMap, configMap.DeepCopy())
+	require.Equal(t, 0, queue.Len())
+	changed := configMap.DeepCopy()
+	changed.ResourceVersion = "2"
+	handlers.OnUpdate(configMap, changed)
+	require.Equal(t, 1, queue.Len())
+}
+
+func TestRemoveCatalogSource(t *testing.T) {
+	subscription := func(name, namespace, source, sourceNamespace string) *v1alpha1.Subscription {
+		return &v1alpha1.Subscription{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: &v1alpha1.SubscriptionSpec{
+				CatalogSource:          source,
+				CatalogSourceNamespace: sourceNamespace,
+				Package:                "rainbows",
+				Channel:                "magical",
+			},
+		}
+	}
+	catalogSource := func(name, namespace string) *v1alpha1.CatalogSource {
+		return &v1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
 
-				// Ensure that the catalog source has been loaded into memory
-				_, ok := op.sources[registry.ResourceKey{Name: tt.catalogSource.GetName(), Namespace: tt.catalogSource.GetNamespace()}]
-				require.True(t, ok, "Expected catalog was not loaded into memory")
+	tests := []struct {
+		name      string
+		deleted   *v1alpha1.CatalogSource
+		tombstone bool
+		requeued  []string
+		remaining []registry.ResourceKey
+	}{
+		{
+			name:      "Global",
+			deleted:   catalogSource("global", "olm"),
+			requeued:  []string{"team/uses-global", "another/uses-global", "team/explicit-global"},
+			remaining: []registry.ResourceKey{{Name: "private", Namespace: "team"}},
+		},
+		{
+			name:      "Private",
+			deleted:   catalogSource("private", "team"),
+			requeued:  []string{"team/uses-private"},
+			remaining: []registry.ResourceKey{{Name: "global", Namespace: "olm"}},
+		},
+		{
+			name:      "Tombstone",
+			deleted:   catalogSource("private", "team"),
+			tombstone: true,
+			requeued:  []string{"team/uses-private"},
+			remaining: []registry.ResourceKey{{Name: "global", Namespace: "olm"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := NewFakeOperator([]runtime.Object{
+				subscription("uses-global", "team", "global", ""),
+				subscription("uses-global", "another", "global", ""),
+				subscription("explicit-global", "team", "global", "olm"),
+				subscription("uses-private", "team", "private", ""),
+				subscription("uses-private", "another", "private", ""),
+				subscription("unrelated", "team", "other", ""),
+				catalogSource("global", "olm"),
+				catalogSource("private", "team"),
+			}, nil, nil, nil, &resolver.ConstraintResolver{}, "olm")
+			require.NoError(t, err)
+			op.updateCatalog(registry.ResourceKey{Name: "global", Namespace: "olm"}, registry.NewInMem(), 0)
+			op.updateCatalog(registry.ResourceKey{Name: "private", Namespace: "team"}, registry.NewInMem(), 0)
+			loaded := op.catalogs()
+
+			// The informer drops the catalog source from its store before calling the handler
+			require.NoError(t, op.catsrcInformers[0].GetIndexer().Delete(tt.deleted))
+
+			var deleted interface{} = tt.deleted
+			if tt.tombstone {
+				deleted = cache.DeletedFinalStateUnknown{Key: "team/private", Obj: tt.deleted}
+			}
+			timeNow = func() metav1.Time { return metav1.NewTime(loaded.lastUpdate.Add(time.Minute)) }
+			defer func() { timeNow = func() metav1.Time { return metav1.NewTime(time.Now().UTC()) } }()
+			op.catalogSourceEventHandlers(op.catsrcQueue).OnDelete(deleted)
+
+			// A sync that was in flight during the deletion can't add the catalog source back
+			op.updateCatalog(registry.ResourceKey{Name: tt.deleted.GetName(), Namespace: tt.deleted.GetNamespace()}, registry.NewInMem(), 0)
+
+			catalogs := op.catalogs()
+			require.True(t, catalogs.lastUpdate.After(loaded.lastUpdate.Time), "removal isn't a catalog update")
+			keys := []registry.ResourceKey{}
+			for key := range catalogs.sources {
+				keys = append(keys, key)
 			}
+			require.ElementsMatch(t, tt.remaining, keys)
+
+			requeued := []string{}
+			for op.subQueue.Len() > 0 {
+				key, _ := op.subQueue.Get()
+				requeued = append(requeued, key.(string))
+				op.subQueue.Done(key)
+			}
+			require.ElementsMatch(t, tt.requeued, requeued)
 		})
 	}
 }
 
+func TestSyncSubscriptionDeletedCatalogSource(t *testing.T) {
+	lastSync := metav1.NewTime(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC))
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "sub", Namespace: "team"},
+		Spec: &v1alpha1.SubscriptionSpec{
+			CatalogSource: "private",
+			Package:       "rainbows",
+			Channel:       "magical",
+		},
+		Status: v1alpha1.SubscriptionStatus{
+			CurrentCSV:   "rainbows.v1",
+			InstalledCSV: "rainbows.v1",
+			State:        v1alpha1.SubscriptionStateAtLatest,
+			LastUpdated:  lastSync,
+		},
+	}
+	op, err := NewFakeOperator([]runtime.Object{sub}, nil, nil, nil, &resolver.ConstraintResolver{}, "olm")
+	require.NoError(t, err)
+	defer func() { timeNow = func() metav1.Time { return metav1.NewTime(time.Now().UTC()) } }()
+
+	// Loaded before the subscription's last sync, removed after it
+	key := registry.ResourceKey{Name: "private", Namespace: "team"}
+	timeNow = func() metav1.Time { return metav1.NewTime(lastSync.Add(-time.Minute)) }
+	op.updateCatalog(key, registry.NewInMem(), 0)
+	timeNow = func() metav1.Time { return metav1.NewTime(lastSync.Add(time.Minute)) }
+	op.removeCatalog(key)
+
+	out, err := op.syncSubscription(sub)
+	require.EqualError(t, err, "unknown catalog source private in namespace olm")
+	require.Equal(t, v1alpha1.SubscriptionReasonInvalidCatalog, out.Status.Reason)
+	cond := out.Status.GetCondition(v1alpha1.SubscriptionCatalogSourcesUnhealthy)
+	require.Equal(t, corev1.ConditionTrue, cond.Status)
+	require.Equal(t, v1alpha1.SubscriptionReasonInvalidCatalog, cond.Reason)
+}
+
+func TestGetSourcesSnapshot(t *testing.T) {
+	op := &Operator{
+		namespace: "olm",
+		sources: &catalogSnapshot{
+			sources: map[registry.ResourceKey]registry.Source{
+				{Name: "global", Namespace: "olm"}:      registry.NewInMem(),
+				{Name: "private", Namespace: "olm"}:     registry.NewInMem(),
+				{Name: "private", Namespace: "team"}:    registry.NewInMem(),
+				{Name: "private", Namespace: "another"}: registry.NewInMem(),
+				{Name: "team", Namespace: "team"}:       registry.NewInMem(),
+				{Name: "urgent", Namespace: "olm"}:      registry.NewInMem(),
+			},
+			priorities: map[registry.ResourceKey]int{
+				{Name: "urgent", Namespace: "olm"}: 10,
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		source          string
+		sourceNamespace string
+		expected        []registry.ResourceKey
+	}{
+		{
+			name:            "plan catalog source first, then priority and name",
+			source:          "private",
+			sourceNamespace: "team",
+			expected: []registry.ResourceKey{
+				{Name: "private", Namespace: "team"},
+				{Name: "urgent", Namespace: "olm"},
+				{Name: "global", Namespace: "olm"},
+				{Name: "private", Namespace: "olm"},
+				{Name: "team", Namespace: "team"},
+			},
+		},
+		{
+			name:   "plan namespace preferred without catalog source namespace",
+			source: "private",
+			expected: []registry.ResourceKey{
+				{Name: "private", Namespace: "team"},
+				{Name: "private", Namespace: "olm"},
+				{Name: "urgent", Namespace: "olm"},
+				{Name: "global", Namespace: "olm"},
+				{Name: "team", Namespace: "team"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &v1alpha1.InstallPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "team"},
+				Spec: v1alpha1.InstallPlanSpec{
+					CatalogSource:          tt.source,
+					CatalogSourceNamespace: tt.sourceNamespace,
+				},
+			}
+
+			keys := []registry.ResourceKey{}
+			for _, ref := range op.getSourcesSnapshot(plan) {
+				keys = append(keys, ref.SourceKey)
+			}
+			require.Equal(t, tt.expected, keys)
+		})
+	}
+}
+
+func TestCatalogNamespaces(t *testing.T) {
+	require.Equal(t, []string{"olm"}, catalogNamespaces("olm", []string{"olm"}))
+	require.Equal(t, []string{"olm", "team", "another"}, catalogNamespaces("olm", []string{"team", "olm", "another"}))
+	require.Equal(t, []string{metav1.NamespaceAll}, catalogNamespaces("olm", []string{"team", metav1.NamespaceAll}))
+}
+
 func TestCompetingCRDOwnersExist(t *testing.T) {
 
 	testNamespace := "default"
@@ -385,14 +899,50 @@ func NewFakeOperator(clientObjs []runtime.Object, k8sObjs []runtime.Object, extO
 		return nil, err
 	}
 
+	// Create informers with stores populated from the client objects
+	informerFactory := externalversions.NewSharedInformerFactory(clientFake, 0)
+	subInformer := informerFactory.Operators().V1alpha1().Subscriptions().Informer()
+	if err := subInformer.AddIndexers(cache.Indexers{subscriptionCSVIndex: subscriptionCSVIndexFunc}); err != nil {
+		return nil, err
+	}
+	catsrcInformer := informerFactory.Operators().V1alpha1().CatalogSources().Informer()
+	if err := catsrcInformer.AddIndexers(cache.Indexers{catalogSourceConfigMapIndex: catalogSourceConfigMapIndexFunc}); err != nil {
+		return nil, err
+	}
+	ipInformer := informerFactory.Operators().V1alpha1().InstallPlans().Informer()
+	if err := ipInformer.AddIndexers(cache.Indexers{installPlanSubscriptionIndex: installPlanSubscriptionIndexFunc}); err != nil {
+		return nil, err
+	}
+	for _, obj := range clientObjs {
+		var err error
+		switch obj.(type) {
+		case *v1alpha1.Subscription:
+			err = subInformer.GetIndexer().Add(obj)
+		case *v1alpha1.CatalogSource:
+			err = catsrcInformer.GetIndexer().Add(obj)
+		case *v1alpha1.InstallPlan:
+			err = ipInformer.GetIndexer().Add(obj)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create the new operator
 	queueOperator, err := queueinformer.NewOperatorFromClient(opClientFake)
 	op := &Operator{
-		Operator:           queueOperator,
-		client:             clientFake,
-		namespace:          namespace,
-		sources:            make(map[registry.ResourceKey]registry.Source),
-		dependencyResolver: resolver,
+		Operator:                queueOperator,
+		client:                  clientFake,
+		namespace:               namespace,
+		sources:                 newCatalogSnapshot(),
+		dependencyResolver:      resolver,
+		subQueue:                workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "subscriptions"),
+		catsrcQueue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources"),
+		subInformers:            []cache.SharedIndexInformer{subInformer},
+		catsrcInformers:         []cache.SharedIndexInformer{catsrcInformer},
+		ipInformers:             []cache.SharedIndexInformer{ipInformer},
+		restMapper:              newRESTMapper(queueOperator.OpClient.KubernetesInterface().Discovery()),
+		installPlanHistoryLimit: -1,
 	}
 
 	return op, nil
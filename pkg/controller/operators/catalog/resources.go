@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"encoding/json"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// newRESTMapper returns a RESTMapper backed by an in-memory cache of the given discovery client. The cache is filled
+// on first use and only refreshed by restMapping when a kind isn't found.
+func newRESTMapper(client discovery.DiscoveryInterface) *restmapper.DeferredDiscoveryRESTMapper {
+	return restmapper.NewDeferredDiscoveryRESTMapper(cached.NewMemCacheClient(client))
+}
+
+// restMapping maps the given kind to the resource serving it. The discovery cache is refreshed if the kind isn't
+// found, since it may be served by a CRD created after the cache was filled, for example by an earlier step.
+func (o *Operator) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := o.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) || err == cached.ErrCacheEmpty {
+		o.restMapper.Reset()
+		mapping, err = o.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+// stepObject returns the object in a step's manifest along with a client for the resource serving its kind. The kind
+// of the step is used if the manifest doesn't set one. Namespaced resources are accessed in the given namespace.
+func (o *Operator) stepObject(resource v1alpha1.StepResource, namespace string) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+	// Marshal the manifest into an unstructured object.
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal([]byte(resource.Manifest), &obj.Object); err != nil {
+		return nil, nil, err
+	}
+
+	if obj.GetKind() == "" {
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: resource.Group, Version: resource.Version, Kind: resource.Kind})
+	}
+
+	mapping, err := o.restMapping(obj.GroupVersionKind())
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return obj, o.dynamicClient.Resource(mapping.Resource), nil
+	}
+	obj.SetNamespace(namespace)
+	return obj, o.dynamicClient.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// createResource creates the manifest of a step whose kind isn't handled by a typed client. The kind is mapped to its
+// resource through discovery, namespaced resources are created in the plan's namespace, and CSV owner references are
+// updated like those of the typed kinds.
+func (o *Operator) createResource(resource v1alpha1.StepResource, namespace string) (v1alpha1.StepStatus, error) {
+	obj, client, err := o.stepObject(resource, namespace)
+	if err != nil {
+		return v1alpha1.StepStatusUnknown, err
+	}
+
+	// Update UIDs on all CSV OwnerReferences
+	if refs := obj.GetOwnerReferences(); len(refs) > 0 {
+		updated, err := o.getUpdatedOwnerReferences(refs, namespace)
+		if err != nil {
+			return v1alpha1.StepStatusUnknown, err
+		}
+		obj.SetOwnerReferences(updated)
+	}
+
+	// Attempt to create the resource.
+	_, err = client.Create(obj)
+	if k8serrors.IsAlreadyExists(err) {
+		// If it already existed, mark the step as Present.
+		return v1alpha1.StepStatusPresent, nil
+	} else if err != nil {
+		return v1alpha1.StepStatusUnknown, err
+	}
+
+	// If no error occurred, mark the step as Created.
+	return v1alpha1.StepStatusCreated, nil
+}
+
+// deleteResource deletes the resource created by createResource for a step
+func (o *Operator) deleteResource(resource v1alpha1.StepResource, namespace string, opts *metav1.DeleteOptions) error {
+	obj, client, err := o.stepObject(resource, namespace)
+	if err != nil {
+		return err
+	}
+
+	return client.Delete(obj.GetName(), opts)
+}
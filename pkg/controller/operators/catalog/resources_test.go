@@ -0,0 +1,296 @@
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// apiServer records the objects created through it, rejects objects that were already created, and gets and deletes
+// the objects it holds
+type apiServer struct {
+	*httptest.Server
+	lock    sync.Mutex
+	created map[string]*unstructured.Unstructured
+	deleted []string
+}
+
+func newAPIServer(t *testing.T, existing ...string) *apiServer {
+	s := &apiServer{created: map[string]*unstructured.Unstructured{}}
+	for _, p := range existing {
+		s.created[p] = nil
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodPost:
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{}
+			require.NoError(t, obj.UnmarshalJSON(body))
+
+			p := r.URL.Path + "/" + obj.GetName()
+			if _, ok := s.created[p]; ok {
+				writeStatus(w, metav1.StatusReasonAlreadyExists, http.StatusConflict)
+				return
+			}
+			s.created[p] = obj
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case http.MethodGet:
+			obj, ok := s.created[r.URL.Path]
+			if !ok || obj == nil {
+				writeStatus(w, metav1.StatusReasonNotFound, http.StatusNotFound)
+				return
+			}
+			data, err := obj.MarshalJSON()
+			require.NoError(t, err)
+			w.Write(data)
+		case http.MethodDelete:
+			if _, ok := s.created[r.URL.Path]; !ok {
+				writeStatus(w, metav1.StatusReasonNotFound, http.StatusNotFound)
+				return
+			}
+			delete(s.created, r.URL.Path)
+			s.deleted = append(s.deleted, r.URL.Path)
+			json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusSuccess,
+			})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	return s
+}
+
+func writeStatus(w http.ResponseWriter, reason metav1.StatusReason, code int32) {
+	w.WriteHeader(int(code))
+	json.NewEncoder(w).Encode(metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Reason:   reason,
+		Code:     code,
+	})
+}
+
+// add adds an existing object to the server
+func (s *apiServer) add(path string, obj map[string]interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.created[path] = &unstructured.Unstructured{Object: obj}
+}
+
+// serve sets up the operator to create resources through the server, and to discover the resources it serves
+func (s *apiServer) serve(t *testing.T, op *Operator) *fakediscovery.FakeDiscovery {
+	client, err := dynamic.NewForConfig(&rest.Config{Host: s.URL})
+	require.NoError(t, err)
+	op.dynamicClient = client
+
+	discovery := op.OpClient.KubernetesInterface().Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "services", Namespaced: true, Kind: "Service"},
+				{Name: "services/status", Namespaced: true, Kind: "Service"},
+				{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"},
+			},
+		},
+		{
+			GroupVersion: "scheduling.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "priorityclasses", Namespaced: false, Kind: "PriorityClass"},
+			},
+		},
+	}
+	op.restMapper = newRESTMapper(discovery)
+	return discovery
+}
+
+func manifest(t *testing.T, obj map[string]interface{}) string {
+	data, err := json.Marshal(obj)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExecutePlanGenericResources(t *testing.T) {
+	namespace := "ns"
+	csv := &v1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcdoperator.v0.9.2", Namespace: namespace, UID: types.UID("csv-uid")},
+	}
+	ownerRefs := []interface{}{
+		map[string]interface{}{
+			"apiVersion": v1alpha1.SchemeGroupVersion.String(),
+			"kind":       v1alpha1.ClusterServiceVersionKind,
+			"name":       csv.GetName(),
+			"uid":        "stale-uid",
+		},
+	}
+
+	tests := []struct {
+		testName          string
+		step              v1alpha1.StepResource
+		existing          []string
+		expectedPath      string
+		expectedNamespace string
+		expectedStatus    v1alpha1.StepStatus
+		expectedErr       bool
+	}{
+		{
+			testName: "NamespacedCoreKind",
+			step: v1alpha1.StepResource{
+				Name: "etcd-service", Version: "v1", Kind: "Service",
+				Manifest: manifest(t, map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"name": "etcd-service", "namespace": "other", "ownerReferences": ownerRefs},
+				}),
+			},
+			expectedPath:      "/api/v1/namespaces/ns/services/etcd-service",
+			expectedNamespace: namespace,
+			expectedStatus:    v1alpha1.StepStatusCreated,
+		},
+		{
+			testName: "ClusterScopedGroupKind",
+			step: v1alpha1.StepResource{
+				Name: "etcd-priority", Group: "scheduling.k8s.io", Version: "v1beta1", Kind: "PriorityClass",
+				Manifest: manifest(t, map[string]interface{}{
+					"apiVersion": "scheduling.k8s.io/v1beta1",
+					"kind":       "PriorityClass",
+					"metadata":   map[string]interface{}{"name": "etcd-priority"},
+					"value":      1000,
+				}),
+			},
+			expectedPath:   "/apis/scheduling.k8s.io/v1beta1/priorityclasses/etcd-priority",
+			expectedStatus: v1alpha1.StepStatusCreated,
+		},
+		{
+			testName: "KindFromStep",
+			step: v1alpha1.StepResource{
+				Name: "etcd-config", Version: "v1", Kind: "ConfigMap",
+				Manifest: manifest(t, map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "etcd-config"},
+				}),
+			},
+			expectedPath:      "/api/v1/namespaces/ns/configmaps/etcd-config",
+			expectedNamespace: namespace,
+			expectedStatus:    v1alpha1.StepStatusCreated,
+		},
+		{
+			testName: "AlreadyExists",
+			step: v1alpha1.StepResource{
+				Name: "etcd-service", Version: "v1", Kind: "Service",
+				Manifest: manifest(t, map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"name": "etcd-service"},
+				}),
+			},
+			existing:       []string{"/api/v1/namespaces/ns/services/etcd-service"},
+			expectedStatus: v1alpha1.StepStatusPresent,
+		},
+		{
+			testName: "UnknownKind",
+			step: v1alpha1.StepResource{
+				Name: "etcd-widget", Group: "example.com", Version: "v1", Kind: "Widget",
+				Manifest: manifest(t, map[string]interface{}{
+					"apiVersion": "example.com/v1",
+					"kind":       "Widget",
+					"metadata":   map[string]interface{}{"name": "etcd-widget"},
+				}),
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			op, err := NewFakeOperator([]runtime.Object{csv}, nil, nil, nil, nil, namespace)
+			require.NoError(t, err)
+
+			server := newAPIServer(t, tt.existing...)
+			defer server.Close()
+			server.serve(t, op)
+
+			plan := &v1alpha1.InstallPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+				Status: v1alpha1.InstallPlanStatus{
+					Phase: v1alpha1.InstallPlanPhaseInstalling,
+					Plan: []v1alpha1.Step{
+						{Resource: tt.step, Status: v1alpha1.StepStatusUnknown},
+					},
+				},
+			}
+
+			err = op.ExecutePlan(plan)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, plan.Status.Plan[0].Status)
+			if tt.expectedPath == "" {
+				return
+			}
+
+			obj, ok := server.created[tt.expectedPath]
+			require.True(t, ok, "expected %s to be created", tt.expectedPath)
+			require.Equal(t, tt.step.Kind, obj.GetKind())
+			require.Equal(t, tt.expectedNamespace, obj.GetNamespace())
+			for _, ref := range obj.GetOwnerReferences() {
+				require.Equal(t, csv.GetUID(), ref.UID)
+			}
+		})
+	}
+}
+
+func TestRESTMappingRefreshesDiscovery(t *testing.T) {
+	op, err := NewFakeOperator(nil, nil, nil, nil, nil, "ns")
+	require.NoError(t, err)
+
+	server := newAPIServer(t)
+	defer server.Close()
+	discovery := server.serve(t, op)
+
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	mapping, err := op.restMapping(service)
+	require.NoError(t, err)
+	require.Equal(t, schema.GroupVersionResource{Version: "v1", Resource: "services"}, mapping.Resource)
+
+	// Known kinds are mapped from the cache
+	discoveryCalls := len(discovery.Actions())
+	_, err = op.restMapping(service)
+	require.NoError(t, err)
+	require.Len(t, discovery.Actions(), discoveryCalls)
+
+	// Kinds added after the cache was filled, like those of CRDs created by earlier steps, refresh the cache
+	widget := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget"}},
+	})
+	mapping, err = op.restMapping(widget)
+	require.NoError(t, err)
+	require.Equal(t, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, mapping.Resource)
+
+	_, err = op.restMapping(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"})
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/metrics"
+)
+
+// historyLimit returns the number of Complete InstallPlans to keep for a subscription, or a negative number if all
+// of them are kept
+func (o *Operator) historyLimit(sub *v1alpha1.Subscription) int {
+	if sub.Spec.InstallPlanHistoryLimit != nil {
+		return int(*sub.Spec.InstallPlanHistoryLimit)
+	}
+	return o.installPlanHistoryLimit
+}
+
+// pruneInstallPlans deletes the oldest Complete InstallPlans of a subscription beyond its history limit. Plans that
+// aren't Complete, and the plan the subscription currently references, are always kept.
+func (o *Operator) pruneInstallPlans(sub *v1alpha1.Subscription) error {
+	limit := o.historyLimit(sub)
+	if limit < 0 {
+		return nil
+	}
+
+	var complete []*v1alpha1.InstallPlan
+	key := fmt.Sprintf("%s/%s", sub.GetNamespace(), sub.GetName())
+	for _, informer := range o.ipInformers {
+		plans, err := informer.GetIndexer().ByIndex(installPlanSubscriptionIndex, key)
+		if err != nil {
+			return fmt.Errorf("error listing InstallPlans of subscription %s: %v", sub.GetName(), err)
+		}
+		for _, obj := range plans {
+			plan := obj.(*v1alpha1.InstallPlan)
+			// A recreated subscription doesn't own the plans of the one it replaced
+			if plan.Status.Phase != v1alpha1.InstallPlanPhaseComplete || !ownerutil.IsOwnedBy(plan, sub) {
+				continue
+			}
+			if sub.Status.Install != nil && sub.Status.Install.Name == plan.GetName() {
+				limit--
+				continue
+			}
+			complete = append(complete, plan)
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if len(complete) <= limit {
+		return nil
+	}
+
+	// Newest first, so that the plans past the limit are the oldest.
+	sort.Slice(complete, func(i, j int) bool {
+		ti, tj := complete[i].GetCreationTimestamp(), complete[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return tj.Before(&ti)
+		}
+		return complete[i].GetName() > complete[j].GetName()
+	})
+
+	var errs []error
+	for _, plan := range complete[limit:] {
+		err := o.client.OperatorsV1alpha1().InstallPlans(plan.GetNamespace()).Delete(plan.GetName(), &metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		log.WithFields(log.Fields{
+			"sub":       sub.GetName(),
+			"namespace": sub.GetNamespace(),
+			"ip":        plan.GetName(),
+		}).Info("pruned InstallPlan")
+		metrics.InstallPlanPrunedCount.Inc()
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,179 @@
+package catalog
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
+)
+
+func TestPruneInstallPlans(t *testing.T) {
+	namespace := "ns"
+	created := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: namespace, UID: types.UID("etcd-sub")},
+		Spec:       &v1alpha1.SubscriptionSpec{Package: "etcd"},
+		Status: v1alpha1.SubscriptionStatus{
+			Install: &v1alpha1.InstallPlanReference{Name: "install-etcd-0"},
+		},
+	}
+	other := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: namespace, UID: types.UID("prometheus-sub")},
+	}
+
+	plan := func(name string, age int, phase v1alpha1.InstallPlanPhase, owner *v1alpha1.Subscription) runtime.Object {
+		ip := &v1alpha1.InstallPlan{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(created.Add(-time.Duration(age) * time.Hour)),
+			},
+			Status: v1alpha1.InstallPlanStatus{Phase: phase},
+		}
+		if owner != nil {
+			ownerutil.AddNonBlockingOwner(ip, owner)
+		}
+		return ip
+	}
+	plans := []runtime.Object{
+		// The plan the subscription references is kept even though it's the oldest.
+		plan("install-etcd-0", 10, v1alpha1.InstallPlanPhaseComplete, sub),
+		plan("install-etcd-1", 5, v1alpha1.InstallPlanPhaseComplete, sub),
+		plan("install-etcd-2", 4, v1alpha1.InstallPlanPhaseComplete, sub),
+		plan("install-etcd-3", 3, v1alpha1.InstallPlanPhaseComplete, sub),
+		plan("install-etcd-4", 2, v1alpha1.InstallPlanPhaseFailed, sub),
+		plan("install-etcd-5", 1, v1alpha1.InstallPlanPhaseRequiresApproval, sub),
+		plan("install-prometheus-0", 9, v1alpha1.InstallPlanPhaseComplete, other),
+		plan("install-manual-0", 9, v1alpha1.InstallPlanPhaseComplete, nil),
+	}
+
+	remaining := func(op *Operator) []string {
+		list, err := op.client.OperatorsV1alpha1().InstallPlans(namespace).List(metav1.ListOptions{})
+		require.NoError(t, err)
+		var names []string
+		for _, ip := range list.Items {
+			names = append(names, ip.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+	all := []string{
+		"install-etcd-0", "install-etcd-1", "install-etcd-2", "install-etcd-3", "install-etcd-4", "install-etcd-5",
+		"install-manual-0", "install-prometheus-0",
+	}
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		name        string
+		globalLimit int
+		subLimit    *int32
+		expected    []string
+	}{
+		{name: "KeepAll", globalLimit: -1, expected: all},
+		{name: "UnderLimit", globalLimit: 10, expected: all},
+		{name: "GlobalLimit", globalLimit: 2, expected: []string{
+			"install-etcd-0", "install-etcd-3", "install-etcd-4", "install-etcd-5", "install-manual-0", "install-prometheus-0",
+		}},
+		{name: "KeepCurrent", globalLimit: 0, expected: []string{
+			"install-etcd-0", "install-etcd-4", "install-etcd-5", "install-manual-0", "install-prometheus-0",
+		}},
+		{name: "SubscriptionLimit", globalLimit: -1, subLimit: int32Ptr(3), expected: []string{
+			"install-etcd-0", "install-etcd-2", "install-etcd-3", "install-etcd-4", "install-etcd-5", "install-manual-0",
+			"install-prometheus-0",
+		}},
+		{name: "SubscriptionOverride", globalLimit: 0, subLimit: int32Ptr(4), expected: all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := NewFakeOperator(plans, nil, nil, nil, nil, namespace)
+			require.NoError(t, err)
+			op.installPlanHistoryLimit = tt.globalLimit
+
+			s := sub.DeepCopy()
+			s.Spec.InstallPlanHistoryLimit = tt.subLimit
+			require.NoError(t, op.pruneInstallPlans(s))
+			require.Equal(t, tt.expected, remaining(op))
+		})
+	}
+}
+
+func TestInstallPlanEventHandlersRequeueSubscription(t *testing.T) {
+	namespace := "ns"
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: namespace, UID: types.UID("etcd-sub")},
+	}
+
+	tests := []struct {
+		name     string
+		oldPhase v1alpha1.InstallPlanPhase
+		owned    bool
+		expected int
+	}{
+		{name: "PhaseChanged", oldPhase: v1alpha1.InstallPlanPhaseInstalling, owned: true, expected: 1},
+		{name: "PhaseUnchanged", oldPhase: v1alpha1.InstallPlanPhaseComplete, owned: true, expected: 0},
+		{name: "NotOwned", oldPhase: v1alpha1.InstallPlanPhaseInstalling, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := NewFakeOperator(nil, nil, nil, nil, nil, namespace)
+			require.NoError(t, err)
+
+			updated := &v1alpha1.InstallPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+				Status:     v1alpha1.InstallPlanStatus{Phase: v1alpha1.InstallPlanPhaseComplete},
+			}
+			if tt.owned {
+				ownerutil.AddNonBlockingOwner(updated, sub)
+			}
+			old := updated.DeepCopy()
+			old.Status.Phase = tt.oldPhase
+
+			queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "installplans")
+			op.installPlanEventHandlers(queue).OnUpdate(old, updated)
+			require.Equal(t, 1, queue.Len())
+			require.Equal(t, tt.expected, op.subQueue.Len())
+			if tt.expected > 0 {
+				key, _ := op.subQueue.Get()
+				require.Equal(t, "ns/etcd", key)
+			}
+		})
+	}
+}
+
+func TestSyncSubscriptionsPrunesInstallPlans(t *testing.T) {
+	namespace := "ns"
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: namespace, UID: types.UID("etcd-sub")},
+		Spec:       &v1alpha1.SubscriptionSpec{CatalogSource: "catalog", Package: "etcd", Channel: "alpha"},
+		Status: v1alpha1.SubscriptionStatus{
+			Install: &v1alpha1.InstallPlanReference{Name: "install-etcd-1"},
+		},
+	}
+	plan := func(name string) *v1alpha1.InstallPlan {
+		ip := &v1alpha1.InstallPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     v1alpha1.InstallPlanStatus{Phase: v1alpha1.InstallPlanPhaseComplete},
+		}
+		ownerutil.AddNonBlockingOwner(ip, sub)
+		return ip
+	}
+
+	op, err := NewFakeOperator([]runtime.Object{sub, plan("install-etcd-0"), plan("install-etcd-1")}, nil, nil, nil, nil, namespace)
+	require.NoError(t, err)
+	op.installPlanHistoryLimit = 0
+
+	// The subscription can't be resolved without its catalog source, but its old plans are still pruned
+	require.Error(t, op.syncSubscriptions(sub))
+	list, err := op.client.OperatorsV1alpha1().InstallPlans(namespace).List(metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "install-etcd-1", list.Items[0].GetName())
+}
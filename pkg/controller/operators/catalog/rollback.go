@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// RollbackPlan deletes the resources created by a failed InstallPlan, in the reverse order of their dependencies. Steps
+// that were already present before the plan ran are left alone, and so are the dependencies of steps that couldn't be
+// rolled back. The outcome of each deletion is recorded on its step, and an error is returned if any of them failed
+// so that the rollback is retried.
+func (o *Operator) RollbackPlan(plan *v1alpha1.InstallPlan) error {
+	var errs []error
+	blocked := map[string]bool{}
+	for _, i := range rollbackOrder(plan.Status.Plan) {
+		step := &plan.Status.Plan[i]
+		if step.Status != v1alpha1.StepStatusCreated || step.Rollback == v1alpha1.StepRollbackStatusDeleted {
+			continue
+		}
+
+		logger := log.WithFields(log.Fields{
+			"ip":        plan.GetName(),
+			"namespace": plan.GetNamespace(),
+			"kind":      step.Resource.Kind,
+			"name":      step.Resource.Name,
+		})
+
+		if blocked[stepKey(step.Resource)] {
+			logger.Debug("waiting for dependent steps to be rolled back")
+			blockDependencies(blocked, step)
+			continue
+		}
+
+		// A resource that is already gone doesn't need to be rolled back.
+		if err := o.deleteStepResource(step.Resource, plan.GetNamespace()); err != nil && !k8serrors.IsNotFound(err) {
+			logger.WithError(err).Warn("failed to roll back step")
+			step.Rollback = v1alpha1.StepRollbackStatusFailed
+			step.RollbackMessage = err.Error()
+			errs = append(errs, fmt.Errorf("%s %s: %s", step.Resource.Kind, step.Resource.Name, err))
+			blockDependencies(blocked, step)
+			continue
+		}
+
+		logger.Debug("rolled back step")
+		step.Rollback = v1alpha1.StepRollbackStatusDeleted
+		step.RollbackMessage = ""
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// blockDependencies keeps the dependencies of a step that wasn't rolled back from being deleted
+func blockDependencies(blocked map[string]bool, step *v1alpha1.Step) {
+	for _, key := range step.DependsOn {
+		blocked[key] = true
+	}
+}
+
+// deleteStepResource deletes the resource created by a step of the given InstallPlan namespace
+func (o *Operator) deleteStepResource(resource v1alpha1.StepResource, namespace string) error {
+	opts := &metav1.DeleteOptions{}
+	switch resource.Kind {
+	case crdKind:
+		return o.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Delete(resource.Name, opts)
+	case v1alpha1.ClusterServiceVersionKind:
+		return o.client.OperatorsV1alpha1().ClusterServiceVersions(namespace).Delete(resource.Name, opts)
+	case secretKind:
+		return o.OpClient.KubernetesInterface().CoreV1().Secrets(namespace).Delete(resource.Name, opts)
+	case clusterRoleKind:
+		return o.OpClient.KubernetesInterface().RbacV1().ClusterRoles().Delete(resource.Name, opts)
+	case clusterRoleBindingKind:
+		return o.OpClient.KubernetesInterface().RbacV1().ClusterRoleBindings().Delete(resource.Name, opts)
+	case roleKind:
+		return o.OpClient.KubernetesInterface().RbacV1().Roles(namespace).Delete(resource.Name, opts)
+	case roleBindingKind:
+		return o.OpClient.KubernetesInterface().RbacV1().RoleBindings(namespace).Delete(resource.Name, opts)
+	case serviceAccountKind:
+		return o.OpClient.KubernetesInterface().CoreV1().ServiceAccounts(namespace).Delete(resource.Name, opts)
+	default:
+		return o.deleteResource(resource, namespace, opts)
+	}
+}
@@ -0,0 +1,286 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+func TestRollbackPlan(t *testing.T) {
+	namespace := "ns"
+	csv := &v1alpha1.ClusterServiceVersion{ObjectMeta: metav1.ObjectMeta{Name: "etcdoperator.v0.9.2", Namespace: namespace}}
+	crd := &v1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "etcdclusters.etcd.database.coreos.com"}}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator", Namespace: namespace}}
+	role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator", Namespace: namespace}}
+
+	op, err := NewFakeOperator([]runtime.Object{csv}, []runtime.Object{sa, role}, []runtime.Object{crd}, nil, nil, namespace)
+	require.NoError(t, err)
+
+	server := newAPIServer(t, "/api/v1/namespaces/ns/services/etcd-service")
+	defer server.Close()
+	server.serve(t, op)
+
+	plan := &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+		Status: v1alpha1.InstallPlanStatus{
+			Phase: v1alpha1.InstallPlanPhaseFailed,
+			Plan: []v1alpha1.Step{
+				{Resource: v1alpha1.StepResource{Kind: crdKind, Name: crd.GetName()}, Status: v1alpha1.StepStatusCreated},
+				{Resource: v1alpha1.StepResource{Kind: v1alpha1.ClusterServiceVersionKind, Name: csv.GetName()}, Status: v1alpha1.StepStatusCreated},
+				{Resource: v1alpha1.StepResource{Kind: serviceAccountKind, Name: sa.GetName()}, Status: v1alpha1.StepStatusCreated},
+				{Resource: v1alpha1.StepResource{Kind: roleKind, Name: role.GetName()}, Status: v1alpha1.StepStatusPresent},
+				{Resource: v1alpha1.StepResource{Kind: roleBindingKind, Name: "etcd-operator"}, Status: v1alpha1.StepStatusCreated},
+				{
+					Resource: v1alpha1.StepResource{
+						Version: "v1", Kind: "Service", Name: "etcd-service",
+						Manifest: manifest(t, map[string]interface{}{
+							"apiVersion": "v1",
+							"kind":       "Service",
+							"metadata":   map[string]interface{}{"name": "etcd-service"},
+						}),
+					},
+					Status: v1alpha1.StepStatusCreated,
+				},
+				{Resource: v1alpha1.StepResource{Kind: secretKind, Name: "pull-secret"}, Status: v1alpha1.StepStatusUnknown},
+			},
+		},
+	}
+
+	require.NoError(t, op.RollbackPlan(plan))
+
+	// Only the steps created by the plan are rolled back, including those that are already gone.
+	expected := []v1alpha1.StepRollbackStatus{
+		v1alpha1.StepRollbackStatusDeleted,
+		v1alpha1.StepRollbackStatusDeleted,
+		v1alpha1.StepRollbackStatusDeleted,
+		"",
+		v1alpha1.StepRollbackStatusDeleted,
+		v1alpha1.StepRollbackStatusDeleted,
+		"",
+	}
+	for i, step := range plan.Status.Plan {
+		require.Equal(t, expected[i], step.Rollback, "step %d", i)
+		require.Empty(t, step.RollbackMessage)
+	}
+
+	_, err = op.client.OperatorsV1alpha1().ClusterServiceVersions(namespace).Get(csv.GetName(), metav1.GetOptions{})
+	require.True(t, k8serrors.IsNotFound(err))
+	_, err = op.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Get(crd.GetName(), metav1.GetOptions{})
+	require.True(t, k8serrors.IsNotFound(err))
+	_, err = op.OpClient.KubernetesInterface().RbacV1().Roles(namespace).Get(role.GetName(), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/api/v1/namespaces/ns/services/etcd-service"}, server.deleted)
+
+	// Steps are rolled back in reverse order.
+	var deleted []string
+	for _, action := range op.OpClient.KubernetesInterface().(*k8sfake.Clientset).Actions() {
+		if action.GetVerb() == "delete" {
+			deleted = append(deleted, action.(k8stesting.DeleteAction).GetResource().Resource)
+		}
+	}
+	require.Equal(t, []string{"rolebindings", "serviceaccounts"}, deleted)
+}
+
+func TestRollbackPlanFailedStep(t *testing.T) {
+	namespace := "ns"
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "etcd-operator", Namespace: namespace}}
+	csv := &v1alpha1.ClusterServiceVersion{ObjectMeta: metav1.ObjectMeta{Name: "etcdoperator.v0.9.2", Namespace: namespace}}
+
+	op, err := NewFakeOperator([]runtime.Object{csv}, []runtime.Object{sa}, nil, nil, nil, namespace)
+	require.NoError(t, err)
+
+	server := newAPIServer(t)
+	defer server.Close()
+	server.serve(t, op)
+
+	plan := &v1alpha1.InstallPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-etcd", Namespace: namespace},
+		Status: v1alpha1.InstallPlanStatus{
+			Phase: v1alpha1.InstallPlanPhaseFailed,
+			Plan: []v1alpha1.Step{
+				{Resource: v1alpha1.StepResource{Kind: serviceAccountKind, Name: sa.GetName()}, Status: v1alpha1.StepStatusCreated},
+				{
+					Resource: v1alpha1.StepResource{
+						Group: "example.com", Version: "v1", Kind: "Widget", Name: "etcd-widget",
+						Manifest: manifest(t, map[string]interface{}{
+							"apiVersion": "example.com/v1",
+							"kind":       "Widget",
+							"metadata":   map[string]interface{}{"name": "etcd-widget"},
+						}),
+					},
+					Status:    v1alpha1.StepStatusCreated,
+					DependsOn: []string{"ClusterServiceVersion/" + csv.GetName()},
+				},
+				{Resource: v1alpha1.StepResource{Kind: v1alpha1.ClusterServiceVersionKind, Name: csv.GetName()}, Status: v1alpha1.StepStatusCreated},
+			},
+		},
+	}
+
+	// A failed step doesn't stop the remaining steps from being rolled back.
+	require.Error(t, op.RollbackPlan(plan))
+	require.Equal(t, v1alpha1.StepRollbackStatusFailed, plan.Status.Plan[1].Rollback)
+	require.NotEmpty(t, plan.Status.Plan[1].RollbackMessage)
+	require.Equal(t, v1alpha1.StepRollbackStatusDeleted, plan.Status.Plan[0].Rollback)
+
+	_, err = op.OpClient.KubernetesInterface().CoreV1().ServiceAccounts(namespace).Get(sa.GetName(), metav1.GetOptions{})
+	require.True(t, k8serrors.IsNotFound(err))
+
+	// The steps it depends on are kept until it has been rolled back.
+	require.Empty(t, plan.Status.Plan[2].Rollback)
+	_, err = op.client.OperatorsV1alpha1().ClusterServiceVersions(namespace).Get(csv.GetName(), metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestTransitionInstallPlanRollback(t *testing.T) {
+	errMsg := "transition test error"
+	rollbackErrMsg := "rollback test error"
+
+	tests := []struct {
+		name              string
+		rollbackOnFailure bool
+		transError        error
+		rollbackError     error
+		expected          *v1alpha1.InstallPlanCondition
+	}{
+		{
+			name:       "Disabled",
+			transError: errors.New(errMsg),
+		},
+		{
+			name:              "Succeeded",
+			rollbackOnFailure: true,
+			transError:        errors.New(errMsg),
+			expected: &v1alpha1.InstallPlanCondition{
+				Type:   v1alpha1.InstallPlanRolledBack,
+				Status: corev1.ConditionTrue,
+			},
+		},
+		{
+			name:              "Failed",
+			rollbackOnFailure: true,
+			transError:        errors.New(errMsg),
+			rollbackError:     errors.New(rollbackErrMsg),
+			expected: &v1alpha1.InstallPlanCondition{
+				Type:    v1alpha1.InstallPlanRolledBack,
+				Status:  corev1.ConditionFalse,
+				Reason:  v1alpha1.InstallPlanReasonRollbackFailed,
+				Message: rollbackErrMsg,
+			},
+		},
+		{
+			name:              "Installed",
+			rollbackOnFailure: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := v1alpha1.InstallPlan{
+				Spec: v1alpha1.InstallPlanSpec{
+					Approval:          v1alpha1.ApprovalAutomatic,
+					RollbackOnFailure: tt.rollbackOnFailure,
+				},
+				Status: v1alpha1.InstallPlanStatus{
+					Phase: v1alpha1.InstallPlanPhaseInstalling,
+				},
+			}
+
+			transitioner := &mockTransitioner{err: tt.transError, rollbackErr: tt.rollbackError}
+			out, _ := transitionInstallPlanState(transitioner, plan)
+
+			var rolledBack *v1alpha1.InstallPlanCondition
+			for i, cond := range out.Status.Conditions {
+				if cond.Type == v1alpha1.InstallPlanRolledBack {
+					rolledBack = &out.Status.Conditions[i]
+				}
+			}
+			if tt.expected == nil {
+				require.Nil(t, rolledBack)
+				return
+			}
+			require.NotNil(t, rolledBack)
+			require.Equal(t, v1alpha1.InstallPlanPhaseFailed, out.Status.Phase)
+			require.Equal(t, tt.expected.Status, rolledBack.Status)
+			require.Equal(t, tt.expected.Reason, rolledBack.Reason)
+			require.Equal(t, tt.expected.Message, rolledBack.Message)
+		})
+	}
+}
+
+func TestTransitionInstallPlanRetryRollback(t *testing.T) {
+	rollbackErrMsg := "rollback test error"
+
+	tests := []struct {
+		name              string
+		rollbackOnFailure bool
+		rolledBack        corev1.ConditionStatus
+		rollbackError     error
+		err               string
+		expected          corev1.ConditionStatus
+	}{
+		{
+			name:              "RetrySucceeds",
+			rollbackOnFailure: true,
+			rolledBack:        corev1.ConditionFalse,
+			expected:          corev1.ConditionTrue,
+		},
+		{
+			name:              "RetryFails",
+			rollbackOnFailure: true,
+			rolledBack:        corev1.ConditionFalse,
+			rollbackError:     errors.New(rollbackErrMsg),
+			err:               rollbackErrMsg,
+			expected:          corev1.ConditionFalse,
+		},
+		{
+			name:              "AlreadyRolledBack",
+			rollbackOnFailure: true,
+			rolledBack:        corev1.ConditionTrue,
+			rollbackError:     errors.New(rollbackErrMsg),
+			expected:          corev1.ConditionTrue,
+		},
+		{
+			name:          "Disabled",
+			rolledBack:    corev1.ConditionFalse,
+			rollbackError: errors.New(rollbackErrMsg),
+			expected:      corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := v1alpha1.InstallPlan{
+				Spec: v1alpha1.InstallPlanSpec{
+					Approval:          v1alpha1.ApprovalAutomatic,
+					RollbackOnFailure: tt.rollbackOnFailure,
+				},
+				Status: v1alpha1.InstallPlanStatus{
+					Phase: v1alpha1.InstallPlanPhaseFailed,
+					Conditions: []v1alpha1.InstallPlanCondition{{
+						Type:   v1alpha1.InstallPlanRolledBack,
+						Status: tt.rolledBack,
+					}},
+				},
+			}
+
+			transitioner := &mockTransitioner{rollbackErr: tt.rollbackError}
+			out, err := transitionInstallPlanState(transitioner, plan)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, v1alpha1.InstallPlanPhaseFailed, out.Status.Phase)
+			require.Equal(t, tt.expected, out.Status.GetCondition(v1alpha1.InstallPlanRolledBack).Status)
+		})
+	}
+}
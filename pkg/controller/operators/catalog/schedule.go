@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/cron"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nextMaintenanceWindow returns the start of the window that is open at the given time, or of the next window to
+// open if none are open. The returned bool reports whether a window is open.
+func nextMaintenanceWindow(schedule v1alpha1.UpgradeSchedule, now time.Time) (time.Time, bool, error) {
+	loc := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(schedule.TimeZone); err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time zone %q: %v", schedule.TimeZone, err)
+		}
+	}
+	now = now.In(loc)
+
+	var next time.Time
+	for _, window := range schedule.Windows {
+		if window.Duration.Duration <= 0 {
+			return time.Time{}, false, fmt.Errorf("invalid duration %s for window %q", window.Duration.Duration, window.Schedule)
+		}
+		cronSchedule, err := cron.Parse(window.Schedule)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		// The earliest start after now minus the duration is either the start of an open window or the next start
+		start := cronSchedule.Next(now.Add(-window.Duration.Duration))
+		if start.IsZero() {
+			continue
+		}
+		if !start.After(now) {
+			return start, true, nil
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+
+	if next.IsZero() {
+		return time.Time{}, false, fmt.Errorf("no maintenance window opens in the future")
+	}
+	return next, false, nil
+}
+
+// checkMaintenanceWindow records when the subscription's next maintenance window opens in its status and reports
+// whether a window is open. Subscriptions without an upgrade schedule are always open. An invalid schedule is
+// reported in the subscription's ResolutionFailed condition.
+func checkMaintenanceWindow(sub *v1alpha1.Subscription, now time.Time) (bool, error) {
+	if sub.Status.GetCondition(v1alpha1.SubscriptionResolutionFailed).Reason == v1alpha1.SubscriptionReasonInvalidUpgradeSchedule {
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed))
+	}
+	if sub.Spec.UpgradeSchedule == nil {
+		sub.Status.NextMaintenanceWindow = nil
+		return true, nil
+	}
+
+	start, open, err := nextMaintenanceWindow(*sub.Spec.UpgradeSchedule, now)
+	if err != nil {
+		sub.Status.NextMaintenanceWindow = nil
+		err = fmt.Errorf("invalid upgrade schedule for subscription %s: %v", sub.GetName(), err)
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonInvalidUpgradeSchedule, err.Error()))
+		return false, err
+	}
+	next := metav1.NewTime(start.UTC())
+	sub.Status.NextMaintenanceWindow = &next
+	return open, nil
+}
+
+// requiresScheduledApproval reports whether the subscription's InstallPlans must wait for a maintenance window.
+func requiresScheduledApproval(sub *v1alpha1.Subscription) bool {
+	return sub.GetInstallPlanApproval() == v1alpha1.ApprovalAutomatic && sub.Spec.UpgradeSchedule != nil
+}
+
+// holdForMaintenanceWindow approves a held InstallPlan once a maintenance window is open, and otherwise requeues the
+// subscription for when the next window opens.
+func (o *Operator) holdForMaintenanceWindow(sub *v1alpha1.Subscription, ip *v1alpha1.InstallPlan, open bool) error {
+	if ip.Spec.Approved {
+		return nil
+	}
+	if !open {
+		o.requeueSubscriptionAt(sub, sub.Status.NextMaintenanceWindow)
+		return nil
+	}
+
+	log.Infof("maintenance window open: approving installplan %s", ip.GetName())
+	approved := ip.DeepCopy()
+	approved.Spec.Approved = true
+	if _, err := o.client.OperatorsV1alpha1().InstallPlans(ip.GetNamespace()).Update(approved); err != nil {
+		return fmt.Errorf("failed to approve installplan %s: %v", ip.GetName(), err)
+	}
+	return nil
+}
+
+// requeueSubscriptionAt syncs the subscription again at the given time
+func (o *Operator) requeueSubscriptionAt(sub *v1alpha1.Subscription, at *metav1.Time) {
+	if o.subQueue == nil || at == nil {
+		return
+	}
+	key := fmt.Sprintf("%s/%s", sub.GetNamespace(), sub.GetName())
+	o.subQueue.AddAfter(key, at.Sub(timeNow().Time))
+}
@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+func TestNextMaintenanceWindow(t *testing.T) {
+	// Friday
+	now := time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		schedule      v1alpha1.UpgradeSchedule
+		expectedStart time.Time
+		expectedOpen  bool
+		err           string
+	}{
+		{
+			name: "window not open",
+			schedule: v1alpha1.UpgradeSchedule{
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+				},
+			},
+			expectedStart: time.Date(2018, time.January, 27, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "window open",
+			schedule: v1alpha1.UpgradeSchedule{
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 20 * * 5", Duration: metav1.Duration{Duration: time.Hour}},
+				},
+			},
+			expectedStart: time.Date(2018, time.January, 26, 20, 0, 0, 0, time.UTC),
+			expectedOpen:  true,
+		},
+		{
+			name: "window closed at end of duration",
+			schedule: v1alpha1.UpgradeSchedule{
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 20 * * *", Duration: metav1.Duration{Duration: 40 * time.Minute}},
+				},
+			},
+			expectedStart: time.Date(2018, time.January, 27, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "earliest of several windows",
+			schedule: v1alpha1.UpgradeSchedule{
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: time.Hour}},
+					{Schedule: "0 22 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+				},
+			},
+			expectedStart: time.Date(2018, time.January, 26, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "window in time zone",
+			schedule: v1alpha1.UpgradeSchedule{
+				TimeZone: "Asia/Tokyo",
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 6 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+				},
+			},
+			expectedStart: time.Date(2018, time.January, 26, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid time zone",
+			schedule: v1alpha1.UpgradeSchedule{
+				TimeZone: "Nowhere/Special",
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 6 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+				},
+			},
+			err: `invalid time zone "Nowhere/Special": unknown time zone Nowhere/Special`,
+		},
+		{
+			name: "invalid duration",
+			schedule: v1alpha1.UpgradeSchedule{
+				Windows: []v1alpha1.MaintenanceWindow{
+					{Schedule: "0 6 * * *"},
+				},
+			},
+			err: `invalid duration 0s for window "0 6 * * *"`,
+		},
+		{
+			name:     "no windows",
+			schedule: v1alpha1.UpgradeSchedule{},
+			err:      "no maintenance window opens in the future",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, open, err := nextMaintenanceWindow(tt.schedule, now)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.expectedStart.Equal(start), "expected %s, got %s", tt.expectedStart, start)
+			require.Equal(t, tt.expectedOpen, open)
+		})
+	}
+}
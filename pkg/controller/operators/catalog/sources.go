@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+)
+
+// catalogSnapshot is an immutable view of the loaded catalog sources. A snapshot is never modified once it has been
+// published: catalog reloads build a new snapshot and swap it in, so syncs can keep using the snapshot they started
+// with without holding a lock.
+type catalogSnapshot struct {
+	sources    map[registry.ResourceKey]registry.Source
+	priorities map[registry.ResourceKey]int
+	lastUpdate metav1.Time
+}
+
+func newCatalogSnapshot() *catalogSnapshot {
+	return &catalogSnapshot{
+		sources:    map[registry.ResourceKey]registry.Source{},
+		priorities: map[registry.ResourceKey]int{},
+	}
+}
+
+// withSource returns a copy of the snapshot with the source for key replaced. Passing a nil source keeps the loaded
+// source and only updates its priority, which doesn't count as a catalog update.
+func (s *catalogSnapshot) withSource(key registry.ResourceKey, source registry.Source, priority int, now metav1.Time) *catalogSnapshot {
+	out := &catalogSnapshot{
+		sources:    make(map[registry.ResourceKey]registry.Source, len(s.sources)+1),
+		priorities: make(map[registry.ResourceKey]int, len(s.priorities)+1),
+		lastUpdate: s.lastUpdate,
+	}
+	for k, v := range s.sources {
+		out.sources[k] = v
+	}
+	for k, v := range s.priorities {
+		out.priorities[k] = v
+	}
+
+	out.priorities[key] = priority
+	if source != nil {
+		out.sources[key] = source
+		out.lastUpdate = now
+	}
+	return out
+}
+
+// withoutSource returns a copy of the snapshot without the source for key. Removing a source counts as a catalog
+// update, so that subscriptions resolve again without it.
+func (s *catalogSnapshot) withoutSource(key registry.ResourceKey, now metav1.Time) *catalogSnapshot {
+	out := &catalogSnapshot{
+		sources:    make(map[registry.ResourceKey]registry.Source, len(s.sources)),
+		priorities: make(map[registry.ResourceKey]int, len(s.priorities)),
+		lastUpdate: now,
+	}
+	for k, v := range s.sources {
+		if k != key {
+			out.sources[k] = v
+		}
+	}
+	for k, v := range s.priorities {
+		if k != key {
+			out.priorities[k] = v
+		}
+	}
+	return out
+}
+
+// catalogs returns the current catalog snapshot. The result must not be modified.
+func (o *Operator) catalogs() *catalogSnapshot {
+	o.sourcesLock.RLock()
+	defer o.sourcesLock.RUnlock()
+	if o.sources == nil {
+		return newCatalogSnapshot()
+	}
+	return o.sources
+}
+
+// updateCatalog atomically replaces the current snapshot with one that includes the given source. Only the swap
+// happens under the lock; loading the source is left to the caller. A replaced source that holds a connection is
+// closed, which fails any sync still using it so that it's retried against the new snapshot.
+//
+// The informer store drops a deleted catalog source before removeCatalog runs, so checking the store under the lock
+// keeps a sync that was in flight during the deletion from adding the source back.
+func (o *Operator) updateCatalog(key registry.ResourceKey, source registry.Source, priority int) {
+	o.sourcesLock.Lock()
+	if !o.catalogSourceExists(key) {
+		o.sourcesLock.Unlock()
+		log.Debugf("not publishing deleted catalog source %s in namespace %s", key.Name, key.Namespace)
+		if source != nil {
+			closeSource(key, source)
+		}
+		return
+	}
+	current := o.sources
+	if current == nil {
+		current = newCatalogSnapshot()
+	}
+	o.sources = current.withSource(key, source, priority, timeNow())
+	o.sourcesLock.Unlock()
+
+	replaced, ok := current.sources[key]
+	if !ok || source == nil || replaced == source {
+		return
+	}
+	closeSource(key, replaced)
+}
+
+// removeCatalog atomically replaces the current snapshot with one without the given source, and closes the removed
+// source.
+func (o *Operator) removeCatalog(key registry.ResourceKey) {
+	o.sourcesLock.Lock()
+	current := o.sources
+	if current == nil {
+		current = newCatalogSnapshot()
+	}
+	o.sources = current.withoutSource(key, timeNow())
+	o.sourcesLock.Unlock()
+
+	if removed, ok := current.sources[key]; ok {
+		closeSource(key, removed)
+	}
+}
+
+// catalogSourceExists returns true if the catalog source is in an informer's store
+func (o *Operator) catalogSourceExists(key registry.ResourceKey) bool {
+	for _, informer := range o.catsrcInformers {
+		if _, exists, _ := informer.GetIndexer().GetByKey(key.Namespace + "/" + key.Name); exists {
+			return true
+		}
+	}
+	return false
+}
+
+// closeSource closes a source that holds a connection
+func closeSource(key registry.ResourceKey, source registry.Source) {
+	if closer, ok := source.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warnf("error closing catalog source %s in namespace %s: %s", key.Name, key.Namespace, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/clientset/versioned/fake"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/informers/externalversions"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
+)
+
+func TestCatalogSnapshotWithSource(t *testing.T) {
+	now := metav1.NewTime(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC))
+	key := registry.ResourceKey{Name: "catalog", Namespace: "ns"}
+	original := newCatalogSnapshot()
+	loaded := registry.NewInMem()
+
+	updated := original.withSource(key, loaded, 5, now)
+	require.Empty(t, original.sources, "snapshot was modified")
+	require.Empty(t, original.priorities, "snapshot was modified")
+	require.True(t, loaded == updated.sources[key])
+	require.Equal(t, 5, updated.priorities[key])
+	require.Equal(t, now, updated.lastUpdate)
+
+	reprioritized := updated.withSource(key, nil, 10, metav1.Now())
+	require.Equal(t, 5, updated.priorities[key], "snapshot was modified")
+	require.True(t, loaded == reprioritized.sources[key])
+	require.Equal(t, 10, reprioritized.priorities[key])
+	require.Equal(t, now, reprioritized.lastUpdate, "priority change counted as catalog update")
+}
+
+func TestCatalogSnapshotWithoutSource(t *testing.T) {
+	loadedAt := metav1.NewTime(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC))
+	removedAt := metav1.NewTime(loadedAt.Add(time.Minute))
+	removed := registry.ResourceKey{Name: "removed", Namespace: "ns"}
+	kept := registry.ResourceKey{Name: "kept", Namespace: "ns"}
+	original := newCatalogSnapshot().withSource(removed, registry.NewInMem(), 5, loadedAt).withSource(kept, registry.NewInMem(), 1, loadedAt)
+
+	updated := original.withoutSource(removed, removedAt)
+	require.Contains(t, original.sources, removed, "snapshot was modified")
+	require.Contains(t, original.priorities, removed, "snapshot was modified")
+	require.NotContains(t, updated.sources, removed)
+	require.NotContains(t, updated.priorities, removed)
+	require.True(t, original.sources[kept] == updated.sources[kept])
+	require.Equal(t, 1, updated.priorities[kept])
+	require.Equal(t, removedAt, updated.lastUpdate)
+}
+
+// BenchmarkSyncSubscription syncs thousands of subscriptions in parallel while catalogs are reloaded.
+func BenchmarkSyncSubscription(b *testing.B) {
+	const subscriptionCount = 5000
+	namespace := "ns"
+
+	installed := v1alpha1.ClusterServiceVersion{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       v1alpha1.ClusterServiceVersionKind,
+			APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbows.v1", Namespace: namespace},
+		Status:     v1alpha1.ClusterServiceVersionStatus{Phase: v1alpha1.CSVPhaseSucceeded},
+	}
+	head := installed
+	head.ObjectMeta = metav1.ObjectMeta{Name: "rainbows.v2", Namespace: namespace}
+	head.Spec.Replaces = installed.GetName()
+	catalog := registry.NewInMem()
+	catalog.AddOrReplaceService(installed)
+	catalog.AddOrReplaceService(head)
+	require.NoError(b, catalog.AddPackageManifest(registry.PackageManifest{
+		PackageName: "rainbows",
+		Channels:    []registry.PackageChannel{{Name: "magical", CurrentCSVName: head.GetName()}},
+	}))
+
+	objs := []runtime.Object{installed.DeepCopy()}
+	subs := make([]*v1alpha1.Subscription, subscriptionCount)
+	for i := range subs {
+		subs[i] = &v1alpha1.Subscription{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("sub-%d", i), Namespace: namespace},
+			Spec: &v1alpha1.SubscriptionSpec{
+				CatalogSource: "catalog",
+				Package:       "rainbows",
+				Channel:       "magical",
+			},
+			Status: v1alpha1.SubscriptionStatus{
+				CurrentCSV:   "rainbows.v1",
+				InstalledCSV: "rainbows.v1",
+				State:        v1alpha1.SubscriptionStateAtLatest,
+			},
+		}
+		objs = append(objs, subs[i])
+	}
+
+	// Catalogs are only published while their catalog source is in an informer's store
+	catsrc := &v1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: namespace}}
+	objs = append(objs, catsrc)
+	client := fake.NewSimpleClientset(objs...)
+	catsrcInformer := externalversions.NewSharedInformerFactory(client, 0).Operators().V1alpha1().CatalogSources().Informer()
+	require.NoError(b, catsrcInformer.GetIndexer().Add(catsrc))
+
+	op := &Operator{
+		client:             client,
+		namespace:          namespace,
+		sources:            newCatalogSnapshot(),
+		dependencyResolver: &resolver.ConstraintResolver{},
+		catsrcInformers:    []cache.SharedIndexInformer{catsrcInformer},
+	}
+	key := registry.ResourceKey{Name: "catalog", Namespace: namespace}
+	op.updateCatalog(key, catalog, 0)
+	require.Contains(b, op.catalogs().sources, key)
+
+	// Reload the catalog for as long as the benchmark runs
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				op.updateCatalog(key, catalog, 0)
+			}
+		}
+	}()
+
+	var next uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			// Each subscription resolves the upgrade to the head of the channel from the snapshot
+			sub := subs[atomic.AddUint64(&next, 1)%subscriptionCount]
+			out, err := op.syncSubscription(sub)
+			if err != nil {
+				b.Fatalf("error syncing subscription %s: %v", sub.GetName(), err)
+			}
+			if out.Status.GetCondition(v1alpha1.SubscriptionCatalogSourcesUnhealthy).Status == corev1.ConditionTrue ||
+				out.Status.GetCondition(v1alpha1.SubscriptionResolutionFailed).Status == corev1.ConditionTrue {
+				b.Fatalf("subscription %s not resolved: %#v", sub.GetName(), out.Status.Conditions)
+			}
+			if out.Status.CurrentCSV != head.GetName() || out.Status.State != v1alpha1.SubscriptionStateUpgradeAvailable {
+				b.Fatalf("subscription %s resolved to %s in state %s", sub.GetName(), out.Status.CurrentCSV, out.Status.State)
+			}
+		}
+	})
+}
@@ -8,7 +8,10 @@ import (
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
 
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/semverrange"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,41 +33,106 @@ func (o *Operator) syncSubscription(in *v1alpha1.Subscription) (*v1alpha1.Subscr
 	out := in.DeepCopy()
 	out = ensureLabels(out)
 
-	// Only sync if catalog has been updated since last sync time
-	if o.sourcesLastUpdate.Before(&out.Status.LastUpdated) && out.Status.State == v1alpha1.SubscriptionStateAtLatest {
-		log.Infof("skipping sync: no new updates to catalog since last sync at %s",
-			out.Status.LastUpdated.String())
-		return nil, nil
-	}
+	// The channel was switched if the current CSV was resolved from a different channel than the spec's
+	channelSwitched := out.Status.CurrentCSV != "" && out.Status.CurrentChannel != "" && out.Status.CurrentChannel != out.Spec.Channel
 
-	o.sourcesLock.Lock()
-	defer o.sourcesLock.Unlock()
+	// Changes to the spec, such as to the version range or upgrade schedule, are resolved even if the catalog isn't
+	specChanged := out.Status.ObservedGeneration != out.GetGeneration()
+	out.Status.ObservedGeneration = out.GetGeneration()
 
-	catalogNamespace := out.Spec.CatalogSourceNamespace
-	if catalogNamespace == "" {
-		catalogNamespace = o.namespace
+	// Resolve against the catalogs as they are now; reloads during the sync swap in a new snapshot
+	catalogs := o.catalogs()
+
+	// Only sync if catalog has been updated since last sync time
+	if !channelSwitched && !specChanged && catalogs.lastUpdate.Before(&out.Status.LastUpdated) && out.Status.State == v1alpha1.SubscriptionStateAtLatest {
+		if synced, err := o.syncSubscriptionAtLatest(catalogs, out); synced {
+			return out, err
+		}
 	}
-	catalog, ok := o.sources[registry.ResourceKey{Name: out.Spec.CatalogSource, Namespace: catalogNamespace}]
-	if !ok {
+
+	catalog, catalogKey, err := o.subscriptionCatalog(catalogs, out)
+	if err != nil {
 		out.Status.State = v1alpha1.SubscriptionStateAtLatest
 		out.Status.Reason = v1alpha1.SubscriptionReasonInvalidCatalog
-		return out, fmt.Errorf("unknown catalog source %s in namespace %s", out.Spec.CatalogSource, catalogNamespace)
+		out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionCatalogSourcesUnhealthy, v1alpha1.SubscriptionReasonInvalidCatalog, err.Error()))
+		return out, err
+	}
+	out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy))
+
+	var versionRange *semverrange.Range
+	if out.Spec.VersionRange != "" {
+		r, err := semverrange.Parse(out.Spec.VersionRange)
+		if err != nil {
+			err = fmt.Errorf("invalid version range for subscription %s: %v", out.GetName(), err)
+			out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonInvalidVersionRange, err.Error()))
+			return out, err
+		}
+		versionRange = r
+	}
+
+	windowOpen, err := checkMaintenanceWindow(out, timeNow().Time)
+	if err != nil {
+		return out, err
+	}
+
+	if channelSwitched {
+		log.Infof("subscription %s switched from channel %s to %s", out.GetName(), out.Status.CurrentChannel, out.Spec.Channel)
+
+		// Abandon any pending upgrade in the old channel and resolve again from the installed CSV
+		if out.Status.CurrentCSV != out.Status.InstalledCSV {
+			if err := o.deleteSupersededInstallPlan(out); err != nil {
+				return out, err
+			}
+			out.Status.CurrentCSV = out.Status.InstalledCSV
+			out.Status.Install = nil
+		}
 	}
 
 	// Find latest CSV if no CSVs are installed already
 	if out.Status.CurrentCSV == "" {
 		if out.Spec.StartingCSV != "" {
+			if versionRange != nil {
+				csv, err := catalog.FindCSVByName(out.Spec.StartingCSV)
+				if err == nil && csv == nil {
+					err = errors.New("nil CSV")
+				}
+				if err != nil {
+					err = fmt.Errorf("failed to find starting CSV %s: %v", out.Spec.StartingCSV, err)
+					out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonCSVNotFound, err.Error()))
+					return out, err
+				}
+				if !versionRange.Contains(csv.Spec.Version) {
+					err = fmt.Errorf("starting CSV %s version %s is outside of range %s", csv.GetName(), csv.Spec.Version.String(), versionRange)
+					out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonOutsideVersionRange, err.Error()))
+					return out, err
+				}
+			}
 			out.Status.CurrentCSV = out.Spec.StartingCSV
 		} else {
 			csv, err := catalog.FindCSVForPackageNameUnderChannel(out.Spec.Package, out.Spec.Channel)
+			if err == nil && csv == nil {
+				err = errors.New("nil CSV")
+			}
 			if err != nil {
-				return out, fmt.Errorf("failed to find CSV for package %s in channel %s: %v", out.Spec.Package, out.Spec.Channel, err)
+				err = fmt.Errorf("failed to find CSV for package %s in channel %s: %v", out.Spec.Package, out.Spec.Channel, err)
+				out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonCSVNotFound, err.Error()))
+				return out, err
 			}
-			if csv == nil {
-				return out, fmt.Errorf("failed to find CSV for package %s in channel %s: nil CSV", out.Spec.Package, out.Spec.Channel)
+			setHeldBack(out, nil)
+			if versionRange != nil && !versionRange.Contains(csv.Spec.Version) {
+				// Walk back from the head of the channel to the newest CSV within the range
+				setHeldBack(out, csv)
+				csv, err = findLatestInRange(catalog, out.Spec.Package, csv, versionRange)
+				if err != nil {
+					err = fmt.Errorf("failed to find CSV for package %s in channel %s: %v", out.Spec.Package, out.Spec.Channel, err)
+					out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonCSVNotFound, err.Error()))
+					return out, err
+				}
 			}
 			out.Status.CurrentCSV = csv.GetName()
 		}
+		out.Status.CurrentChannel = out.Spec.Channel
+		out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed))
 		out.Status.State = v1alpha1.SubscriptionStateUpgradeAvailable
 		return out, nil
 	}
@@ -80,6 +148,12 @@ func (o *Operator) syncSubscription(in *v1alpha1.Subscription) (*v1alpha1.Subscr
 			}
 			if err == nil && ip != nil {
 				log.Infof("installplan for %s already exists", out.Status.CurrentCSV)
+				if requiresScheduledApproval(out) {
+					if err := o.holdForMaintenanceWindow(out, ip, windowOpen); err != nil {
+						return out, err
+					}
+				}
+				setInstallPlanConditions(out, ip)
 				return out, nil
 			}
 			log.Infof("installplan %s not found: creating new plan", out.Status.Install.Name)
@@ -95,21 +169,36 @@ func (o *Operator) syncSubscription(in *v1alpha1.Subscription) (*v1alpha1.Subscr
 				Approval:                   out.GetInstallPlanApproval(),
 			},
 		}
+		if ip.Spec.Approval == v1alpha1.ApprovalManual {
+			ip.Spec.ApprovalPolicy = out.Spec.ApprovalPolicy.DeepCopy()
+		}
+		if requiresScheduledApproval(out) {
+			// Hold the plan for approval until a maintenance window opens
+			ip.Spec.Approval = v1alpha1.ApprovalManual
+			ip.Spec.Approved = windowOpen
+			if !windowOpen {
+				o.requeueSubscriptionAt(out, out.Status.NextMaintenanceWindow)
+			}
+		}
 		ownerutil.AddNonBlockingOwner(ip, out)
 		ip.SetGenerateName(fmt.Sprintf("install-%s-", out.Status.CurrentCSV))
 		ip.SetNamespace(out.GetNamespace())
 
 		// Inherit the subscription's catalog source
 		ip.Spec.CatalogSource = out.Spec.CatalogSource
-		ip.Spec.CatalogSourceNamespace = out.Spec.CatalogSourceNamespace
+		ip.Spec.CatalogSourceNamespace = catalogKey.Namespace
 
 		res, err := o.client.OperatorsV1alpha1().InstallPlans(out.GetNamespace()).Create(ip)
-		if err != nil {
-			return out, fmt.Errorf("failed to ensure current CSV %s installed: %v", out.Status.CurrentCSV, err)
+		if err == nil && res == nil {
+			err = errors.New("unexpected installplan returned by k8s api on create: <nil>")
+		} else if err != nil {
+			err = fmt.Errorf("failed to ensure current CSV %s installed: %v", out.Status.CurrentCSV, err)
 		}
-		if res == nil {
-			return out, errors.New("unexpected installplan returned by k8s api on create: <nil>")
+		if err != nil {
+			out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanFailed, v1alpha1.SubscriptionReasonInstallPlanNotCreated, err.Error()))
+			return out, err
 		}
+		setInstallPlanConditions(out, res)
 		out.Status.Install = &v1alpha1.InstallPlanReference{
 			UID:        res.GetUID(),
 			Name:       res.GetName(),
@@ -121,25 +210,295 @@ func (o *Operator) syncSubscription(in *v1alpha1.Subscription) (*v1alpha1.Subscr
 
 	// Set the installed CSV
 	out.Status.InstalledCSV = out.Status.CurrentCSV
+	out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending))
+	out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed))
+	setInstalledCSVCondition(out, csv)
+
+	// Propagate the subscription config to the installed CSV
+	if err := o.ensureSubscriptionConfig(out, csv); err != nil {
+		return out, err
+	}
 
 	// Poll catalog for an update
 	repl, err := catalog.FindReplacementCSVForPackageNameUnderChannel(out.Spec.Package, out.Spec.Channel, out.Status.CurrentCSV)
+	if err != nil {
+		err = fmt.Errorf("failed to lookup replacement CSV for %s: %v", out.Status.CurrentCSV, err)
+	} else if repl == nil {
+		err = fmt.Errorf("nil replacement CSV for %s returned from catalog", out.Status.CurrentCSV)
+	}
 	if err != nil {
 		out.Status.State = v1alpha1.SubscriptionStateAtLatest
-		return out, fmt.Errorf("failed to lookup replacement CSV for %s: %v", out.Status.CurrentCSV, err)
+		switch {
+		case isChannelHead(catalog, out):
+			out.Status.CurrentChannel = out.Spec.Channel
+			out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed))
+		case channelSwitched:
+			// Keep tracking the old channel until the new channel has a path from the installed CSV
+			err = fmt.Errorf("no upgrade path from %s in channel %s to channel %s: %v", out.Status.CurrentCSV, out.Status.CurrentChannel, out.Spec.Channel, err)
+			out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonNoUpgradePath, err.Error()))
+		default:
+			out.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonReplacementNotFound, err.Error()))
+		}
+		return out, err
 	}
-	if repl == nil {
+	out.Status.CurrentChannel = out.Spec.Channel
+	out.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed))
+
+	// Hold back replacements outside of the version range
+	if versionRange != nil && !versionRange.Contains(repl.Spec.Version) {
+		log.Infof("holding back upgrade to %s: version %s is outside of range %s", repl.GetName(), repl.Spec.Version.String(), versionRange)
+		setHeldBack(out, repl)
 		out.Status.State = v1alpha1.SubscriptionStateAtLatest
-		return out, fmt.Errorf("nil replacement CSV for %s returned from catalog", out.Status.CurrentCSV)
+		out.Status.Reason = v1alpha1.SubscriptionReasonOutsideVersionRange
+		return out, nil
 	}
 
 	// Update subscription with new latest
+	setHeldBack(out, nil)
 	out.Status.CurrentCSV = repl.GetName()
 	out.Status.Install = nil
 	out.Status.State = v1alpha1.SubscriptionStateUpgradeAvailable
 	return out, nil
 }
 
+// syncSubscriptionAtLatest refreshes the status of a subscription that is at the latest CSV and hasn't changed since
+// the catalog was last updated, without resolving it again. It returns false if the subscription has to be resolved.
+func (o *Operator) syncSubscriptionAtLatest(catalogs *catalogSnapshot, sub *v1alpha1.Subscription) (bool, error) {
+	// An unavailable catalog is reported by resolution
+	if _, _, err := o.subscriptionCatalog(catalogs, sub); err != nil {
+		return false, nil
+	}
+	log.Infof("skipping sync: no new updates to catalog since last sync at %s", sub.Status.LastUpdated.String())
+	sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy))
+
+	// Subscriptions that reached the latest CSV before the channel was tracked are in the spec's channel, since the
+	// spec hasn't changed since
+	if sub.Status.CurrentChannel == "" && sub.Status.CurrentCSV != "" {
+		sub.Status.CurrentChannel = sub.Spec.Channel
+	}
+
+	// Keep the next maintenance window current as windows pass
+	if _, err := checkMaintenanceWindow(sub, timeNow().Time); err != nil {
+		return true, err
+	}
+
+	if sub.Status.InstalledCSV == "" {
+		return true, nil
+	}
+
+	// Config changes still need to reach the installed CSV
+	csv, err := o.client.OperatorsV1alpha1().ClusterServiceVersions(sub.GetNamespace()).Get(sub.Status.InstalledCSV, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		// Resolve again rather than failing every sync until the catalog changes
+		log.Infof("installed CSV %s not found", sub.Status.InstalledCSV)
+		return false, nil
+	case err != nil:
+		return true, fmt.Errorf("error fetching installed CSV %s: %v", sub.Status.InstalledCSV, err)
+	}
+	sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending))
+	sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed))
+	setInstalledCSVCondition(sub, csv)
+	return true, o.ensureSubscriptionConfig(sub, csv)
+}
+
+// deleteSupersededInstallPlan deletes the subscription's InstallPlan for a pending upgrade that was abandoned, so that
+// it can't be approved and install a CSV from the old channel. Plans that already started installing are kept.
+func (o *Operator) deleteSupersededInstallPlan(sub *v1alpha1.Subscription) error {
+	if sub.Status.Install == nil || sub.Status.Install.Name == "" {
+		return nil
+	}
+
+	ip, err := o.client.OperatorsV1alpha1().InstallPlans(sub.GetNamespace()).Get(sub.Status.Install.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching superseded installplan %s: %v", sub.Status.Install.Name, err)
+	}
+	switch ip.Status.Phase {
+	case v1alpha1.InstallPlanPhaseNone, v1alpha1.InstallPlanPhasePlanning, v1alpha1.InstallPlanPhaseRequiresApproval:
+	default:
+		return nil
+	}
+
+	log.Infof("deleting installplan %s superseded by channel switch", ip.GetName())
+	err = o.client.OperatorsV1alpha1().InstallPlans(ip.GetNamespace()).Delete(ip.GetName(), &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting superseded installplan %s: %v", ip.GetName(), err)
+	}
+	return nil
+}
+
+// findLatestInRange follows the replacement graph of the package back from the given CSV and returns the newest CSV
+// with a version within the given range. CSVs are followed through `replaces`, `skips` and `skipRange`.
+func findLatestInRange(catalog registry.Source, packageName string, csv *v1alpha1.ClusterServiceVersion, versionRange *semverrange.Range) (*v1alpha1.ClusterServiceVersion, error) {
+	candidates := packageCSVs(catalog, packageName)
+
+	var latest *v1alpha1.ClusterServiceVersion
+	visited := map[string]struct{}{}
+	queue := []*v1alpha1.ClusterServiceVersion{csv}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[current.GetName()]; ok {
+			continue
+		}
+		visited[current.GetName()] = struct{}{}
+
+		if versionRange.Contains(current.Spec.Version) {
+			if latest == nil || latest.Spec.Version.LessThan(current.Spec.Version) {
+				latest = current
+			}
+			continue
+		}
+
+		for _, candidate := range candidates {
+			replaces, err := registry.ReplacesInOneHop(current, candidate.GetName(), &candidate.Spec.Version)
+			if err != nil {
+				return nil, err
+			}
+			if replaces {
+				queue = append(queue, candidate)
+			}
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no CSV with version in range %s", versionRange)
+	}
+	return latest, nil
+}
+
+// packageCSVs returns the CSVs in the catalog that are reachable from the heads of the package's channels through
+// `replaces` and `skips`
+func packageCSVs(catalog registry.Source, packageName string) []*v1alpha1.ClusterServiceVersion {
+	csvs := []*v1alpha1.ClusterServiceVersion{}
+	seen := map[string]struct{}{}
+	queue := []string{}
+	for _, channel := range catalog.AllPackages()[packageName].Channels {
+		queue = append(queue, channel.CurrentCSVName)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		// Skipped CSVs may have been pruned from the catalog
+		csv, err := catalog.FindCSVByName(name)
+		if err != nil || csv == nil {
+			continue
+		}
+		csvs = append(csvs, csv)
+		queue = append(queue, csv.Spec.Replaces)
+		queue = append(queue, csv.Spec.Skips...)
+	}
+	return csvs
+}
+
+// isChannelHead reports whether the subscription's current CSV is the latest CSV in its channel
+func isChannelHead(catalog registry.Source, sub *v1alpha1.Subscription) bool {
+	head, err := catalog.FindCSVForPackageNameUnderChannel(sub.Spec.Package, sub.Spec.Channel)
+	return err == nil && head != nil && head.GetName() == sub.Status.CurrentCSV
+}
+
+// subscriptionCatalog returns the catalog source a subscription resolves against. Without an explicit source
+// namespace, a catalog source in the subscription's namespace takes precedence over a global one. Catalog sources
+// outside of the operator namespace are only available to subscriptions in the same namespace.
+func (o *Operator) subscriptionCatalog(catalogs *catalogSnapshot, sub *v1alpha1.Subscription) (registry.Source, registry.ResourceKey, error) {
+	namespaces := []string{sub.GetNamespace(), o.namespace}
+	if sub.Spec.CatalogSourceNamespace != "" {
+		namespaces = []string{sub.Spec.CatalogSourceNamespace}
+	}
+
+	for _, namespace := range namespaces {
+		key := registry.ResourceKey{Name: sub.Spec.CatalogSource, Namespace: namespace}
+		if namespace != o.namespace && namespace != sub.GetNamespace() {
+			return nil, key, fmt.Errorf("catalog source %s in namespace %s is not available to subscriptions in namespace %s", key.Name, key.Namespace, sub.GetNamespace())
+		}
+		if catalog, ok := catalogs.sources[key]; ok {
+			return catalog, key, nil
+		}
+	}
+	return nil, registry.ResourceKey{}, fmt.Errorf("unknown catalog source %s in namespace %s", sub.Spec.CatalogSource, namespaces[len(namespaces)-1])
+}
+
+// setInstalledCSVCondition mirrors the phase of the subscription's installed CSV into its conditions
+func setInstalledCSVCondition(sub *v1alpha1.Subscription, csv *v1alpha1.ClusterServiceVersion) {
+	switch csv.Status.Phase {
+	case v1alpha1.CSVPhaseSucceeded:
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstalledCSVUnhealthy))
+	case v1alpha1.CSVPhaseNone:
+		sub.Status.SetCondition(v1alpha1.SubscriptionCondition{
+			Type:   v1alpha1.SubscriptionInstalledCSVUnhealthy,
+			Status: corev1.ConditionUnknown,
+		})
+	default:
+		message := fmt.Sprintf("installed CSV %s is %s", csv.GetName(), csv.Status.Phase)
+		if csv.Status.Message != "" {
+			message = fmt.Sprintf("%s: %s", message, csv.Status.Message)
+		}
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstalledCSVUnhealthy, csv.Status.Reason, message))
+	}
+}
+
+// setInstallPlanConditions mirrors the phase of the subscription's InstallPlan into its conditions
+func setInstallPlanConditions(sub *v1alpha1.Subscription, ip *v1alpha1.InstallPlan) {
+	switch ip.Status.Phase {
+	case v1alpha1.InstallPlanPhaseFailed:
+		message := fmt.Sprintf("installplan %s failed", ip.GetName())
+		reason := v1alpha1.ConditionReason(v1alpha1.InstallPlanPhaseFailed)
+		for _, cond := range ip.Status.Conditions {
+			if cond.Status == corev1.ConditionFalse {
+				message = fmt.Sprintf("%s: %s", message, cond.Message)
+				reason = v1alpha1.ConditionReason(cond.Reason)
+				break
+			}
+		}
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending))
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanFailed, reason, message))
+	default:
+		message := fmt.Sprintf("waiting for installplan %s to install %s", ip.GetName(), sub.Status.CurrentCSV)
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, v1alpha1.ConditionReason(ip.Status.Phase), message))
+		sub.Status.SetCondition(v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed))
+	}
+}
+
+// setHeldBack records the given CSV as held back by the subscription's version range, or clears it if nil
+func setHeldBack(sub *v1alpha1.Subscription, csv *v1alpha1.ClusterServiceVersion) {
+	if csv == nil {
+		sub.Status.HeldBackCSV = ""
+		sub.Status.HeldBackVersion = ""
+		if sub.Status.Reason == v1alpha1.SubscriptionReasonOutsideVersionRange {
+			sub.Status.Reason = ""
+		}
+		return
+	}
+	sub.Status.HeldBackCSV = csv.GetName()
+	sub.Status.HeldBackVersion = csv.Spec.Version.String()
+}
+
+// ensureSubscriptionConfig records the subscription's config on the installed CSV so that the OLM operator applies it
+// to the CSV's deployments.
+func (o *Operator) ensureSubscriptionConfig(sub *v1alpha1.Subscription, csv *v1alpha1.ClusterServiceVersion) error {
+	updated := csv.DeepCopy()
+	changed, err := updated.SetSubscriptionConfig(sub.Spec.Config)
+	if err != nil {
+		return fmt.Errorf("failed to set config on CSV %s: %v", csv.GetName(), err)
+	}
+	if !changed {
+		return nil
+	}
+
+	if _, err := o.client.OperatorsV1alpha1().ClusterServiceVersions(csv.GetNamespace()).Update(updated); err != nil {
+		return fmt.Errorf("failed to update config on CSV %s: %v", csv.GetName(), err)
+	}
+	return nil
+}
+
 func ensureLabels(sub *v1alpha1.Subscription) *v1alpha1.Subscription {
 	labels := sub.GetLabels()
 	if labels == nil {
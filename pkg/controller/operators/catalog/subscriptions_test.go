@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -19,6 +21,7 @@ import (
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/fakes"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/semverrange"
 	"k8s.io/apimachinery/pkg/util/diff"
 )
 
@@ -47,6 +50,8 @@ func TestSyncSubscription(t *testing.T) {
 		nowTime      = metav1.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC)
 		earlierTime  = metav1.Date(2018, time.January, 19, 20, 20, 0, 0, time.UTC)
 		earliestTime = metav1.Date(2017, time.December, 10, 12, 00, 0, 0, time.UTC)
+
+		nextWindowTime = metav1.Date(2018, time.January, 27, 2, 0, 0, 0, time.UTC)
 	)
 	timeNow = func() metav1.Time { return nowTime }
 
@@ -59,6 +64,9 @@ func TestSyncSubscription(t *testing.T) {
 		findReplacementCSVResult *v1alpha1.ClusterServiceVersion
 		findReplacementCSVError  error
 
+		findCSVByNameResult *v1alpha1.ClusterServiceVersion
+		allPackagesResult   map[string]registry.PackageManifest
+
 		getInstallPlanResult *v1alpha1.InstallPlan
 		getInstallPlanError  error
 
@@ -190,6 +198,91 @@ func TestSyncSubscription(t *testing.T) {
 						CurrentCSV:  "latest-and-greatest",
 						LastUpdated: earliestTime,
 						State:       v1alpha1.SubscriptionStateUpgradePending,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "", "waiting for installplan existing-install to install latest-and-greatest"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+						},
+						Install: &v1alpha1.InstallPlanReference{
+							Kind:       v1alpha1.InstallPlanKind,
+							APIVersion: v1alpha1.SchemeGroupVersion.String(),
+							Name:       "existing-install",
+						},
+					},
+				},
+				err: "",
+			},
+		},
+		{
+			name:    "no csv",
+			subName: "installplan for current CSV failed",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				findLatestCSVResult: &v1alpha1.ClusterServiceVersion{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "latest-and-greatest",
+						Namespace: "fairy-land",
+					},
+				},
+				getInstallPlanResult: &v1alpha1.InstallPlan{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.InstallPlanKind,
+						APIVersion: v1alpha1.InstallPlanAPIVersion,
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "existing-install",
+						Namespace: "fairy-land",
+					},
+					Status: v1alpha1.InstallPlanStatus{
+						Phase: v1alpha1.InstallPlanPhaseFailed,
+						Conditions: []v1alpha1.InstallPlanCondition{
+							v1alpha1.ConditionFailed(v1alpha1.InstallPlanInstalled, v1alpha1.InstallPlanReasonComponentFailed, errors.New("missing CRD")),
+						},
+					},
+				},
+				sourcesLastUpdate: earliestTime,
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV:  "latest-and-greatest",
+					LastUpdated: earliestTime,
+					State:       v1alpha1.SubscriptionStateUpgradePending,
+					Install: &v1alpha1.InstallPlanReference{
+						Kind:       v1alpha1.InstallPlanKind,
+						APIVersion: v1alpha1.SchemeGroupVersion.String(),
+						Name:       "existing-install",
+					},
+				},
+			}},
+			expected: expected{
+				csvName: "latest-and-greatest",
+				subscription: &v1alpha1.Subscription{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"},
+					},
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV:  "latest-and-greatest",
+						LastUpdated: earliestTime,
+						State:       v1alpha1.SubscriptionStateUpgradePending,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanFailed, v1alpha1.ConditionReason(v1alpha1.InstallPlanReasonComponentFailed), "installplan existing-install failed: missing CRD"),
+						},
 						Install: &v1alpha1.InstallPlanReference{
 							Kind:       v1alpha1.InstallPlanKind,
 							APIVersion: v1alpha1.SchemeGroupVersion.String(),
@@ -279,10 +372,15 @@ func TestSyncSubscription(t *testing.T) {
 						Channel:       "magical",
 					},
 					Status: v1alpha1.SubscriptionStatus{
-						CurrentCSV:  "latest-and-greatest",
-						LastUpdated: earliestTime,
-						Install:     nil,
-						State:       v1alpha1.SubscriptionStateUpgradeAvailable,
+						CurrentCSV:     "latest-and-greatest",
+						CurrentChannel: "magical",
+						LastUpdated:    earliestTime,
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
 					},
 				},
 				err: "",
@@ -326,10 +424,15 @@ func TestSyncSubscription(t *testing.T) {
 						StartingCSV:   "wayback",
 					},
 					Status: v1alpha1.SubscriptionStatus{
-						CurrentCSV:  "wayback",
-						LastUpdated: earliestTime,
-						Install:     nil,
-						State:       v1alpha1.SubscriptionStateUpgradeAvailable,
+						CurrentCSV:     "wayback",
+						CurrentChannel: "magical",
+						LastUpdated:    earliestTime,
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
 					},
 				},
 				err: "",
@@ -429,7 +532,7 @@ func TestSyncSubscription(t *testing.T) {
 					},
 					Spec: v1alpha1.InstallPlanSpec{
 						CatalogSource:              "flying-unicorns",
-						CatalogSourceNamespace:     "",
+						CatalogSourceNamespace:     "ns",
 						ClusterServiceVersionNames: []string{"latest-and-greatest"},
 						Approval:                   v1alpha1.ApprovalAutomatic,
 					},
@@ -455,6 +558,11 @@ func TestSyncSubscription(t *testing.T) {
 							Name:       "installplan-1",
 						},
 						State: v1alpha1.SubscriptionStateUpgradePending,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "", "waiting for installplan installplan-1 to install latest-and-greatest"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+						},
 					},
 				},
 				err: "",
@@ -508,7 +616,7 @@ func TestSyncSubscription(t *testing.T) {
 					},
 					Spec: v1alpha1.InstallPlanSpec{
 						CatalogSource:              "flying-unicorns",
-						CatalogSourceNamespace:     "",
+						CatalogSourceNamespace:     "ns",
 						ClusterServiceVersionNames: []string{"latest-and-greatest"},
 						Approval:                   v1alpha1.ApprovalAutomatic,
 					},
@@ -534,6 +642,109 @@ func TestSyncSubscription(t *testing.T) {
 							Name:       "installplan-1",
 						},
 						State: v1alpha1.SubscriptionStateUpgradePending,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "", "waiting for installplan installplan-1 to install latest-and-greatest"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+						},
+					},
+				},
+				csvName:   "latest-and-greatest",
+				namespace: "fairy-land",
+				err:       "",
+			},
+		},
+		{
+			name:    "no csv or installplan",
+			subName: "holds installplan for approval until maintenance window",
+			initial: initial{
+				catalogName:  "flying-unicorns",
+				getCSVResult: nil,
+				createInstallPlanResult: &v1alpha1.InstallPlan{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "installplan-1",
+						UID:  types.UID("UID-OK"),
+					},
+				},
+				createInstallPlanError: nil,
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "fairy-land",
+					Name:      "test-subscription",
+					UID:       types.UID("subscription-uid"),
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+					UpgradeSchedule: &v1alpha1.UpgradeSchedule{
+						Windows: []v1alpha1.MaintenanceWindow{
+							{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+						},
+					},
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV: "latest-and-greatest",
+					Install:    nil,
+				},
+			}},
+			expected: expected{
+				installPlan: &v1alpha1.InstallPlan{
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "install-latest-and-greatest-",
+						Namespace:    "fairy-land",
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								APIVersion:         "operators.coreos.com/v1alpha1",
+								Kind:               "Subscription",
+								Name:               "test-subscription",
+								UID:                types.UID("subscription-uid"),
+								BlockOwnerDeletion: &blockOwnerDeletion,
+								Controller:         &isController,
+							},
+						},
+					},
+					Spec: v1alpha1.InstallPlanSpec{
+						CatalogSource:              "flying-unicorns",
+						CatalogSourceNamespace:     "ns",
+						ClusterServiceVersionNames: []string{"latest-and-greatest"},
+						Approval:                   v1alpha1.ApprovalManual,
+						Approved:                   false,
+					},
+				},
+				subscription: &v1alpha1.Subscription{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:    map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"},
+						Namespace: "fairy-land",
+						Name:      "test-subscription",
+						UID:       types.UID("subscription-uid"),
+					},
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+						UpgradeSchedule: &v1alpha1.UpgradeSchedule{
+							Windows: []v1alpha1.MaintenanceWindow{
+								{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+							},
+						},
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV: "latest-and-greatest",
+						Install: &v1alpha1.InstallPlanReference{
+							Kind:       v1alpha1.InstallPlanKind,
+							APIVersion: v1alpha1.SchemeGroupVersion.String(),
+							UID:        types.UID("UID-OK"),
+							Name:       "installplan-1",
+						},
+						State:                 v1alpha1.SubscriptionStateUpgradePending,
+						NextMaintenanceWindow: &nextWindowTime,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "", "waiting for installplan installplan-1 to install latest-and-greatest"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+						},
 					},
 				},
 				csvName:   "latest-and-greatest",
@@ -590,7 +801,7 @@ func TestSyncSubscription(t *testing.T) {
 					},
 					Spec: v1alpha1.InstallPlanSpec{
 						CatalogSource:              "flying-unicorns",
-						CatalogSourceNamespace:     "",
+						CatalogSourceNamespace:     "ns",
 						ClusterServiceVersionNames: []string{"latest-and-greatest"},
 						Approval:                   v1alpha1.ApprovalManual,
 					},
@@ -617,6 +828,11 @@ func TestSyncSubscription(t *testing.T) {
 							Name:       "installplan-1",
 						},
 						State: v1alpha1.SubscriptionStateUpgradePending,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "", "waiting for installplan installplan-1 to install latest-and-greatest"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+						},
 					},
 				},
 				csvName:   "latest-and-greatest",
@@ -669,7 +885,7 @@ func TestSyncSubscription(t *testing.T) {
 					},
 					Spec: v1alpha1.InstallPlanSpec{
 						CatalogSource:              "flying-unicorns",
-						CatalogSourceNamespace:     "",
+						CatalogSourceNamespace:     "ns",
 						ClusterServiceVersionNames: []string{"pending"},
 						Approval:                   v1alpha1.ApprovalAutomatic,
 					},
@@ -772,6 +988,9 @@ func TestSyncSubscription(t *testing.T) {
 						Kind:       v1alpha1.ClusterServiceVersionKind,
 						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
 					},
+					Status: v1alpha1.ClusterServiceVersionStatus{
+						Phase: v1alpha1.CSVPhaseSucceeded,
+					},
 				},
 				findReplacementCSVResult: &v1alpha1.ClusterServiceVersion{
 					ObjectMeta: metav1.ObjectMeta{
@@ -813,104 +1032,485 @@ func TestSyncSubscription(t *testing.T) {
 						Channel:       "magical",
 					},
 					Status: v1alpha1.SubscriptionStatus{
-						CurrentCSV:   "next",
-						InstalledCSV: "toupgrade",
-						Install:      nil,
-						State:        v1alpha1.SubscriptionStateUpgradeAvailable,
+						CurrentCSV:     "next",
+						CurrentChannel: "magical",
+						InstalledCSV:   "toupgrade",
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstalledCSVUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
 					},
 				},
 			},
 		},
-	}
-	for _, tt := range table {
-		testName := fmt.Sprintf("%s: %s", tt.name, tt.subName)
-		t.Run(testName, func(t *testing.T) {
-			// configure cluster state
-			existingObjects := []runtime.Object{}
-			expectedActions := []core.Action{}
-
-			if tt.initial.getCSVResult != nil {
-				existingObjects = append(existingObjects, tt.initial.getCSVResult)
-			}
-			if tt.initial.getInstallPlanResult != nil {
-				existingObjects = append(existingObjects, tt.initial.getInstallPlanResult)
-			}
-			if tt.args.subscription != nil {
-				existingObjects = append(existingObjects, tt.args.subscription)
-			}
-
-			clientFake := fake.NewSimpleClientset(existingObjects...)
-
-			// configure expected actions
-			if tt.expected.csvName != "" {
-				expectedActions = append(expectedActions,
-					core.NewGetAction(
-						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "clusterserviceversions"},
-						tt.expected.namespace,
-						tt.expected.csvName,
-					),
-				)
-			}
-
-			if tt.initial.getInstallPlanError != nil {
-				expectedActions = append(expectedActions,
-					core.NewGetAction(
-						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
-						tt.args.subscription.GetNamespace(),
-						tt.args.subscription.Status.Install.Name,
-					),
-				)
-			}
-
-			if tt.expected.installPlan != nil {
-				expectedActions = append(expectedActions,
-					core.NewCreateAction(
-						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
-						tt.expected.namespace,
-						tt.expected.installPlan,
-					),
-				)
-			}
-
-			if tt.args.subscription != nil {
-				if tt.args.subscription.Status.Install != nil && tt.initial.getInstallPlanError == nil {
-					expectedActions = append(expectedActions,
-						core.NewGetAction(
-							schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
-							tt.args.subscription.GetNamespace(),
-							tt.args.subscription.Status.Install.Name,
-						),
-					)
-				}
-			}
-
-			// fake api calls
-			if tt.initial.getCSVError != nil {
-				clientFake.PrependReactor("get", "clusterserviceversions", func(action core.Action) (bool, runtime.Object, error) {
-					if action.(core.GetAction).GetName() != tt.expected.csvName {
-						return false, nil, nil
-					}
-					return true, nil, tt.initial.getCSVError
-				})
-
-			}
-
-			if tt.initial.getInstallPlanError != nil {
-				clientFake.PrependReactor("get", "installplans", func(action core.Action) (bool, runtime.Object, error) {
-					if action.(core.GetAction).GetName() != tt.expected.existingInstallPlanName {
-						return false, nil, nil
-					}
-					return true, nil, tt.initial.getInstallPlanError
-				})
-			}
-
-			if tt.initial.updateSubscriptionError != nil {
-				clientFake.PrependReactor("update", "subscriptions", func(action core.Action) (bool, runtime.Object, error) {
-					return true, nil, tt.initial.updateSubscriptionError
-				})
-			}
-
-			if tt.initial.createInstallPlanResult != nil {
+		{
+			name:    "csv installed",
+			subName: "reports failed installed csv",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				getCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "toupgrade",
+						Namespace: "fairy-land",
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+					Status: v1alpha1.ClusterServiceVersionStatus{
+						Phase:   v1alpha1.CSVPhaseFailed,
+						Reason:  v1alpha1.CSVReasonComponentFailed,
+						Message: "install strategy failed",
+					},
+				},
+				findReplacementCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "next",
+					},
+				},
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "fairy-land",
+					Name:      "test-subscription",
+					UID:       types.UID("subscription-uid"),
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV: "toupgrade",
+					Install:    nil,
+				},
+			}},
+			expected: expected{
+				csvName:     "toupgrade",
+				namespace:   "fairy-land",
+				packageName: "rainbows",
+				channelName: "magical",
+				subscription: &v1alpha1.Subscription{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "fairy-land",
+						Name:      "test-subscription",
+						UID:       types.UID("subscription-uid"),
+						Labels:    map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"},
+					},
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV:     "next",
+						CurrentChannel: "magical",
+						InstalledCSV:   "toupgrade",
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+							v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstalledCSVUnhealthy, v1alpha1.CSVReasonComponentFailed, "installed CSV toupgrade is Failed: install strategy failed"),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "clean install",
+			subName: "sets newest version within version range",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				findLatestCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "latest-and-greatest",
+					},
+					Spec: v1alpha1.ClusterServiceVersionSpec{
+						Version:  *semver.New("2.0.0"),
+						Replaces: "older-but-stable",
+					},
+				},
+				findCSVByNameResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "older-but-stable",
+					},
+					Spec: v1alpha1.ClusterServiceVersionSpec{
+						Version: *semver.New("1.2.0"),
+					},
+				},
+				allPackagesResult: map[string]registry.PackageManifest{
+					"rainbows": {
+						PackageName: "rainbows",
+						Channels:    []registry.PackageChannel{{Name: "magical", CurrentCSVName: "older-but-stable"}},
+					},
+				},
+				sourcesLastUpdate: earlierTime,
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+					VersionRange:  "<2.0.0",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					LastUpdated: earliestTime,
+				},
+			}},
+			expected: expected{
+				packageName: "rainbows",
+				channelName: "magical",
+				subscription: &v1alpha1.Subscription{
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+						VersionRange:  "<2.0.0",
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV:     "older-but-stable",
+						CurrentChannel: "magical",
+						LastUpdated:    earliestTime,
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
+						HeldBackCSV:     "latest-and-greatest",
+						HeldBackVersion: "2.0.0",
+					},
+				},
+			},
+		},
+		{
+			name:    "invalid input",
+			subName: "subscription has an invalid version range",
+			initial: initial{catalogName: "flying-unicorns"},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-subscription",
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					VersionRange:  ">=one",
+				},
+			}},
+			expected: expected{err: `invalid version range for subscription test-subscription: invalid range ">=one": one is not in dotted-tri format`},
+		},
+		{
+			name:    "clean install",
+			subName: "starting version outside of version range",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				findCSVByNameResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{Name: "wayback"},
+					Spec:       v1alpha1.ClusterServiceVersionSpec{Version: *semver.New("1.0.0")},
+				},
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-subscription",
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					StartingCSV:   "wayback",
+					VersionRange:  ">=2.0.0",
+				},
+			}},
+			expected: expected{err: "starting CSV wayback version 1.0.0 is outside of range >=2.0.0"},
+		},
+		{
+			name:    "csv installed",
+			subName: "holds back upgrade outside of version range",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				getCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "toupgrade",
+						Namespace: "fairy-land",
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+				},
+				findReplacementCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "next",
+					},
+					Spec: v1alpha1.ClusterServiceVersionSpec{
+						Version: *semver.New("2.0.0"),
+					},
+				},
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "fairy-land",
+					Name:      "test-subscription",
+					UID:       types.UID("subscription-uid"),
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+					VersionRange:  ">=1.0.0 <2.0.0",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV: "toupgrade",
+					Install:    nil,
+				},
+			}},
+			expected: expected{
+				csvName:     "toupgrade",
+				namespace:   "fairy-land",
+				packageName: "rainbows",
+				channelName: "magical",
+				subscription: &v1alpha1.Subscription{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "fairy-land",
+						Name:      "test-subscription",
+						UID:       types.UID("subscription-uid"),
+						Labels:    map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"},
+					},
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+						VersionRange:  ">=1.0.0 <2.0.0",
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV:     "toupgrade",
+						CurrentChannel: "magical",
+						InstalledCSV:   "toupgrade",
+						Install:        nil,
+						State:          v1alpha1.SubscriptionStateAtLatest,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+							{Type: v1alpha1.SubscriptionInstalledCSVUnhealthy, Status: corev1.ConditionUnknown},
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
+						Reason:          v1alpha1.SubscriptionReasonOutsideVersionRange,
+						HeldBackCSV:     "next",
+						HeldBackVersion: "2.0.0",
+					},
+				},
+			},
+		},
+		{
+			name:    "channel switched",
+			subName: "abandons pending upgrade and finds replacement in new channel",
+			initial: initial{
+				catalogName:       "flying-unicorns",
+				sourcesLastUpdate: earliestTime,
+				getCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "installed",
+						Namespace: "fairy-land",
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+				},
+				findReplacementCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "next-in-magical",
+					},
+				},
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "fairy-land",
+					Name:      "test-subscription",
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV:     "next-in-mundane",
+					CurrentChannel: "mundane",
+					InstalledCSV:   "installed",
+					LastUpdated:    earlierTime,
+					State:          v1alpha1.SubscriptionStateAtLatest,
+				},
+			}},
+			expected: expected{
+				csvName:     "installed",
+				namespace:   "fairy-land",
+				packageName: "rainbows",
+				channelName: "magical",
+				subscription: &v1alpha1.Subscription{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "fairy-land",
+						Name:      "test-subscription",
+						Labels:    map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"},
+					},
+					Spec: &v1alpha1.SubscriptionSpec{
+						CatalogSource: "flying-unicorns",
+						Package:       "rainbows",
+						Channel:       "magical",
+					},
+					Status: v1alpha1.SubscriptionStatus{
+						CurrentCSV:     "next-in-magical",
+						CurrentChannel: "magical",
+						InstalledCSV:   "installed",
+						LastUpdated:    earlierTime,
+						State:          v1alpha1.SubscriptionStateUpgradeAvailable,
+						Conditions: []v1alpha1.SubscriptionCondition{
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+							{Type: v1alpha1.SubscriptionInstalledCSVUnhealthy, Status: corev1.ConditionUnknown},
+							v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionResolutionFailed),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "channel switched",
+			subName: "no upgrade path to new channel",
+			initial: initial{
+				catalogName: "flying-unicorns",
+				getCSVResult: &v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "installed",
+						Namespace: "fairy-land",
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+				},
+				findReplacementCSVError: errors.New("CatalogError"),
+			},
+			args: args{subscription: &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "fairy-land",
+					Name:      "test-subscription",
+				},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource: "flying-unicorns",
+					Package:       "rainbows",
+					Channel:       "magical",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV:     "installed",
+					CurrentChannel: "mundane",
+					InstalledCSV:   "installed",
+				},
+			}},
+			expected: expected{
+				csvName:     "installed",
+				namespace:   "fairy-land",
+				packageName: "rainbows",
+				channelName: "magical",
+				err:         "no upgrade path from installed in channel mundane to channel magical: failed to lookup replacement CSV for installed: CatalogError",
+			},
+		},
+	}
+	for _, tt := range table {
+		testName := fmt.Sprintf("%s: %s", tt.name, tt.subName)
+		t.Run(testName, func(t *testing.T) {
+			// configure cluster state
+			existingObjects := []runtime.Object{}
+			expectedActions := []core.Action{}
+
+			if tt.initial.getCSVResult != nil {
+				existingObjects = append(existingObjects, tt.initial.getCSVResult)
+			}
+			if tt.initial.getInstallPlanResult != nil {
+				existingObjects = append(existingObjects, tt.initial.getInstallPlanResult)
+			}
+			if tt.args.subscription != nil {
+				existingObjects = append(existingObjects, tt.args.subscription)
+			}
+
+			clientFake := fake.NewSimpleClientset(existingObjects...)
+
+			// configure expected actions
+			if tt.expected.csvName != "" {
+				expectedActions = append(expectedActions,
+					core.NewGetAction(
+						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "clusterserviceversions"},
+						tt.expected.namespace,
+						tt.expected.csvName,
+					),
+				)
+			}
+
+			if tt.initial.getInstallPlanError != nil {
+				expectedActions = append(expectedActions,
+					core.NewGetAction(
+						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
+						tt.args.subscription.GetNamespace(),
+						tt.args.subscription.Status.Install.Name,
+					),
+				)
+			}
+
+			if tt.expected.installPlan != nil {
+				expectedActions = append(expectedActions,
+					core.NewCreateAction(
+						schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
+						tt.expected.namespace,
+						tt.expected.installPlan,
+					),
+				)
+			}
+
+			if tt.args.subscription != nil {
+				if tt.args.subscription.Status.Install != nil && tt.initial.getInstallPlanError == nil {
+					expectedActions = append(expectedActions,
+						core.NewGetAction(
+							schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "installplans"},
+							tt.args.subscription.GetNamespace(),
+							tt.args.subscription.Status.Install.Name,
+						),
+					)
+				}
+			}
+
+			// fake api calls
+			if tt.initial.getCSVError != nil {
+				clientFake.PrependReactor("get", "clusterserviceversions", func(action core.Action) (bool, runtime.Object, error) {
+					if action.(core.GetAction).GetName() != tt.expected.csvName {
+						return false, nil, nil
+					}
+					return true, nil, tt.initial.getCSVError
+				})
+
+			}
+
+			if tt.initial.getInstallPlanError != nil {
+				clientFake.PrependReactor("get", "installplans", func(action core.Action) (bool, runtime.Object, error) {
+					if action.(core.GetAction).GetName() != tt.expected.existingInstallPlanName {
+						return false, nil, nil
+					}
+					return true, nil, tt.initial.getInstallPlanError
+				})
+			}
+
+			if tt.initial.updateSubscriptionError != nil {
+				clientFake.PrependReactor("update", "subscriptions", func(action core.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.initial.updateSubscriptionError
+				})
+			}
+
+			if tt.initial.createInstallPlanResult != nil {
 				clientFake.PrependReactor("create", "installplans", func(action core.Action) (bool, runtime.Object, error) {
 					return true, tt.initial.createInstallPlanResult, nil
 				})
@@ -944,15 +1544,19 @@ func TestSyncSubscription(t *testing.T) {
 					catalogFake.FindReplacementCSVForPackageNameUnderChannelReturns(tt.initial.findReplacementCSVResult, tt.initial.findReplacementCSVError)
 				}
 			}
+			catalogFake.FindCSVByNameReturns(tt.initial.findCSVByNameResult, nil)
+			catalogFake.AllPackagesReturns(tt.initial.allPackagesResult)
 
 			op := &Operator{
 				client:    clientFake,
 				namespace: "ns",
-				sources: map[registry.ResourceKey]registry.Source{
-					registry.ResourceKey{Name: tt.initial.catalogName, Namespace: "ns"}: catalogFake,
+				sources: &catalogSnapshot{
+					sources: map[registry.ResourceKey]registry.Source{
+						registry.ResourceKey{Name: tt.initial.catalogName, Namespace: "ns"}: catalogFake,
+					},
+					lastUpdate: tt.initial.sourcesLastUpdate,
 				},
-				sourcesLastUpdate:  tt.initial.sourcesLastUpdate,
-				dependencyResolver: &resolver.MultiSourceResolver{},
+				dependencyResolver: &resolver.ConstraintResolver{},
 			}
 
 			// run subscription sync
@@ -971,6 +1575,10 @@ func TestSyncSubscription(t *testing.T) {
 				// If we fail to update the subscription these won't be set
 				if tt.initial.updateSubscriptionError == nil {
 					require.Equal(t, map[string]string{PackageLabel: "rainbows", CatalogLabel: "flying-unicorns", ChannelLabel: "magical"}, sub.GetLabels())
+					for i := range sub.Status.Conditions {
+						sub.Status.Conditions[i].LastUpdateTime = metav1.Time{}
+						sub.Status.Conditions[i].LastTransitionTime = metav1.Time{}
+					}
 					require.Equal(t, tt.expected.subscription.Status, sub.Status)
 				}
 			}
@@ -981,3 +1589,453 @@ func TestSyncSubscription(t *testing.T) {
 
 	}
 }
+
+func TestFindLatestInRange(t *testing.T) {
+	csv := func(name, version, replaces string, skips []string, skipRange string) v1alpha1.ClusterServiceVersion {
+		return v1alpha1.ClusterServiceVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.ClusterServiceVersionSpec{
+				Version:   *semver.New(version),
+				Replaces:  replaces,
+				Skips:     skips,
+				SkipRange: skipRange,
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		csvs         []v1alpha1.ClusterServiceVersion
+		channels     []registry.PackageChannel
+		head         string
+		versionRange string
+		expected     string
+		err          string
+	}{
+		{
+			name: "Replaces",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				csv("rainbows.v1", "1.0.0", "", nil, ""),
+				csv("rainbows.v1.1", "1.1.0", "rainbows.v1", nil, ""),
+				csv("rainbows.v2", "2.0.0", "rainbows.v1.1", nil, ""),
+			},
+			channels:     []registry.PackageChannel{{Name: "magical", CurrentCSVName: "rainbows.v2"}},
+			head:         "rainbows.v2",
+			versionRange: "<2.0.0",
+			expected:     "rainbows.v1.1",
+		},
+		{
+			name: "SkipsOnly",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				csv("rainbows.v1", "1.0.0", "", nil, ""),
+				csv("rainbows.v2", "2.0.0", "", []string{"rainbows.v1"}, ""),
+				csv("rainbows.v3", "3.0.0", "rainbows.v2", nil, ""),
+			},
+			channels:     []registry.PackageChannel{{Name: "magical", CurrentCSVName: "rainbows.v3"}},
+			head:         "rainbows.v3",
+			versionRange: "<2.0.0",
+			expected:     "rainbows.v1",
+		},
+		{
+			name: "SkipRangeOnly",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				csv("rainbows.v1", "1.0.0", "", nil, ""),
+				csv("rainbows.v1.1", "1.1.0", "rainbows.v1", nil, ""),
+				csv("rainbows.v2", "2.0.0", "", nil, ">=1.0.0 <2.0.0"),
+			},
+			channels: []registry.PackageChannel{
+				{Name: "magical", CurrentCSVName: "rainbows.v2"},
+				{Name: "mundane", CurrentCSVName: "rainbows.v1.1"},
+			},
+			head:         "rainbows.v2",
+			versionRange: "<2.0.0",
+			expected:     "rainbows.v1.1",
+		},
+		{
+			name: "SkipRangeIgnoresOtherPackages",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				csv("unicorns.v1", "1.0.0", "", nil, ""),
+				csv("rainbows.v2", "2.0.0", "", nil, ">=1.0.0 <2.0.0"),
+			},
+			channels:     []registry.PackageChannel{{Name: "magical", CurrentCSVName: "rainbows.v2"}},
+			head:         "rainbows.v2",
+			versionRange: "<2.0.0",
+			err:          "no CSV with version in range <2.0.0",
+		},
+		{
+			name: "NoneInRange",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				csv("rainbows.v2", "2.0.0", "", nil, ""),
+			},
+			channels:     []registry.PackageChannel{{Name: "magical", CurrentCSVName: "rainbows.v2"}},
+			head:         "rainbows.v2",
+			versionRange: "<2.0.0",
+			err:          "no CSV with version in range <2.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := registry.NewInMem()
+			for _, csv := range tt.csvs {
+				catalog.AddOrReplaceService(csv)
+			}
+			require.NoError(t, catalog.AddPackageManifest(registry.PackageManifest{PackageName: "rainbows", Channels: tt.channels}))
+
+			head, err := catalog.FindCSVByName(tt.head)
+			require.NoError(t, err)
+			versionRange, err := semverrange.Parse(tt.versionRange)
+			require.NoError(t, err)
+
+			latest, err := findLatestInRange(catalog, "rainbows", head, versionRange)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, latest.GetName())
+		})
+	}
+}
+
+func TestSubscriptionCatalog(t *testing.T) {
+	global := new(fakes.FakeSource)
+	private := new(fakes.FakeSource)
+	other := new(fakes.FakeSource)
+	op := &Operator{namespace: "olm"}
+	catalogs := &catalogSnapshot{
+		sources: map[registry.ResourceKey]registry.Source{
+			{Name: "global", Namespace: "olm"}:      global,
+			{Name: "shadowed", Namespace: "olm"}:    global,
+			{Name: "private", Namespace: "team"}:    private,
+			{Name: "shadowed", Namespace: "team"}:   private,
+			{Name: "private", Namespace: "another"}: other,
+		},
+	}
+
+	tests := []struct {
+		name            string
+		source          string
+		sourceNamespace string
+		expected        registry.Source
+		expectedKey     registry.ResourceKey
+		err             string
+	}{
+		{
+			name:        "global catalog",
+			source:      "global",
+			expected:    global,
+			expectedKey: registry.ResourceKey{Name: "global", Namespace: "olm"},
+		},
+		{
+			name:        "catalog in subscription namespace",
+			source:      "private",
+			expected:    private,
+			expectedKey: registry.ResourceKey{Name: "private", Namespace: "team"},
+		},
+		{
+			name:        "subscription namespace takes precedence",
+			source:      "shadowed",
+			expected:    private,
+			expectedKey: registry.ResourceKey{Name: "shadowed", Namespace: "team"},
+		},
+		{
+			name:            "explicit global namespace",
+			source:          "shadowed",
+			sourceNamespace: "olm",
+			expected:        global,
+			expectedKey:     registry.ResourceKey{Name: "shadowed", Namespace: "olm"},
+		},
+		{
+			name:            "catalog in another namespace",
+			source:          "private",
+			sourceNamespace: "another",
+			err:             "catalog source private in namespace another is not available to subscriptions in namespace team",
+		},
+		{
+			name:   "unknown catalog",
+			source: "missing",
+			err:    "unknown catalog source missing in namespace olm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{Name: "sub", Namespace: "team"},
+				Spec: &v1alpha1.SubscriptionSpec{
+					CatalogSource:          tt.source,
+					CatalogSourceNamespace: tt.sourceNamespace,
+				},
+			}
+			catalog, key, err := op.subscriptionCatalog(catalogs, sub)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.expected == catalog)
+			require.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
+
+func TestSyncSubscriptionAtLatest(t *testing.T) {
+	nowTime := metav1.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC)
+	nextWindowTime := metav1.Date(2018, time.January, 27, 2, 0, 0, 0, time.UTC)
+	timeNow = func() metav1.Time { return nowTime }
+	namespace := "ns"
+
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbows", Namespace: namespace},
+		Spec: &v1alpha1.SubscriptionSpec{
+			CatalogSource: "flying-unicorns",
+			Package:       "rainbows",
+			Channel:       "magical",
+		},
+		Status: v1alpha1.SubscriptionStatus{
+			CurrentCSV:     "rainbows.v1",
+			CurrentChannel: "magical",
+			InstalledCSV:   "rainbows.v1",
+			LastUpdated:    nowTime,
+			State:          v1alpha1.SubscriptionStateAtLatest,
+		},
+	}
+	installed := &v1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbows.v1", Namespace: namespace},
+		Status:     v1alpha1.ClusterServiceVersionStatus{Phase: v1alpha1.CSVPhaseSucceeded},
+	}
+
+	replacement := &v1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbows.v2", Namespace: namespace},
+		Spec:       v1alpha1.ClusterServiceVersionSpec{Version: *semver.New("2.0.0"), Replaces: "rainbows.v1"},
+	}
+
+	tests := []struct {
+		name               string
+		existing           []runtime.Object
+		noCatalog          bool
+		update             func(sub *v1alpha1.Subscription)
+		expectedState      v1alpha1.SubscriptionState
+		expectedCSV        string
+		expectedPlans      int
+		expectedConditions []v1alpha1.SubscriptionCondition
+		expectedWindow     *metav1.Time
+		expectedErr        string
+	}{
+		{
+			name:          "InstalledCSVPresent",
+			existing:      []runtime.Object{installed},
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+		},
+		{
+			name:          "InstalledCSVDeleted",
+			expectedState: v1alpha1.SubscriptionStateUpgradePending,
+			expectedCSV:   "rainbows.v1",
+			expectedPlans: 1,
+		},
+		{
+			name:     "HeldBackWithoutSpecChange",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.Spec.VersionRange = "<3.0.0"
+				sub.Status.HeldBackCSV = "rainbows.v2"
+				sub.Status.Reason = v1alpha1.SubscriptionReasonOutsideVersionRange
+			},
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+		},
+		{
+			name:     "VersionRangeWidened",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.SetGeneration(2)
+				sub.Spec.VersionRange = "<3.0.0"
+				sub.Status.HeldBackCSV = "rainbows.v2"
+				sub.Status.Reason = v1alpha1.SubscriptionReasonOutsideVersionRange
+			},
+			expectedState: v1alpha1.SubscriptionStateUpgradeAvailable,
+			expectedCSV:   "rainbows.v2",
+		},
+		{
+			name:     "BackfillsCurrentChannel",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.Status.CurrentChannel = ""
+			},
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+		},
+		{
+			name:     "RefreshesConditions",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionCatalogSourcesUnhealthy, v1alpha1.SubscriptionReasonInvalidCatalog, "unknown catalog"))
+				sub.Status.SetCondition(v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionInstallPlanPending, "Installing", "waiting"))
+			},
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+			expectedConditions: []v1alpha1.SubscriptionCondition{
+				v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionCatalogSourcesUnhealthy),
+				v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanPending),
+				v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstallPlanFailed),
+				v1alpha1.SubscriptionConditionFalse(v1alpha1.SubscriptionInstalledCSVUnhealthy),
+			},
+		},
+		{
+			name:     "RecomputesMaintenanceWindow",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.Spec.UpgradeSchedule = &v1alpha1.UpgradeSchedule{
+					Windows: []v1alpha1.MaintenanceWindow{{Schedule: "0 2 * * *", Duration: metav1.Duration{Duration: time.Hour}}},
+				}
+				passed := metav1.Date(2018, time.January, 26, 2, 0, 0, 0, time.UTC)
+				sub.Status.NextMaintenanceWindow = &passed
+			},
+			expectedState:  v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:    "rainbows.v1",
+			expectedWindow: &nextWindowTime,
+		},
+		{
+			name:          "CatalogRemoved",
+			existing:      []runtime.Object{installed},
+			noCatalog:     true,
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+			expectedConditions: []v1alpha1.SubscriptionCondition{
+				v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionCatalogSourcesUnhealthy, v1alpha1.SubscriptionReasonInvalidCatalog, "unknown catalog source flying-unicorns in namespace ns"),
+			},
+			expectedErr: "unknown catalog source flying-unicorns in namespace ns",
+		},
+		{
+			name:     "InvalidUpgradeSchedule",
+			existing: []runtime.Object{installed},
+			update: func(sub *v1alpha1.Subscription) {
+				sub.SetGeneration(2)
+				sub.Spec.UpgradeSchedule = &v1alpha1.UpgradeSchedule{TimeZone: "Mars/Olympus_Mons"}
+			},
+			expectedState: v1alpha1.SubscriptionStateAtLatest,
+			expectedCSV:   "rainbows.v1",
+			expectedConditions: []v1alpha1.SubscriptionCondition{
+				v1alpha1.SubscriptionConditionTrue(v1alpha1.SubscriptionResolutionFailed, v1alpha1.SubscriptionReasonInvalidUpgradeSchedule,
+					`invalid upgrade schedule for subscription rainbows: invalid time zone "Mars/Olympus_Mons": unknown time zone Mars/Olympus_Mons`),
+			},
+			expectedErr: `invalid upgrade schedule for subscription rainbows: invalid time zone "Mars/Olympus_Mons": unknown time zone Mars/Olympus_Mons`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientFake := fake.NewSimpleClientset(tt.existing...)
+			catalogFake := new(fakes.FakeSource)
+			catalogFake.FindReplacementCSVForPackageNameUnderChannelReturns(replacement, nil)
+			sources := map[registry.ResourceKey]registry.Source{
+				{Name: "flying-unicorns", Namespace: namespace}: catalogFake,
+			}
+			if tt.noCatalog {
+				sources = nil
+			}
+			op := &Operator{
+				client:    clientFake,
+				namespace: namespace,
+				sources: &catalogSnapshot{
+					sources:    sources,
+					lastUpdate: metav1.NewTime(nowTime.Add(-time.Hour)),
+				},
+			}
+
+			in := sub.DeepCopy()
+			in.SetGeneration(1)
+			in.Status.ObservedGeneration = 1
+			if tt.update != nil {
+				tt.update(in)
+			}
+			out, err := op.syncSubscription(in)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedState, out.Status.State)
+			require.Equal(t, tt.expectedCSV, out.Status.CurrentCSV)
+			require.Equal(t, "magical", out.Status.CurrentChannel)
+			require.Equal(t, tt.expectedWindow, out.Status.NextMaintenanceWindow)
+			require.Equal(t, in.GetGeneration(), out.Status.ObservedGeneration)
+			for _, cond := range tt.expectedConditions {
+				actual := out.Status.GetCondition(cond.Type)
+				require.Equal(t, cond.Status, actual.Status, "condition %s", cond.Type)
+				require.Equal(t, cond.Reason, actual.Reason, "condition %s", cond.Type)
+				require.Equal(t, cond.Message, actual.Message, "condition %s", cond.Type)
+			}
+
+			plans, err := clientFake.OperatorsV1alpha1().InstallPlans(namespace).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, plans.Items, tt.expectedPlans)
+		})
+	}
+}
+
+func TestSyncSubscriptionChannelSwitchSupersedesInstallPlan(t *testing.T) {
+	timeNow = func() metav1.Time { return metav1.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC) }
+	namespace := "ns"
+
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbows", Namespace: namespace},
+		Spec: &v1alpha1.SubscriptionSpec{
+			CatalogSource:       "flying-unicorns",
+			Package:             "rainbows",
+			Channel:             "magical",
+			InstallPlanApproval: v1alpha1.ApprovalManual,
+		},
+		Status: v1alpha1.SubscriptionStatus{
+			CurrentCSV:     "next-in-mundane",
+			CurrentChannel: "mundane",
+			InstalledCSV:   "installed",
+			Install:        &v1alpha1.InstallPlanReference{Name: "install-next-in-mundane"},
+			State:          v1alpha1.SubscriptionStateUpgradePending,
+		},
+	}
+	installed := &v1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "installed", Namespace: namespace},
+		Status:     v1alpha1.ClusterServiceVersionStatus{Phase: v1alpha1.CSVPhaseSucceeded},
+	}
+
+	tests := []struct {
+		name     string
+		phase    v1alpha1.InstallPlanPhase
+		expected int
+	}{
+		{name: "RequiresApprovalDeleted", phase: v1alpha1.InstallPlanPhaseRequiresApproval, expected: 0},
+		{name: "InstallingKept", phase: v1alpha1.InstallPlanPhaseInstalling, expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := &v1alpha1.InstallPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "install-next-in-mundane", Namespace: namespace},
+				Spec:       v1alpha1.InstallPlanSpec{ClusterServiceVersionNames: []string{"next-in-mundane"}, Approval: v1alpha1.ApprovalManual},
+				Status:     v1alpha1.InstallPlanStatus{Phase: tt.phase},
+			}
+			clientFake := fake.NewSimpleClientset(installed, ip)
+			catalogFake := new(fakes.FakeSource)
+			catalogFake.FindReplacementCSVForPackageNameUnderChannelReturns(&v1alpha1.ClusterServiceVersion{
+				ObjectMeta: metav1.ObjectMeta{Name: "next-in-magical"},
+			}, nil)
+			op := &Operator{
+				client:    clientFake,
+				namespace: namespace,
+				sources: &catalogSnapshot{
+					sources: map[registry.ResourceKey]registry.Source{
+						{Name: "flying-unicorns", Namespace: namespace}: catalogFake,
+					},
+				},
+			}
+
+			out, err := op.syncSubscription(sub.DeepCopy())
+			require.NoError(t, err)
+			require.Equal(t, "next-in-magical", out.Status.CurrentCSV)
+			require.Nil(t, out.Status.Install)
+
+			plans, err := clientFake.OperatorsV1alpha1().InstallPlans(namespace).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, plans.Items, tt.expected)
+		})
+	}
+}
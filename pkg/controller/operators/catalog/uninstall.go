@@ -0,0 +1,255 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+// ensureUninstallFinalizer adds or removes the uninstall finalizer to match the subscription's deletion policy. It
+// returns true if the subscription was updated, in which case the update triggers another sync.
+func (o *Operator) ensureUninstallFinalizer(sub *v1alpha1.Subscription) (bool, error) {
+	wanted := sub.GetDeletionPolicy() != v1alpha1.SubscriptionDeletionPolicyOrphan
+	if wanted == hasUninstallFinalizer(sub) {
+		return false, nil
+	}
+
+	out := sub.DeepCopy()
+	if wanted {
+		out.SetFinalizers(append(out.GetFinalizers(), v1alpha1.SubscriptionUninstallFinalizer))
+	} else {
+		removeUninstallFinalizer(out)
+	}
+	if _, err := o.client.OperatorsV1alpha1().Subscriptions(out.GetNamespace()).Update(out); err != nil {
+		return true, fmt.Errorf("failed to update finalizers of subscription %s: %v", sub.GetName(), err)
+	}
+	return true, nil
+}
+
+// uninstallSubscription removes the components installed for a deleted subscription according to its deletion
+// policy, then removes the uninstall finalizer so that the deletion can complete. It returns an error while the
+// components are still being torn down so that the subscription is requeued.
+func (o *Operator) uninstallSubscription(sub *v1alpha1.Subscription) error {
+	if !hasUninstallFinalizer(sub) {
+		return nil
+	}
+
+	if sub.GetDeletionPolicy() != v1alpha1.SubscriptionDeletionPolicyOrphan {
+		for _, name := range subscriptionCSVNames(sub) {
+			removed, err := o.uninstallCSV(sub, name)
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return fmt.Errorf("waiting for CSV %s of subscription %s to be removed", name, sub.GetName())
+			}
+		}
+
+		if sub.GetDeletionPolicy() == v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs {
+			if err := o.uninstallCRDs(sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	out := sub.DeepCopy()
+	removeUninstallFinalizer(out)
+	if _, err := o.client.OperatorsV1alpha1().Subscriptions(out.GetNamespace()).Update(out); err != nil {
+		return fmt.Errorf("failed to remove finalizer from subscription %s: %v", sub.GetName(), err)
+	}
+	return nil
+}
+
+// uninstallCSV deletes the named CSV. If the subscription asks for its CRDs to be removed, the CRDs owned by the CSV
+// are first recorded on the subscription, since they can't be looked up once the CSV is gone. It returns true once
+// the CSV and the deployments and RBAC created for it have been removed.
+func (o *Operator) uninstallCSV(sub *v1alpha1.Subscription, name string) (bool, error) {
+	csv, err := o.client.OperatorsV1alpha1().ClusterServiceVersions(sub.GetNamespace()).Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return o.strategyResourcesRemoved(sub.GetNamespace(), name)
+	}
+	if err != nil {
+		return false, fmt.Errorf("error fetching CSV %s: %v", name, err)
+	}
+	if csv.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+
+	if sub.GetDeletionPolicy() == v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs {
+		// The update triggers another sync, which deletes the CSV
+		if recorded, err := o.recordUninstallCRDs(sub, csv); recorded || err != nil {
+			return false, err
+		}
+	}
+
+	log.Infof("deleting CSV %s of subscription %s", name, sub.GetName())
+	propagation := metav1.DeletePropagationForeground
+	err = o.client.OperatorsV1alpha1().ClusterServiceVersions(sub.GetNamespace()).Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete CSV %s: %v", name, err)
+	}
+	return false, nil
+}
+
+// strategyResourcesRemoved returns true once no deployments, roles or rolebindings created for the named CSV remain.
+// Their owner references don't block the CSV's deletion, so the garbage collector may still be removing them after
+// the CSV is gone.
+func (o *Operator) strategyResourcesRemoved(namespace, name string) (bool, error) {
+	kubeClient := o.OpClient.KubernetesInterface()
+
+	selector := labels.SelectorFromSet(labels.Set{"alm-owner-name": name, "alm-owner-namespace": namespace})
+	deployments, err := kubeClient.AppsV1().Deployments(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, fmt.Errorf("error listing deployments of CSV %s: %v", name, err)
+	}
+	if len(deployments.Items) > 0 {
+		return false, nil
+	}
+
+	roles, err := kubeClient.RbacV1().Roles(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error listing roles of CSV %s: %v", name, err)
+	}
+	for i := range roles.Items {
+		if ownedByCSV(&roles.Items[i], name) {
+			return false, nil
+		}
+	}
+
+	roleBindings, err := kubeClient.RbacV1().RoleBindings(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error listing rolebindings of CSV %s: %v", name, err)
+	}
+	for i := range roleBindings.Items {
+		if ownedByCSV(&roleBindings.Items[i], name) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// recordUninstallCRDs adds the CRDs owned by the CSV to the subscription's uninstall annotation. It returns true if
+// the subscription was updated.
+func (o *Operator) recordUninstallCRDs(sub *v1alpha1.Subscription, csv *v1alpha1.ClusterServiceVersion) (bool, error) {
+	crds := uninstallCRDNames(sub)
+	recorded := map[string]struct{}{}
+	for _, name := range crds {
+		recorded[name] = struct{}{}
+	}
+	updated := false
+	for _, crd := range csv.Spec.CustomResourceDefinitions.Owned {
+		if _, ok := recorded[crd.Name]; !ok {
+			recorded[crd.Name] = struct{}{}
+			crds = append(crds, crd.Name)
+			updated = true
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+
+	out := sub.DeepCopy()
+	annotations := out.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[v1alpha1.SubscriptionUninstallCRDsAnnotationKey] = strings.Join(crds, ",")
+	out.SetAnnotations(annotations)
+	if _, err := o.client.OperatorsV1alpha1().Subscriptions(out.GetNamespace()).Update(out); err != nil {
+		return true, fmt.Errorf("failed to record CRDs of CSV %s on subscription %s: %v", csv.GetName(), sub.GetName(), err)
+	}
+	return true, nil
+}
+
+// uninstallCRDs deletes the CRDs recorded on the subscription, except those still owned or required by a CSV in any
+// namespace.
+func (o *Operator) uninstallCRDs(sub *v1alpha1.Subscription) error {
+	crds := uninstallCRDNames(sub)
+	if len(crds) == 0 {
+		return nil
+	}
+
+	csvs, err := o.client.OperatorsV1alpha1().ClusterServiceVersions(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing CSVs: %v", err)
+	}
+	inUse := map[string]string{}
+	for _, csv := range csvs.Items {
+		for _, crd := range csv.Spec.CustomResourceDefinitions.Owned {
+			inUse[crd.Name] = csv.GetNamespace() + "/" + csv.GetName()
+		}
+		for _, crd := range csv.Spec.CustomResourceDefinitions.Required {
+			inUse[crd.Name] = csv.GetNamespace() + "/" + csv.GetName()
+		}
+	}
+
+	for _, name := range crds {
+		if csv, ok := inUse[name]; ok {
+			log.Infof("keeping CRD %s of subscription %s, still used by CSV %s", name, sub.GetName(), csv)
+			continue
+		}
+		log.Infof("deleting CRD %s of subscription %s", name, sub.GetName())
+		err := o.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete CRD %s of subscription %s: %v", name, sub.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// subscriptionCSVNames returns the names of the installed CSV and of the CSV being upgraded to, if any
+func subscriptionCSVNames(sub *v1alpha1.Subscription) []string {
+	names := []string{}
+	if sub.Status.InstalledCSV != "" {
+		names = append(names, sub.Status.InstalledCSV)
+	}
+	if sub.Status.CurrentCSV != "" && sub.Status.CurrentCSV != sub.Status.InstalledCSV {
+		names = append(names, sub.Status.CurrentCSV)
+	}
+	return names
+}
+
+// uninstallCRDNames returns the CRDs recorded on the subscription by recordUninstallCRDs
+func uninstallCRDNames(sub *v1alpha1.Subscription) []string {
+	names := []string{}
+	for _, name := range strings.Split(sub.GetAnnotations()[v1alpha1.SubscriptionUninstallCRDsAnnotationKey], ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func ownedByCSV(obj metav1.Object, name string) bool {
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.Kind == v1alpha1.ClusterServiceVersionKind && owner.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasUninstallFinalizer(sub *v1alpha1.Subscription) bool {
+	for _, f := range sub.GetFinalizers() {
+		if f == v1alpha1.SubscriptionUninstallFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeUninstallFinalizer(sub *v1alpha1.Subscription) {
+	finalizers := []string{}
+	for _, f := range sub.GetFinalizers() {
+		if f != v1alpha1.SubscriptionUninstallFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	sub.SetFinalizers(finalizers)
+}
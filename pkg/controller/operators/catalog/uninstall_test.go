@@ -0,0 +1,257 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry/resolver"
+)
+
+func uninstallTestSubscription(policy v1alpha1.SubscriptionDeletionPolicy, finalizers []string, deleted bool) *v1alpha1.Subscription {
+	sub := &v1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "sub",
+			Namespace:  "ns",
+			Finalizers: finalizers,
+		},
+		Spec: &v1alpha1.SubscriptionSpec{
+			CatalogSource:  "catalog",
+			Package:        "rainbows",
+			Channel:        "magical",
+			DeletionPolicy: policy,
+		},
+		Status: v1alpha1.SubscriptionStatus{
+			CurrentCSV:   "installed",
+			InstalledCSV: "installed",
+		},
+	}
+	if deleted {
+		now := metav1.Now()
+		sub.SetDeletionTimestamp(&now)
+	}
+	return sub
+}
+
+func TestEnsureUninstallFinalizer(t *testing.T) {
+	tests := []struct {
+		name               string
+		subscription       *v1alpha1.Subscription
+		expectedUpdated    bool
+		expectedFinalizers []string
+	}{
+		{
+			name:            "default policy has no finalizer",
+			subscription:    uninstallTestSubscription("", nil, false),
+			expectedUpdated: false,
+		},
+		{
+			name:               "uninstall policy adds finalizer",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstall, []string{"other"}, false),
+			expectedUpdated:    true,
+			expectedFinalizers: []string{"other", v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:               "uninstall policy with finalizer",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs, []string{v1alpha1.SubscriptionUninstallFinalizer}, false),
+			expectedUpdated:    false,
+			expectedFinalizers: []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:               "orphan policy removes finalizer",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyOrphan, []string{v1alpha1.SubscriptionUninstallFinalizer, "other"}, false),
+			expectedUpdated:    true,
+			expectedFinalizers: []string{"other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := NewFakeOperator([]runtime.Object{tt.subscription}, nil, nil, nil, &resolver.ConstraintResolver{}, "ns")
+			require.NoError(t, err)
+
+			updated, err := op.ensureUninstallFinalizer(tt.subscription)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedUpdated, updated)
+
+			sub, err := op.client.OperatorsV1alpha1().Subscriptions("ns").Get("sub", metav1.GetOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedFinalizers, sub.GetFinalizers())
+		})
+	}
+}
+
+func TestUninstallSubscription(t *testing.T) {
+	csv := &v1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "installed",
+			Namespace: "ns",
+		},
+		Spec: v1alpha1.ClusterServiceVersionSpec{
+			CustomResourceDefinitions: v1alpha1.CustomResourceDefinitions{
+				Owned: []v1alpha1.CRDDescription{{Name: "rainbows.example.com"}},
+			},
+		},
+	}
+	crd := &v1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "rainbows.example.com",
+		},
+	}
+	csvOwner := []metav1.OwnerReference{{
+		APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+		Kind:       v1alpha1.ClusterServiceVersionKind,
+		Name:       "installed",
+	}}
+	withRecordedCRDs := func(sub *v1alpha1.Subscription) *v1alpha1.Subscription {
+		sub.SetAnnotations(map[string]string{v1alpha1.SubscriptionUninstallCRDsAnnotationKey: crd.GetName()})
+		return sub
+	}
+
+	tests := []struct {
+		name                 string
+		subscription         *v1alpha1.Subscription
+		existingCSV          bool
+		clientObjs           []runtime.Object
+		k8sObjs              []runtime.Object
+		err                  string
+		expectedCSV          bool
+		expectedCRD          bool
+		expectedRecordedCRDs string
+		expectedFinalizers   []string
+	}{
+		{
+			name:               "deletes installed CSV and waits for it",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstall, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:        true,
+			err:                "waiting for CSV installed of subscription sub to be removed",
+			expectedCSV:        false,
+			expectedCRD:        true,
+			expectedFinalizers: []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:                 "records owned CRDs before deleting CSV",
+			subscription:         uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:          true,
+			err:                  "waiting for CSV installed of subscription sub to be removed",
+			expectedCSV:          true,
+			expectedCRD:          true,
+			expectedRecordedCRDs: "rainbows.example.com",
+			expectedFinalizers:   []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:                 "keeps CRDs until CSV is gone",
+			subscription:         withRecordedCRDs(uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs, []string{v1alpha1.SubscriptionUninstallFinalizer}, true)),
+			existingCSV:          true,
+			err:                  "waiting for CSV installed of subscription sub to be removed",
+			expectedCSV:          false,
+			expectedCRD:          true,
+			expectedRecordedCRDs: "rainbows.example.com",
+			expectedFinalizers:   []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:                 "deletes recorded CRDs once CSV is gone",
+			subscription:         withRecordedCRDs(uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs, []string{v1alpha1.SubscriptionUninstallFinalizer}, true)),
+			existingCSV:          false,
+			expectedCRD:          false,
+			expectedRecordedCRDs: "rainbows.example.com",
+			expectedFinalizers:   []string{},
+		},
+		{
+			name:         "keeps recorded CRDs required by other CSVs",
+			subscription: withRecordedCRDs(uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstallWithCRDs, []string{v1alpha1.SubscriptionUninstallFinalizer}, true)),
+			existingCSV:  false,
+			clientObjs: []runtime.Object{&v1alpha1.ClusterServiceVersion{
+				ObjectMeta: metav1.ObjectMeta{Name: "dependent", Namespace: "other"},
+				Spec: v1alpha1.ClusterServiceVersionSpec{
+					CustomResourceDefinitions: v1alpha1.CustomResourceDefinitions{
+						Required: []v1alpha1.CRDDescription{{Name: "rainbows.example.com"}},
+					},
+				},
+			}},
+			expectedCRD:          true,
+			expectedRecordedCRDs: "rainbows.example.com",
+			expectedFinalizers:   []string{},
+		},
+		{
+			name:         "waits for deployments of removed CSV",
+			subscription: uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstall, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:  false,
+			k8sObjs: []runtime.Object{&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "operator",
+					Namespace:       "ns",
+					Labels:          map[string]string{"alm-owner-name": "installed", "alm-owner-namespace": "ns"},
+					OwnerReferences: csvOwner,
+				},
+			}},
+			err:                "waiting for CSV installed of subscription sub to be removed",
+			expectedCRD:        true,
+			expectedFinalizers: []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:         "waits for RBAC of removed CSV",
+			subscription: uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstall, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:  false,
+			k8sObjs: []runtime.Object{&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "installed-role-binding",
+					Namespace:       "ns",
+					OwnerReferences: csvOwner,
+				},
+			}},
+			err:                "waiting for CSV installed of subscription sub to be removed",
+			expectedCRD:        true,
+			expectedFinalizers: []string{v1alpha1.SubscriptionUninstallFinalizer},
+		},
+		{
+			name:               "removes finalizer once CSV is gone",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyUninstall, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:        false,
+			expectedCRD:        true,
+			expectedFinalizers: []string{},
+		},
+		{
+			name:               "orphan policy leaves CSV in place",
+			subscription:       uninstallTestSubscription(v1alpha1.SubscriptionDeletionPolicyOrphan, []string{v1alpha1.SubscriptionUninstallFinalizer}, true),
+			existingCSV:        true,
+			expectedCSV:        true,
+			expectedCRD:        true,
+			expectedFinalizers: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientObjs := append([]runtime.Object{tt.subscription}, tt.clientObjs...)
+			if tt.existingCSV {
+				clientObjs = append(clientObjs, csv.DeepCopy())
+			}
+			op, err := NewFakeOperator(clientObjs, tt.k8sObjs, []runtime.Object{crd.DeepCopy()}, nil, &resolver.ConstraintResolver{}, "ns")
+			require.NoError(t, err)
+
+			err = op.uninstallSubscription(tt.subscription)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			_, err = op.client.OperatorsV1alpha1().ClusterServiceVersions("ns").Get("installed", metav1.GetOptions{})
+			require.Equal(t, tt.expectedCSV, !k8serrors.IsNotFound(err))
+
+			_, err = op.OpClient.ApiextensionsV1beta1Interface().ApiextensionsV1beta1().CustomResourceDefinitions().Get(crd.GetName(), metav1.GetOptions{})
+			require.Equal(t, tt.expectedCRD, !k8serrors.IsNotFound(err))
+
+			sub, err := op.client.OperatorsV1alpha1().Subscriptions("ns").Get("sub", metav1.GetOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedRecordedCRDs, sub.GetAnnotations()[v1alpha1.SubscriptionUninstallCRDsAnnotationKey])
+			require.Equal(t, tt.expectedFinalizers, sub.GetFinalizers())
+		})
+	}
+}
@@ -472,6 +472,13 @@ func (a *Operator) updateInstallStatus(csv *v1alpha1.ClusterServiceVersion, inst
 
 	// TODO(Nick): check if apiServiceErr is unrecoverable
 
+	// installed components are out of date with the CSV (e.g. the Subscription config changed), reinstall them
+	if install.IsErrorOutdated(strategyErr) {
+		csv.SetPhase(v1alpha1.CSVPhaseInstallReady, v1alpha1.CSVReasonNeedsReinstall, fmt.Sprintf("reinstalling: %s", strategyErr))
+		a.requeueCSV(csv.GetName(), csv.GetNamespace())
+		return strategyErr
+	}
+
 	// installcheck determined we can't progress (e.g. deployment failed to come up in time)
 	if install.IsErrorUnrecoverable(strategyErr) {
 		csv.SetPhase(v1alpha1.CSVPhaseFailed, v1alpha1.CSVReasonInstallCheckFailed, fmt.Sprintf("install failed: %s", strategyErr))
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// MaxArchiveSize limits the size of downloaded catalog archives
+const MaxArchiveSize = 100 << 20
+
+var archiveClient = &http.Client{Timeout: time.Minute}
+
+// Archive is the result of polling a catalog archive URL
+type Archive struct {
+	// NotModified is set when the server reported that the archive matching the requested ETag is unchanged. The
+	// body isn't sent again in that case.
+	NotModified bool
+	ETag        string
+	Digest      string
+	Body        []byte
+}
+
+// FetchArchive downloads the catalog archive at url. If etag is set, the server may report that the archive is
+// unchanged instead of sending it again.
+func FetchArchive(url, etag string) (*Archive, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := archiveClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return &Archive{NotModified: true, ETag: etag}, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxArchiveSize {
+		return nil, fmt.Errorf("archive is larger than %d bytes", MaxArchiveSize)
+	}
+	return &Archive{
+		ETag:   resp.Header.Get("ETag"),
+		Digest: fmt.Sprintf("%x", sha256.Sum256(body)),
+		Body:   body,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ArchiveCatalogResourceLoader loads a gzipped tar archive of a catalog directory into the in-memory catalog. The
+// archive is unpacked into a temporary directory, which is read like DirectoryCatalogResourceLoader reads a directory.
+type ArchiveCatalogResourceLoader struct {
+	Catalog *InMem
+}
+
+func (a *ArchiveCatalogResourceLoader) LoadCatalogResources(archive io.Reader) error {
+	log.Debugf("Load Archive -- BEGIN")
+	directory, err := ioutil.TempDir("", "catalog-archive-")
+	if err != nil {
+		return fmt.Errorf("error creating directory for catalog archive: %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	if err := unpackArchive(archive, directory); err != nil {
+		log.Debugf("Load Archive -- ERROR %s", err)
+		return fmt.Errorf("error unpacking catalog archive: %s", err)
+	}
+
+	loader := DirectoryCatalogResourceLoader{a.Catalog}
+	if err := loader.LoadCatalogResources(directory); err != nil {
+		return err
+	}
+	log.Debugf("Load Archive -- OK")
+	return nil
+}
+
+// unpackArchive writes the directories and regular files of a gzipped tar archive under directory. Other entries
+// are skipped, and entries that would be written outside of directory are rejected.
+func unpackArchive(archive io.Reader, directory string) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(directory, header.Name)
+		if path == filepath.Clean(directory) {
+			continue
+		}
+		if !strings.HasPrefix(path, filepath.Clean(directory)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %s is outside of the catalog directory", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(path, tr); err != nil {
+				return err
+			}
+		default:
+			log.Debugf("Load Archive -- SKIP %s", header.Name)
+		}
+	}
+}
+
+func writeArchiveFile(path string, contents io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, contents); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// archiveDirectory returns a gzipped tar archive of directory, with entries under prefix
+func archiveDirectory(t *testing.T, directory, prefix string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(directory, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(f, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.Join(prefix, rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(contents)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestArchiveLoader(t *testing.T) {
+	directory := "../../../deploy/chart/catalog_resources/rh-operators"
+	expected, err := NewInMemoryFromDirectory(directory)
+	require.NoError(t, err)
+
+	for _, prefix := range []string{".", "rh-operators"} {
+		catalog, err := NewInMemoryFromArchive(bytes.NewReader(archiveDirectory(t, directory, prefix)))
+		require.NoError(t, err, prefix)
+		require.Equal(t, expected.AllPackages(), catalog.AllPackages(), prefix)
+		require.Equal(t, len(expected.clusterservices), len(catalog.clusterservices), prefix)
+		require.Equal(t, len(expected.crds), len(catalog.crds), prefix)
+	}
+}
+
+func TestArchiveLoaderInvalidArchives(t *testing.T) {
+	_, err := NewInMemoryFromArchive(bytes.NewReader([]byte("not an archive")))
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	contents := []byte("escaped")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escaped.crd.yaml", Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	_, err = NewInMemoryFromArchive(&buf)
+	require.EqualError(t, err, "error unpacking catalog archive: archive entry ../escaped.crd.yaml is outside of the catalog directory")
+}
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+)
+
+// The registry service is plain gRPC with JSON encoded messages, so that catalog types can be sent without
+// generating protobuf definitions for them. Each method corresponds to the Source method of the same name.
+const (
+	registryServiceName = "registry.Registry"
+
+	methodFindCSVForPackageNameUnderChannel            = "FindCSVForPackageNameUnderChannel"
+	methodFindReplacementCSVForPackageNameUnderChannel = "FindReplacementCSVForPackageNameUnderChannel"
+	methodAllPackages                                  = "AllPackages"
+	methodFindReplacementCSVForName                    = "FindReplacementCSVForName"
+	methodFindCSVByName                                = "FindCSVByName"
+	methodListServices                                 = "ListServices"
+	methodFindCRDByKey                                 = "FindCRDByKey"
+	methodListLatestCSVsForCRD                         = "ListLatestCSVsForCRD"
+	methodListCRDs                                     = "ListCRDs"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes gRPC messages as JSON. Clients select it with the "json" content subtype.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// registryRequest holds the arguments of a registry call. Each method uses the fields matching its Source
+// method's parameters.
+type registryRequest struct {
+	PackageName string  `json:"packageName,omitempty"`
+	ChannelName string  `json:"channelName,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	CRDKey      *CRDKey `json:"crdKey,omitempty"`
+}
+
+// crdLister is implemented by sources that can list their CRDs, which the registry service serves as ListCRDs
+type crdLister interface {
+	ListCRDs() ([]v1beta1.CustomResourceDefinition, error)
+}
+
+// RegisterRegistryServer serves the given source on a gRPC server
+func RegisterRegistryServer(s *grpc.Server, source Source) {
+	s.RegisterService(&registryServiceDesc, source)
+}
+
+var registryServiceDesc = grpc.ServiceDesc{
+	ServiceName: registryServiceName,
+	HandlerType: (*Source)(nil),
+	Methods: []grpc.MethodDesc{
+		registryMethod(methodFindCSVForPackageNameUnderChannel, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.FindCSVForPackageNameUnderChannel(r.PackageName, r.ChannelName)
+		}),
+		registryMethod(methodFindReplacementCSVForPackageNameUnderChannel, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.FindReplacementCSVForPackageNameUnderChannel(r.PackageName, r.ChannelName, r.Name)
+		}),
+		registryMethod(methodAllPackages, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.AllPackages(), nil
+		}),
+		registryMethod(methodFindReplacementCSVForName, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.FindReplacementCSVForName(r.Name)
+		}),
+		registryMethod(methodFindCSVByName, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.FindCSVByName(r.Name)
+		}),
+		registryMethod(methodListServices, func(s Source, r *registryRequest) (interface{}, error) {
+			return s.ListServices()
+		}),
+		registryMethod(methodFindCRDByKey, func(s Source, r *registryRequest) (interface{}, error) {
+			if r.CRDKey == nil {
+				return nil, status.Error(codes.InvalidArgument, "missing CRD key")
+			}
+			return s.FindCRDByKey(*r.CRDKey)
+		}),
+		registryMethod(methodListLatestCSVsForCRD, func(s Source, r *registryRequest) (interface{}, error) {
+			if r.CRDKey == nil {
+				return nil, status.Error(codes.InvalidArgument, "missing CRD key")
+			}
+			return s.ListLatestCSVsForCRD(*r.CRDKey)
+		}),
+		registryMethod(methodListCRDs, func(s Source, r *registryRequest) (interface{}, error) {
+			lister, ok := s.(crdLister)
+			if !ok {
+				return nil, status.Error(codes.Unimplemented, "source doesn't list CRDs")
+			}
+			return lister.ListCRDs()
+		}),
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// registryMethod adapts a Source call to a unary gRPC method
+func registryMethod(name string, call func(Source, *registryRequest) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := &registryRequest{}
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(Source), req.(*registryRequest))
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + registryServiceName + "/" + name,
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+const defaultGRPCCallTimeout = 30 * time.Second
+
+// GRPCSource - catalog source implementation that queries a registry server over gRPC
+var _ Source = &GRPCSource{}
+
+type GRPCSource struct {
+	address string
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// NewGRPCSource returns a source for the registry server at the given address. The connection is established in the
+// background, so an unreachable server is only reported by calls to the source.
+func NewGRPCSource(address string) (*GRPCSource, error) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to registry at %s: %s", address, err)
+	}
+	return &GRPCSource{
+		address: address,
+		conn:    conn,
+		timeout: defaultGRPCCallTimeout,
+	}, nil
+}
+
+// Address returns the address of the registry server
+func (s *GRPCSource) Address() string {
+	return s.address
+}
+
+// Close closes the connection to the registry server
+func (s *GRPCSource) Close() error {
+	return s.conn.Close()
+}
+
+func (s *GRPCSource) FindCSVForPackageNameUnderChannel(packageName string, channelName string) (*v1alpha1.ClusterServiceVersion, error) {
+	var csv *v1alpha1.ClusterServiceVersion
+	err := s.call(methodFindCSVForPackageNameUnderChannel, &registryRequest{PackageName: packageName, ChannelName: channelName}, &csv)
+	return csv, err
+}
+
+func (s *GRPCSource) FindReplacementCSVForPackageNameUnderChannel(packageName string, channelName string, csvName string) (*v1alpha1.ClusterServiceVersion, error) {
+	var csv *v1alpha1.ClusterServiceVersion
+	err := s.call(methodFindReplacementCSVForPackageNameUnderChannel, &registryRequest{PackageName: packageName, ChannelName: channelName, Name: csvName}, &csv)
+	return csv, err
+}
+
+// AllPackages returns all package manifests in the catalog, or nil if the registry server can't be reached
+func (s *GRPCSource) AllPackages() map[string]PackageManifest {
+	packages, err := s.ListPackages()
+	if err != nil {
+		return nil
+	}
+	return packages
+}
+
+// ListPackages returns all package manifests in the catalog
+func (s *GRPCSource) ListPackages() (map[string]PackageManifest, error) {
+	packages := map[string]PackageManifest{}
+	if err := s.call(methodAllPackages, &registryRequest{}, &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+func (s *GRPCSource) FindReplacementCSVForName(name string) (*v1alpha1.ClusterServiceVersion, error) {
+	var csv *v1alpha1.ClusterServiceVersion
+	err := s.call(methodFindReplacementCSVForName, &registryRequest{Name: name}, &csv)
+	return csv, err
+}
+
+func (s *GRPCSource) FindCSVByName(name string) (*v1alpha1.ClusterServiceVersion, error) {
+	var csv *v1alpha1.ClusterServiceVersion
+	err := s.call(methodFindCSVByName, &registryRequest{Name: name}, &csv)
+	return csv, err
+}
+
+func (s *GRPCSource) ListServices() ([]v1alpha1.ClusterServiceVersion, error) {
+	services := []v1alpha1.ClusterServiceVersion{}
+	if err := s.call(methodListServices, &registryRequest{}, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (s *GRPCSource) FindCRDByKey(key CRDKey) (*v1beta1.CustomResourceDefinition, error) {
+	var crd *v1beta1.CustomResourceDefinition
+	err := s.call(methodFindCRDByKey, &registryRequest{CRDKey: &key}, &crd)
+	return crd, err
+}
+
+func (s *GRPCSource) ListLatestCSVsForCRD(key CRDKey) ([]CSVAndChannelInfo, error) {
+	csvs := []CSVAndChannelInfo{}
+	if err := s.call(methodListLatestCSVsForCRD, &registryRequest{CRDKey: &key}, &csvs); err != nil {
+		return nil, err
+	}
+	return csvs, nil
+}
+
+// ListCRDs lists all versions of the CRDs in the catalog
+func (s *GRPCSource) ListCRDs() ([]v1beta1.CustomResourceDefinition, error) {
+	crds := []v1beta1.CustomResourceDefinition{}
+	if err := s.call(methodListCRDs, &registryRequest{}, &crds); err != nil {
+		return nil, err
+	}
+	return crds, nil
+}
+
+// call invokes a registry method. Errors returned by the server's source are passed on unchanged, so that callers
+// see the same errors as from a local source.
+func (s *GRPCSource) call(method string, req *registryRequest, reply interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	err := s.conn.Invoke(ctx, "/"+registryServiceName+"/"+method, req, reply)
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok && st.Code() == codes.Unknown {
+		return errors.New(st.Message())
+	}
+	return fmt.Errorf("error calling %s on registry at %s: %s", method, s.address, err)
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestGRPCSource(t *testing.T) {
+	catalog, err := NewInMemoryFromDirectory("../../../deploy/chart/catalog_resources/rh-operators")
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	RegisterRegistryServer(server, catalog)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	source, err := NewGRPCSource(lis.Addr().String())
+	require.NoError(t, err)
+	defer source.Close()
+
+	packages, err := source.ListPackages()
+	require.NoError(t, err)
+	require.Equal(t, catalog.AllPackages(), packages)
+	require.Equal(t, catalog.AllPackages(), source.AllPackages())
+
+	csv, err := source.FindCSVForPackageNameUnderChannel("etcd", "alpha")
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.2", csv.GetName())
+
+	csv, err = source.FindReplacementCSVForPackageNameUnderChannel("etcd", "alpha", "etcdoperator.v0.9.0")
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.2", csv.GetName())
+
+	csv, err = source.FindReplacementCSVForName("etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.0", csv.GetName())
+
+	csv, err = source.FindCSVByName("etcdoperator.v0.9.0")
+	require.NoError(t, err)
+	expected, err := catalog.FindCSVByName("etcdoperator.v0.9.0")
+	require.NoError(t, err)
+	require.Equal(t, expected.Spec.Version, csv.Spec.Version)
+	require.Equal(t, expected.Spec.Replaces, csv.Spec.Replaces)
+
+	services, err := source.ListServices()
+	require.NoError(t, err)
+	expectedServices, err := catalog.ListServices()
+	require.NoError(t, err)
+	require.Len(t, services, len(expectedServices))
+
+	crds, err := source.ListCRDs()
+	require.NoError(t, err)
+	expectedCRDs, err := catalog.ListCRDs()
+	require.NoError(t, err)
+	require.Len(t, crds, len(expectedCRDs))
+
+	key := CRDKey{Kind: "EtcdCluster", Name: "etcdclusters.etcd.database.coreos.com", Version: "v1beta2"}
+	crd, err := source.FindCRDByKey(key)
+	require.NoError(t, err)
+	require.Equal(t, key.Name, crd.GetName())
+
+	owners, err := source.ListLatestCSVsForCRD(key)
+	require.NoError(t, err)
+	require.Len(t, owners, 1)
+	require.Equal(t, "etcdoperator.v0.9.2", owners[0].CSV.GetName())
+	require.True(t, owners[0].IsDefaultChannel)
+
+	// Errors from the served source are passed on unchanged
+	_, err = source.FindCSVByName("missing")
+	_, expectedErr := catalog.FindCSVByName("missing")
+	require.EqualError(t, err, expectedErr.Error())
+}
+
+func TestGRPCSourceUnavailable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	source, err := NewGRPCSource(address)
+	require.NoError(t, err)
+	defer source.Close()
+
+	_, err = source.ListPackages()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error calling AllPackages on registry at "+address)
+	require.Nil(t, source.AllPackages())
+}
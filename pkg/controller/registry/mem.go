@@ -2,13 +2,16 @@ package registry
 
 import (
 	"fmt"
+	"io"
 
+	"github.com/coreos/go-semver/semver"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/equality"
 
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/operatorclient"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/semverrange"
 )
 
 // InMem - catalog source implementation that stores the data in memory in golang maps
@@ -18,9 +21,6 @@ type InMem struct {
 	// map ClusterServiceVersion name to their resource definition
 	clusterservices map[string]v1alpha1.ClusterServiceVersion
 
-	// map ClusterServiceVersions by name to metadata for the CSV that replaces it
-	replaces map[string][]CSVMetadata
-
 	// map CRD to their full definition
 	crds map[CRDKey]v1beta1.CustomResourceDefinition
 
@@ -48,6 +48,15 @@ func NewInMemoryFromDirectory(directory string) (*InMem, error) {
 	return loader.Catalog, nil
 }
 
+// NewInMemoryFromArchive loads a catalog from a gzipped tar archive of a catalog directory
+func NewInMemoryFromArchive(archive io.Reader) (*InMem, error) {
+	loader := ArchiveCatalogResourceLoader{NewInMem()}
+	if err := loader.LoadCatalogResources(archive); err != nil {
+		return nil, err
+	}
+	return loader.Catalog, nil
+}
+
 func NewInMemoryFromConfigMap(cmClient operatorclient.ClientInterface, namespace, cmName string) (*InMem, error) {
 	log.Infof("loading catalog from a configmap: %s", cmName)
 	loader := ConfigMapCatalogResourceLoader{namespace, cmClient}
@@ -62,7 +71,6 @@ func NewInMemoryFromConfigMap(cmClient operatorclient.ClientInterface, namespace
 func NewInMem() *InMem {
 	return &InMem{
 		clusterservices:    map[string]v1alpha1.ClusterServiceVersion{},
-		replaces:           map[string][]CSVMetadata{},
 		crds:               map[CRDKey]v1beta1.CustomResourceDefinition{},
 		crdOwners:          map[CRDKey][]string{},
 		packages:           map[string]PackageManifest{},
@@ -85,8 +93,9 @@ func (m *InMem) SetCRDDefinition(crd v1beta1.CustomResourceDefinition) error {
 	return nil
 }
 
-// FindReplacementCSVForPackageNameUnderChannel returns the CSV that replaces the CSV with the
-// matching CSV name, within the package and channel specified.
+// FindReplacementCSVForPackageNameUnderChannel returns the newest CSV that can replace the CSV with the
+// matching CSV name in a single hop, within the package and channel specified. A CSV replaces another in a
+// single hop if it names it in `replaces` or `skips`, or if its `skipRange` contains the other's version.
 func (m *InMem) FindReplacementCSVForPackageNameUnderChannel(packageName string, channelName string, csvName string) (*v1alpha1.ClusterServiceVersion, error) {
 	latestCSV, err := m.FindCSVForPackageNameUnderChannel(packageName, channelName)
 	if err != nil {
@@ -97,15 +106,28 @@ func (m *InMem) FindReplacementCSVForPackageNameUnderChannel(packageName string,
 		return nil, fmt.Errorf("Channel is already up-to-date")
 	}
 
-	// Walk backwards over the `replaces` field until we find the CSV with the specified name.
+	// The replaced CSV may have been pruned from the catalog, in which case only its name can be matched.
+	var csvVersion *semver.Version
+	if csv, err := m.FindCSVByName(csvName); err == nil {
+		csvVersion = &csv.Spec.Version
+	}
+
+	// Walk backwards over the `replaces` field from the head of the channel until we find the first
+	// (and so newest) CSV that replaces the CSV with the specified name.
 	var currentCSV = latestCSV
-	var nextCSV *v1alpha1.ClusterServiceVersion = nil
 	for currentCSV != nil {
 		if currentCSV.GetName() == csvName {
-			return nextCSV, nil
+			break
+		}
+
+		replaces, err := ReplacesInOneHop(currentCSV, csvName, csvVersion)
+		if err != nil {
+			return nil, err
+		}
+		if replaces {
+			return currentCSV, nil
 		}
 
-		nextCSV = currentCSV
 		replacesName := currentCSV.Spec.Replaces
 		currentCSV = nil
 
@@ -122,6 +144,30 @@ func (m *InMem) FindReplacementCSVForPackageNameUnderChannel(packageName string,
 	return nil, fmt.Errorf("Could not find matching replacement for CSV `%s` in package `%s` for channel `%s`", csvName, packageName, channelName)
 }
 
+// ReplacesInOneHop reports whether the given CSV can directly replace the CSV with the given name and version.
+// The version is optional and is only checked against the CSV's skipRange.
+func ReplacesInOneHop(csv *v1alpha1.ClusterServiceVersion, name string, version *semver.Version) (bool, error) {
+	if csv.Spec.Replaces == name {
+		return true, nil
+	}
+
+	for _, skipped := range csv.Spec.Skips {
+		if skipped == name {
+			return true, nil
+		}
+	}
+
+	if csv.Spec.SkipRange == "" || version == nil {
+		return false, nil
+	}
+
+	skipRange, err := semverrange.Parse(csv.Spec.SkipRange)
+	if err != nil {
+		return false, fmt.Errorf("invalid skipRange for CSV %s: %v", csv.GetName(), err)
+	}
+	return skipRange.Contains(*version), nil
+}
+
 // FindCSVForPackageNameUnderChannel finds the CSV referenced by the specified channel under the
 // package with the specified name.
 func (m *InMem) FindCSVForPackageNameUnderChannel(packageName string, channelName string) (*v1alpha1.ClusterServiceVersion, error) {
@@ -188,23 +234,35 @@ func (m *InMem) AddPackageManifest(pkg PackageManifest) error {
 	return nil
 }
 
-// fullCSVHistory returns the full set of CSVs in the `replaces` history, starting at the given CSV.
+// fullCSVReplacesHistory returns the given CSV and every CSV in the catalog that it replaces, directly or through
+// other CSVs. CSVs are followed through `replaces` and `skips`, but not `skipRange`: the history is used to find the
+// package of a CSV, which is needed to tell whether a skipRange applies to it.
 func (m *InMem) fullCSVReplacesHistory(csv *v1alpha1.ClusterServiceVersion) ([]v1alpha1.ClusterServiceVersion, error) {
-	if csv.Spec.Replaces == "" {
-		return []v1alpha1.ClusterServiceVersion{*csv}, nil
-	}
-
-	replaced, err := m.FindCSVByName(csv.Spec.Replaces)
-	if err != nil {
-		return []v1alpha1.ClusterServiceVersion{}, err
-	}
+	history := []v1alpha1.ClusterServiceVersion{}
+	visited := map[string]struct{}{}
+	queue := []v1alpha1.ClusterServiceVersion{*csv}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[current.GetName()]; ok {
+			continue
+		}
+		visited[current.GetName()] = struct{}{}
+		history = append(history, current)
 
-	replacedChain, err := m.fullCSVReplacesHistory(replaced)
-	if err != nil {
-		return []v1alpha1.ClusterServiceVersion{}, err
+		// The replaced CSV must be in the catalog, skipped CSVs may have been pruned
+		if current.Spec.Replaces != "" {
+			if _, err := m.FindCSVByName(current.Spec.Replaces); err != nil {
+				return []v1alpha1.ClusterServiceVersion{}, err
+			}
+		}
+		for name, replaced := range m.clusterservices {
+			if replaces, _ := ReplacesInOneHop(&current, name, nil); replaces {
+				queue = append(queue, replaced)
+			}
+		}
 	}
-
-	return append(replacedChain, *csv), nil
+	return history, nil
 }
 
 // setOrReplaceCRDDefinition overwrites any existing definition with the same name
@@ -251,18 +309,6 @@ func (m *InMem) addService(csv v1alpha1.ClusterServiceVersion, safe bool) error
 	// add service
 	m.clusterservices[name] = csv
 
-	// register it as replacing CSV from its spec, if any
-	if csv.Spec.Replaces != "" {
-		if _, ok := m.replaces[csv.Spec.Replaces]; !ok {
-			m.replaces[csv.Spec.Replaces] = []CSVMetadata{}
-		}
-
-		m.replaces[csv.Spec.Replaces] = append(m.replaces[csv.Spec.Replaces], CSVMetadata{
-			Name:    name,
-			Version: csv.Spec.Version.String(),
-		})
-	}
-
 	// register its crds
 	for _, crd := range csv.Spec.CustomResourceDefinitions.Owned {
 		key := CRDKey{
@@ -293,16 +339,11 @@ func (m *InMem) AddOrReplaceService(csv v1alpha1.ClusterServiceVersion) {
 
 // removeService is a helper fn to delete a service from the catalog
 func (m *InMem) removeService(name string) error {
-	csv, exists := m.clusterservices[name]
-	if !exists {
+	if _, exists := m.clusterservices[name]; !exists {
 		return fmt.Errorf("not found: ClusterServiceVersion %s", name)
 	}
 
 	delete(m.clusterservices, name)
-	if csv.Spec.Replaces != "" {
-		delete(m.replaces, csv.Spec.Replaces)
-	}
-
 	return nil
 }
 
@@ -316,18 +357,56 @@ func (m *InMem) FindCSVByName(name string) (*v1alpha1.ClusterServiceVersion, err
 	return &csv, nil
 }
 
-// FindReplacementCSVForName looks up any CSV in the catalog that replaces the given CSV, if any.
+// FindReplacementCSVForName looks up the newest CSV in the catalog that can replace the given CSV in a single hop,
+// if any. A CSV's skipRange is only matched against CSVs in the same package, since any package may have a CSV with
+// a version in the range.
 func (m *InMem) FindReplacementCSVForName(name string) (*v1alpha1.ClusterServiceVersion, error) {
-	csvMetadata, ok := m.replaces[name]
-	if !ok {
-		return nil, fmt.Errorf("not found: ClusterServiceVersion that replaces %s", name)
+	var replacedVersion *semver.Version
+	if replaced, ok := m.clusterservices[name]; ok {
+		replacedVersion = &replaced.Spec.Version
 	}
 
-	if len(csvMetadata) < 1 {
+	var latest *v1alpha1.ClusterServiceVersion
+	for _, csv := range m.clusterservices {
+		csv := csv
+		if csv.GetName() == name {
+			continue
+		}
+
+		version := replacedVersion
+		if !m.inSamePackage(csv.GetName(), name) {
+			version = nil
+		}
+		replaces, err := ReplacesInOneHop(&csv, name, version)
+		if err != nil {
+			log.Warnf("skipping CSV %s: %v", csv.GetName(), err)
+			continue
+		}
+		if !replaces {
+			continue
+		}
+		if latest == nil || latest.Spec.Version.LessThan(csv.Spec.Version) ||
+			(latest.Spec.Version.Equal(csv.Spec.Version) && latest.GetName() < csv.GetName()) {
+			latest = &csv
+		}
+	}
+
+	if latest == nil {
 		return nil, fmt.Errorf("not found: ClusterServiceVersion that replaces %s", name)
 	}
+	return latest, nil
+}
 
-	return m.FindCSVByName(csvMetadata[0].Name)
+// inSamePackage reports whether the named CSVs are in a channel of the same package
+func (m *InMem) inSamePackage(name, other string) bool {
+	for _, pc := range m.csvPackageChannels[name] {
+		for _, otherPC := range m.csvPackageChannels[other] {
+			if pc.packageRef.PackageName == otherPC.packageRef.PackageName {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // AllPackages returns all package manifests in the catalog
@@ -344,6 +423,15 @@ func (m *InMem) ListServices() ([]v1alpha1.ClusterServiceVersion, error) {
 	return services, nil
 }
 
+// ListCRDs lists all versions of the CRDs in the catalog
+func (m *InMem) ListCRDs() ([]v1beta1.CustomResourceDefinition, error) {
+	crds := []v1beta1.CustomResourceDefinition{}
+	for _, crd := range m.crds {
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
 // ListLatestCSVsForCRD lists the latests versions of the service that manages the given CRD.
 func (m *InMem) ListLatestCSVsForCRD(key CRDKey) ([]CSVAndChannelInfo, error) {
 	// Find the names of the CSVs that own the CRD.
@@ -210,6 +210,115 @@ func TestFindReplacementCSVForName(t *testing.T) {
 	compareResources(t, &testCSVResourceLatest, foundCSV)
 }
 
+func TestFindReplacementCSVForNameWithSkips(t *testing.T) {
+	withSkips := func(csv v1alpha1.ClusterServiceVersion, skips []string, skipRange string) v1alpha1.ClusterServiceVersion {
+		csv.Spec.Skips = skips
+		csv.Spec.SkipRange = skipRange
+		return csv
+	}
+
+	tests := []struct {
+		name     string
+		csvs     []v1alpha1.ClusterServiceVersion
+		packages []PackageManifest
+		replaced string
+		expected string
+		err      string
+	}{
+		{
+			name: "SkipsOnly",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				createCSV("etcd.v1", "1.0.0", "", nil),
+				withSkips(createCSV("etcd.v2", "2.0.0", "", nil), []string{"etcd.v1"}, ""),
+			},
+			replaced: "etcd.v1",
+			expected: "etcd.v2",
+		},
+		{
+			name: "SkipsPrunedCSV",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				withSkips(createCSV("etcd.v2", "2.0.0", "", nil), []string{"etcd.v1"}, ""),
+			},
+			replaced: "etcd.v1",
+			expected: "etcd.v2",
+		},
+		{
+			name: "SkipRangeOnly",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				createCSV("etcd.v1", "1.0.0", "", nil),
+				withSkips(createCSV("etcd.v2", "2.0.0", "", nil), nil, ">=1.0.0 <2.0.0"),
+			},
+			packages: []PackageManifest{{
+				PackageName: "etcd",
+				Channels: []PackageChannel{
+					{Name: "alpha", CurrentCSVName: "etcd.v2"},
+					{Name: "stable", CurrentCSVName: "etcd.v1"},
+				},
+			}},
+			replaced: "etcd.v1",
+			expected: "etcd.v2",
+		},
+		{
+			name: "SkipRangeIgnoresOtherPackages",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				createCSV("vault.v1", "1.0.0", "", nil),
+				withSkips(createCSV("etcd.v2", "2.0.0", "", nil), nil, ">=1.0.0 <2.0.0"),
+			},
+			packages: []PackageManifest{
+				{PackageName: "etcd", Channels: []PackageChannel{{Name: "alpha", CurrentCSVName: "etcd.v2"}}},
+				{PackageName: "vault", Channels: []PackageChannel{{Name: "alpha", CurrentCSVName: "vault.v1"}}},
+			},
+			replaced: "vault.v1",
+			err:      "not found: ClusterServiceVersion that replaces vault.v1",
+		},
+		{
+			name: "PrefersNewestReplacement",
+			csvs: []v1alpha1.ClusterServiceVersion{
+				createCSV("etcd.v1", "1.0.0", "", nil),
+				createCSV("etcd.v2", "2.0.0", "etcd.v1", nil),
+				withSkips(createCSV("etcd.v3", "3.0.0", "etcd.v2", nil), []string{"etcd.v1"}, ""),
+			},
+			replaced: "etcd.v1",
+			expected: "etcd.v3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := NewInMem()
+			for _, csv := range tt.csvs {
+				catalog.AddOrReplaceService(csv)
+			}
+			for _, pkg := range tt.packages {
+				assert.NoError(t, catalog.AddPackageManifest(pkg))
+			}
+
+			foundCSV, err := catalog.FindReplacementCSVForName(tt.replaced)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, foundCSV.GetName())
+		})
+	}
+}
+
+func TestAddPackageManifestFollowsSkips(t *testing.T) {
+	catalog := NewInMem()
+	v1 := createCSV("etcd.v1", "1.0.0", "", nil)
+	v2 := createCSV("etcd.v2", "2.0.0", "", nil)
+	v2.Spec.Skips = []string{"etcd.v1", "etcd.v1.5"}
+	catalog.AddOrReplaceService(v1)
+	catalog.AddOrReplaceService(v2)
+
+	assert.NoError(t, catalog.AddPackageManifest(PackageManifest{
+		PackageName: "etcd",
+		Channels:    []PackageChannel{{Name: "alpha", CurrentCSVName: "etcd.v2"}},
+	}))
+	assert.Len(t, catalog.csvPackageChannels["etcd.v1"], 1, "skipped CSV should belong to the package")
+	assert.NotContains(t, catalog.csvPackageChannels, "etcd.v1.5")
+}
+
 func TestFindReplacementCSVForPackageNameUnderChannel(t *testing.T) {
 	var (
 		testStableCSVName   = "mockservice-operator.v1.0.0"
@@ -301,6 +410,71 @@ func TestFindReplacementCSVForPackageNameUnderChannel(t *testing.T) {
 	assert.Equal(t, 3, len(found))
 }
 
+func TestFindReplacementCSVForPackageNameUnderChannelWithSkips(t *testing.T) {
+	var (
+		testCSVNameV1 = "mockservice-operator.v1.0.0"
+		testCSVNameV2 = "mockservice-operator.v1.1.0"
+		testCSVNameV3 = "mockservice-operator.v1.2.0"
+		testCSVNameV4 = "mockservice-operator.v2.0.0"
+		testPrunedCSV = "mockservice-operator.v0.9.0"
+
+		testOwnedCRDName = "mockserviceresource-v1.catalog.testing.coreos.com"
+	)
+
+	// v2.0.0 replaces v1.2.0 replaces v1.1.0 replaces v1.0.0
+	testCSVResourceV1 := createCSV(testCSVNameV1, "1.0.0", "", []string{testOwnedCRDName})
+	testCSVResourceV2 := createCSV(testCSVNameV2, "1.1.0", testCSVNameV1, []string{testOwnedCRDName})
+	testCSVResourceV3 := createCSV(testCSVNameV3, "1.2.0", testCSVNameV2, []string{testOwnedCRDName})
+	testCSVResourceV3.Spec.Skips = []string{testCSVNameV1, testPrunedCSV}
+	testCSVResourceV4 := createCSV(testCSVNameV4, "2.0.0", testCSVNameV3, []string{testOwnedCRDName})
+	testCSVResourceV4.Spec.SkipRange = ">=1.1.0 <1.2.0"
+
+	catalog := NewInMem()
+	catalog.setOrReplaceCRDDefinition(v1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: testOwnedCRDName,
+		},
+	})
+	catalog.AddOrReplaceService(testCSVResourceV1)
+	catalog.AddOrReplaceService(testCSVResourceV2)
+	catalog.AddOrReplaceService(testCSVResourceV3)
+	catalog.AddOrReplaceService(testCSVResourceV4)
+
+	err := catalog.AddPackageManifest(PackageManifest{
+		PackageName: "mockservice",
+		Channels: []PackageChannel{
+			{
+				Name:           "stable",
+				CurrentCSVName: testCSVNameV4,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// v1.0.0 -> v1.2.0 via skips
+	csv, err := catalog.FindReplacementCSVForPackageNameUnderChannel("mockservice", "stable", testCSVNameV1)
+	assert.NoError(t, err)
+	assert.Equal(t, testCSVNameV3, csv.GetName())
+
+	// v1.1.0 -> v2.0.0 via skipRange
+	csv, err = catalog.FindReplacementCSVForPackageNameUnderChannel("mockservice", "stable", testCSVNameV2)
+	assert.NoError(t, err)
+	assert.Equal(t, testCSVNameV4, csv.GetName())
+
+	// v1.2.0 -> v2.0.0 via replaces
+	csv, err = catalog.FindReplacementCSVForPackageNameUnderChannel("mockservice", "stable", testCSVNameV3)
+	assert.NoError(t, err)
+	assert.Equal(t, testCSVNameV4, csv.GetName())
+
+	// v0.9.0 is not in the catalog, but is skipped by name
+	csv, err = catalog.FindReplacementCSVForPackageNameUnderChannel("mockservice", "stable", testPrunedCSV)
+	assert.NoError(t, err)
+	assert.Equal(t, testCSVNameV3, csv.GetName())
+
+	_, err = catalog.FindReplacementCSVForPackageNameUnderChannel("mockservice", "stable", "mockservice-operator.v0.1.0")
+	assert.Error(t, err)
+}
+
 func TestListLatestCSVsForCRD(t *testing.T) {
 	var (
 		testStableCSVName   = "mockservice-operator.v1.0.0"
@@ -0,0 +1,367 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	olmerrors "github.com/operator-framework/operator-lifecycle-manager/pkg/controller/errors"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+)
+
+// maxResolutionAttempts bounds the number of candidate CSVs tried while resolving an InstallPlan
+const maxResolutionAttempts = 1000
+
+// maxResolutionTraceEntries bounds the size of the resolution trace stored in an InstallPlan's status
+const maxResolutionTraceEntries = 500
+
+// maxResolutionErrorReasons bounds the number of reasons summarized in a ResolutionError's message
+const maxResolutionErrorReasons = 5
+
+// ResolutionError is returned when no set of CSVs installs an InstallPlan. It carries the trace of the CSVs and CRD
+// requirements the resolver considered, and its message summarizes why the candidates were rejected.
+type ResolutionError struct {
+	CSVNames []string
+	Trace    []v1alpha1.ResolutionTraceEntry
+	Err      error
+}
+
+func (e *ResolutionError) Error() string {
+	var reasons []string
+	seen := map[string]struct{}{}
+	for _, entry := range e.Trace {
+		var reason string
+		switch entry.Result {
+		case v1alpha1.ResolutionTraceResultNotFound:
+			reason = fmt.Sprintf("CSV %s not found in catalogs %v", entry.CSV, entry.CatalogSources)
+		case v1alpha1.ResolutionTraceResultUnsatisfied:
+			reason = fmt.Sprintf("CRD %s required by %s: %s", entry.CRD, entry.RequiredBy, entry.Message)
+		case v1alpha1.ResolutionTraceResultRejected:
+			reason = fmt.Sprintf("CSV %s from %s rejected: %s", entry.CSV, strings.Join(entry.CatalogSources, ", "), entry.Message)
+			if entry.CRD != "" {
+				reason = fmt.Sprintf("CRD %s required by %s: %s", entry.CRD, entry.RequiredBy, reason)
+			}
+		default:
+			continue
+		}
+		if _, ok := seen[reason]; ok {
+			continue
+		}
+		seen[reason] = struct{}{}
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return fmt.Sprintf("unable to resolve %v: %s", e.CSVNames, e.Err)
+	}
+	if len(reasons) > maxResolutionErrorReasons {
+		reasons = append(reasons[:maxResolutionErrorReasons], fmt.Sprintf("and %d more", len(reasons)-maxResolutionErrorReasons))
+	}
+	return fmt.Sprintf("unable to resolve %v: %s", e.CSVNames, strings.Join(reasons, "; "))
+}
+
+// IsResolutionError returns true if err is a ResolutionError
+func IsResolutionError(err error) bool {
+	_, ok := err.(*ResolutionError)
+	return ok
+}
+
+// candidate is a CSV from a catalog that can be installed
+type candidate struct {
+	csv            *v1alpha1.ClusterServiceVersion
+	sourceKey      registry.ResourceKey
+	defaultChannel bool
+}
+
+// requirement is a CRD required by a selected CSV
+type requirement struct {
+	key        registry.CRDKey
+	requiredBy string
+}
+
+// solverState is a partial resolution: the CSVs selected so far, the CRDs they own, and the CRDs they require that
+// have yet to be satisfied
+type solverState struct {
+	selected []candidate
+	owners   map[string]string
+	pending  []requirement
+}
+
+// solver searches for the smallest set of new CSVs that installs the requested CSVs, such that every required CRD
+// is owned by exactly one CSV, either already in the namespace or in the set. CRDs owned by a CSV already in the
+// namespace are never resolved to a new CSV. Candidates for the other CRDs are every CSV listed by any catalog as
+// owning it, tried default channels first; when a candidate leads to conflicting ownership, the solver backtracks
+// and tries the next one. Among sets of the same size, the first one found in that order is kept.
+type solver struct {
+	sourceRefs        []registry.SourceRef
+	existingCRDOwners map[string][]string
+	namespace         string
+
+	providers map[registry.CRDKey][]candidate
+	attempts  int
+	best      []candidate
+	err       error
+	trace     []v1alpha1.ResolutionTraceEntry
+}
+
+func newSolver(sourceRefs []registry.SourceRef, existingCRDOwners map[string][]string, namespace string) *solver {
+	return &solver{
+		sourceRefs:        sourceRefs,
+		existingCRDOwners: existingCRDOwners,
+		namespace:         namespace,
+		providers:         map[registry.CRDKey][]candidate{},
+	}
+}
+
+// resolve returns the CSVs to install for the given CSV names, in the order they were selected
+func (s *solver) resolve(csvNames []string) ([]candidate, error) {
+	state := &solverState{owners: map[string]string{}}
+	for _, name := range csvNames {
+		c, err := s.findCSV(name)
+		if err != nil {
+			s.record(v1alpha1.ResolutionTraceEntry{CSV: name, CatalogSources: s.catalogNames(), Result: v1alpha1.ResolutionTraceResultNotFound, Message: err.Error()})
+			return nil, err
+		}
+		next, err := s.add(state, c, true)
+		if err != nil {
+			s.record(v1alpha1.ResolutionTraceEntry{Depth: len(state.selected), CSV: name, CatalogSources: []string{catalogName(c.sourceKey)}, Result: v1alpha1.ResolutionTraceResultRejected, Message: err.Error()})
+			return nil, err
+		}
+		s.record(v1alpha1.ResolutionTraceEntry{Depth: len(state.selected), CSV: name, CatalogSources: []string{catalogName(c.sourceKey)}, Result: v1alpha1.ResolutionTraceResultSelected})
+		state = next
+	}
+
+	s.search(state)
+	switch {
+	case s.best != nil:
+		if s.attempts >= maxResolutionAttempts {
+			log.Infof("stopped resolving %v after %d attempts, the resolution may not be minimal", csvNames, s.attempts)
+		}
+		return s.best, nil
+	case s.attempts >= maxResolutionAttempts:
+		return nil, fmt.Errorf("no resolution found for %v after %d attempts", csvNames, s.attempts)
+	default:
+		return nil, s.err
+	}
+}
+
+// search resolves the pending CRDs of a state, keeping the smallest complete resolution found
+func (s *solver) search(state *solverState) {
+	// Selecting more CSVs can't lead to a smaller resolution than the best one found.
+	if s.best != nil && len(state.selected) >= len(s.best) {
+		return
+	}
+
+	depth := len(state.selected)
+	pending := state.pending
+	for len(pending) > 0 {
+		owner, ok := state.owners[pending[0].key.Name]
+		if !ok {
+			break
+		}
+		s.record(v1alpha1.ResolutionTraceEntry{
+			Depth: depth, CRD: pending[0].key.Name, RequiredBy: pending[0].requiredBy,
+			Result: v1alpha1.ResolutionTraceResultSatisfied, Message: fmt.Sprintf("owned by selected CSV %s", owner),
+		})
+		pending = pending[1:]
+	}
+	if len(pending) == 0 {
+		s.best = state.selected
+		return
+	}
+
+	req, rest := pending[0], pending[1:]
+	entry := v1alpha1.ResolutionTraceEntry{Depth: depth, CRD: req.key.Name, RequiredBy: req.requiredBy}
+	switch owners := s.existingCRDOwners[req.key.Name]; {
+	case len(owners) == 1:
+		log.Debugf("%s is owned by existing CSV %s", req.key, owners[0])
+		entry.Result, entry.Message = v1alpha1.ResolutionTraceResultSatisfied, fmt.Sprintf("owned by existing CSV %s", owners[0])
+		s.record(entry)
+		s.search(&solverState{selected: state.selected, owners: state.owners, pending: rest})
+		return
+	case len(owners) > 1:
+		err := olmerrors.NewMultipleExistingCRDOwnersError(owners, req.key.Name, s.namespace)
+		entry.Result, entry.Message = v1alpha1.ResolutionTraceResultUnsatisfied, err.Error()
+		s.record(entry)
+		s.fail(err)
+		return
+	}
+
+	entry.CatalogSources = s.catalogNames()
+	candidates, err := s.candidates(req.key)
+	if err != nil {
+		entry.Result, entry.Message = v1alpha1.ResolutionTraceResultUnsatisfied, err.Error()
+		s.record(entry)
+		s.fail(err)
+		return
+	}
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.csv.GetName())
+	}
+	entry.Result, entry.Message = v1alpha1.ResolutionTraceResultSearched, fmt.Sprintf("candidates %v", names)
+	s.record(entry)
+
+	for _, c := range candidates {
+		if s.attempts >= maxResolutionAttempts {
+			return
+		}
+		s.attempts++
+
+		log.Debugf("trying %s as the owner of %s", c.csv.GetName(), req.key)
+		entry := v1alpha1.ResolutionTraceEntry{
+			Depth: depth, CSV: c.csv.GetName(), CRD: req.key.Name, RequiredBy: req.requiredBy,
+			CatalogSources: []string{catalogName(c.sourceKey)},
+		}
+		next, err := s.add(&solverState{selected: state.selected, owners: state.owners, pending: rest}, c, false)
+		if err != nil {
+			log.Debugf("can't select %s: %s", c.csv.GetName(), err)
+			entry.Result, entry.Message = v1alpha1.ResolutionTraceResultRejected, err.Error()
+			s.record(entry)
+			s.fail(err)
+			continue
+		}
+		entry.Result = v1alpha1.ResolutionTraceResultSelected
+		s.record(entry)
+		s.search(next)
+	}
+}
+
+// record adds an entry to the resolution trace. Entries past maxResolutionTraceEntries are dropped.
+func (s *solver) record(entry v1alpha1.ResolutionTraceEntry) {
+	if len(s.trace) < maxResolutionTraceEntries {
+		s.trace = append(s.trace, entry)
+	}
+}
+
+// fail records the reason a resolution was abandoned. The first reason is reported if no resolution is found, since
+// it's from the most preferred candidates.
+func (s *solver) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// add returns the state with a CSV selected. Requested CSVs may take over CRDs owned by CSVs already in the
+// namespace, which they are expected to replace, while their dependencies may not.
+func (s *solver) add(state *solverState, c candidate, requested bool) (*solverState, error) {
+	name := c.csv.GetName()
+	for _, selected := range state.selected {
+		if selected.csv.GetName() == name {
+			return state, nil
+		}
+	}
+
+	owners := make(map[string]string, len(state.owners))
+	for crd, owner := range state.owners {
+		owners[crd] = owner
+	}
+	for _, crdDesc := range c.csv.Spec.CustomResourceDefinitions.Owned {
+		if owner, ok := owners[crdDesc.Name]; ok && owner != name {
+			return nil, fmt.Errorf("CSVs %s and %s both own CRD %s", owner, name, crdDesc.Name)
+		}
+		if existing := s.existingCRDOwners[crdDesc.Name]; !requested && len(existing) > 0 && (len(existing) > 1 || existing[0] != name) {
+			return nil, fmt.Errorf("CRD %s owned by %s is already owned by %v in namespace %s", crdDesc.Name, name, existing, s.namespace)
+		}
+		if _, _, err := s.findCRD(crdKey(crdDesc)); err != nil {
+			return nil, err
+		}
+		owners[crdDesc.Name] = name
+	}
+
+	pending := append([]requirement(nil), state.pending...)
+	for _, crdDesc := range c.csv.Spec.CustomResourceDefinitions.Required {
+		pending = append(pending, requirement{key: crdKey(crdDesc), requiredBy: name})
+	}
+
+	return &solverState{
+		selected: append(append([]candidate(nil), state.selected...), c),
+		owners:   owners,
+		pending:  pending,
+	}, nil
+}
+
+// candidates returns every CSV that any catalog lists as owning a CRD, with CSVs in default channels first
+func (s *solver) candidates(key registry.CRDKey) ([]candidate, error) {
+	if candidates, ok := s.providers[key]; ok {
+		return candidates, nil
+	}
+
+	var candidates []candidate
+	var listErr error
+	indexes := map[string]int{}
+	for _, ref := range s.sourceRefs {
+		csvs, err := ref.Source.ListLatestCSVsForCRD(key)
+		if err != nil {
+			if listErr == nil {
+				listErr = err
+			}
+			continue
+		}
+		for _, info := range csvs {
+			// A CSV can be listed once per channel it's in, and by several catalogs. The first catalog is used.
+			if i, ok := indexes[info.CSV.GetName()]; ok {
+				candidates[i].defaultChannel = candidates[i].defaultChannel || info.IsDefaultChannel
+				continue
+			}
+			indexes[info.CSV.GetName()] = len(candidates)
+			candidates = append(candidates, candidate{csv: info.CSV, sourceKey: ref.SourceKey, defaultChannel: info.IsDefaultChannel})
+		}
+	}
+	if len(candidates) == 0 {
+		if listErr == nil {
+			listErr = fmt.Errorf("Unknown CRD %s", key)
+		}
+		return nil, listErr
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].defaultChannel && !candidates[j].defaultChannel
+	})
+	s.providers[key] = candidates
+	return candidates, nil
+}
+
+// findCSV returns a CSV from the first catalog that has it
+func (s *solver) findCSV(name string) (candidate, error) {
+	err := fmt.Errorf("not found: ClusterServiceVersion %s", name)
+	for _, ref := range s.sourceRefs {
+		var csv *v1alpha1.ClusterServiceVersion
+		if csv, err = ref.Source.FindCSVByName(name); err == nil {
+			return candidate{csv: csv, sourceKey: ref.SourceKey}, nil
+		}
+	}
+	return candidate{}, err
+}
+
+// findCRD returns a CRD from the first catalog that has it
+func (s *solver) findCRD(key registry.CRDKey) (*v1beta1.CustomResourceDefinition, registry.ResourceKey, error) {
+	err := fmt.Errorf("not found: CRD %s", key)
+	for _, ref := range s.sourceRefs {
+		var crd *v1beta1.CustomResourceDefinition
+		if crd, err = ref.Source.FindCRDByKey(key); err == nil {
+			return crd, ref.SourceKey, nil
+		}
+	}
+	return nil, registry.ResourceKey{}, err
+}
+
+// catalogNames returns the names of the catalogs searched, in order
+func (s *solver) catalogNames() []string {
+	names := make([]string, 0, len(s.sourceRefs))
+	for _, ref := range s.sourceRefs {
+		names = append(names, catalogName(ref.SourceKey))
+	}
+	return names
+}
+
+func catalogName(key registry.ResourceKey) string {
+	return key.Namespace + "/" + key.Name
+}
+
+func crdKey(crdDesc v1alpha1.CRDDescription) registry.CRDKey {
+	return registry.CRDKey{Kind: crdDesc.Kind, Name: crdDesc.Name, Version: crdDesc.Version}
+}
@@ -0,0 +1,268 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	olmerrors "github.com/operator-framework/operator-lifecycle-manager/pkg/controller/errors"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
+)
+
+func TestSolverResolve(t *testing.T) {
+	type csvName struct {
+		name     string
+		owned    []string
+		required []string
+	}
+	namespace := "default"
+	cheesePackage := registry.PackageManifest{
+		PackageName: "cheese",
+		Channels: []registry.PackageChannel{
+			{Name: "alpha", CurrentCSVName: "cheese-alpha"},
+			{Name: "stable", CurrentCSVName: "cheese-stable"},
+		},
+		DefaultChannelName: "stable",
+	}
+
+	var table = []struct {
+		description       string
+		csvs              [][]csvName
+		existingCRDOwners map[string][]string
+		expected          []string
+		expectedErr       error
+	}{
+		{
+			description: "DefaultChannel",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese"}, nil},
+			}},
+			expected: []string{"macaroni", "cheese-stable"},
+		},
+		{
+			description: "InstalledOwner",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese"}, nil},
+			}},
+			existingCRDOwners: map[string][]string{"cheese": {"cheese-alpha"}},
+			expected:          []string{"macaroni"},
+		},
+		{
+			description: "ConflictingOwnerBacktracks",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni", "pasta"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese", "pasta"}, nil},
+			}},
+			expected: []string{"macaroni", "cheese-alpha"},
+		},
+		{
+			description: "InstalledConflictBacktracks",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese", "milk"}, nil},
+			}},
+			existingCRDOwners: map[string][]string{"milk": {"milk-v1"}},
+			expected:          []string{"macaroni", "cheese-alpha"},
+		},
+		{
+			description: "DeepConflictBacktracks",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, []string{"salt"}},
+				{"cheese-stable", []string{"cheese"}, []string{"milk"}},
+				{"milk-v1", []string{"milk", "macaroni"}, nil},
+				{"salt-v1", []string{"salt"}, nil},
+			}},
+			expected: []string{"macaroni", "cheese-alpha", "salt-v1"},
+		},
+		{
+			description: "NoResolution",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, []string{"milk"}},
+				{"cheese-stable", []string{"cheese"}, []string{"milk"}},
+				{"milk-v1", []string{"milk", "macaroni"}, nil},
+			}},
+			expectedErr: errors.New("CSVs macaroni and milk-v1 both own CRD macaroni"),
+		},
+		{
+			description: "MinimalSet",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese"}, []string{"milk"}},
+				{"milk-v1", []string{"milk"}, nil},
+			}},
+			expected: []string{"macaroni", "cheese-alpha"},
+		},
+		{
+			description: "SharedDependency",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese", "milk"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese", "milk"}, nil},
+				{"milk-v1", []string{"milk"}, nil},
+			}},
+			expected: []string{"macaroni", "cheese-stable"},
+		},
+		{
+			description: "OwnerInAnotherCatalog",
+			csvs: [][]csvName{
+				{{"macaroni", []string{"macaroni"}, []string{"cheese"}}},
+				{{"cheese-alpha", []string{"cheese"}, nil}, {"cheese-stable", []string{"cheese"}, nil}},
+			},
+			expected: []string{"macaroni", "cheese-stable"},
+		},
+		{
+			description: "MissingOwner",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+			}},
+			expectedErr: errors.New("not found: CRD cheese/cheese/v1"),
+		},
+		{
+			description: "MultipleInstalledOwners",
+			csvs: [][]csvName{{
+				{"macaroni", []string{"macaroni"}, []string{"cheese"}},
+				{"cheese-alpha", []string{"cheese"}, nil},
+				{"cheese-stable", []string{"cheese"}, nil},
+			}},
+			existingCRDOwners: map[string][]string{"cheese": {"cheese-alpha", "cheese-beta"}},
+			expectedErr:       olmerrors.NewMultipleExistingCRDOwnersError([]string{"cheese-alpha", "cheese-beta"}, "cheese", namespace),
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.description, func(t *testing.T) {
+			var srcRefs []registry.SourceRef
+			for i, csvs := range tt.csvs {
+				source := registry.NewInMem()
+				crds := map[string]struct{}{}
+				hasCheese := false
+				for _, c := range csvs {
+					source.AddOrReplaceService(csv(c.name, namespace, c.owned, c.required, installStrategy("deployment", nil, nil)))
+					for _, name := range c.owned {
+						crds[name] = struct{}{}
+					}
+					hasCheese = hasCheese || c.name == "cheese-stable"
+				}
+				for name := range crds {
+					require.NoError(t, source.SetCRDDefinition(crd(name, namespace)))
+				}
+				if hasCheese {
+					require.NoError(t, source.AddPackageManifest(cheesePackage))
+				}
+
+				srcRefs = append(srcRefs, registry.SourceRef{
+					SourceKey: registry.ResourceKey{Name: string('a' + rune(i)), Namespace: namespace},
+					Source:    source,
+				})
+			}
+
+			selected, err := newSolver(srcRefs, tt.existingCRDOwners, namespace).resolve([]string{"macaroni"})
+			if tt.expectedErr != nil {
+				require.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, c := range selected {
+				names = append(names, c.csv.GetName())
+			}
+			require.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestSolverResolveTrace(t *testing.T) {
+	namespace := "default"
+	source := registry.NewInMem()
+	source.AddOrReplaceService(csv("macaroni", namespace, []string{"macaroni"}, []string{"cheese"}, installStrategy("deployment", nil, nil)))
+	source.AddOrReplaceService(csv("cheese-alpha", namespace, []string{"cheese"}, []string{"milk"}, installStrategy("deployment", nil, nil)))
+	source.AddOrReplaceService(csv("milk-v1", namespace, []string{"milk", "macaroni"}, nil, installStrategy("deployment", nil, nil)))
+	for _, name := range []string{"macaroni", "cheese", "milk"} {
+		require.NoError(t, source.SetCRDDefinition(crd(name, namespace)))
+	}
+	srcRefs := []registry.SourceRef{{SourceKey: registry.ResourceKey{Name: "a", Namespace: namespace}, Source: source}}
+
+	s := newSolver(srcRefs, nil, namespace)
+	_, err := s.resolve([]string{"macaroni"})
+	require.EqualError(t, err, "CSVs macaroni and milk-v1 both own CRD macaroni")
+
+	catalogs := []string{"default/a"}
+	require.Equal(t, []v1alpha1.ResolutionTraceEntry{
+		{Depth: 0, CSV: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultSelected},
+		{Depth: 1, CRD: "cheese", RequiredBy: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultSearched, Message: "candidates [cheese-alpha]"},
+		{Depth: 1, CSV: "cheese-alpha", CRD: "cheese", RequiredBy: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultSelected},
+		{Depth: 2, CRD: "milk", RequiredBy: "cheese-alpha", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultSearched, Message: "candidates [milk-v1]"},
+		{Depth: 2, CSV: "milk-v1", CRD: "milk", RequiredBy: "cheese-alpha", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultRejected, Message: "CSVs macaroni and milk-v1 both own CRD macaroni"},
+	}, s.trace)
+}
+
+func TestResolutionErrorMessage(t *testing.T) {
+	catalogs := []string{"default/a", "default/b"}
+	rejected := func(csv string) v1alpha1.ResolutionTraceEntry {
+		return v1alpha1.ResolutionTraceEntry{CSV: csv, CRD: "cheese", RequiredBy: "macaroni", CatalogSources: catalogs[:1], Result: v1alpha1.ResolutionTraceResultRejected, Message: "conflict"}
+	}
+
+	tests := []struct {
+		description string
+		trace       []v1alpha1.ResolutionTraceEntry
+		expected    string
+	}{
+		{
+			description: "NoTrace",
+			expected:    "unable to resolve [macaroni]: not found",
+		},
+		{
+			description: "NotFound",
+			trace: []v1alpha1.ResolutionTraceEntry{
+				{CSV: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultNotFound, Message: "not found"},
+			},
+			expected: "unable to resolve [macaroni]: CSV macaroni not found in catalogs [default/a default/b]",
+		},
+		{
+			description: "RejectedAndUnsatisfied",
+			trace: []v1alpha1.ResolutionTraceEntry{
+				{CSV: "macaroni", CatalogSources: catalogs[:1], Result: v1alpha1.ResolutionTraceResultSelected},
+				{CRD: "cheese", RequiredBy: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultSearched},
+				rejected("cheese-alpha"),
+				{CRD: "milk", RequiredBy: "macaroni", CatalogSources: catalogs, Result: v1alpha1.ResolutionTraceResultUnsatisfied, Message: "Unknown CRD milk"},
+			},
+			expected: "unable to resolve [macaroni]: CRD cheese required by macaroni: CSV cheese-alpha from default/a rejected: conflict; " +
+				"CRD milk required by macaroni: Unknown CRD milk",
+		},
+		{
+			description: "Deduplicated",
+			trace:       []v1alpha1.ResolutionTraceEntry{rejected("cheese-alpha"), rejected("cheese-alpha")},
+			expected:    "unable to resolve [macaroni]: CRD cheese required by macaroni: CSV cheese-alpha from default/a rejected: conflict",
+		},
+		{
+			description: "Truncated",
+			trace: []v1alpha1.ResolutionTraceEntry{
+				rejected("cheese-1"), rejected("cheese-2"), rejected("cheese-3"), rejected("cheese-4"), rejected("cheese-5"),
+				rejected("cheese-6"), rejected("cheese-7"),
+			},
+			expected: "unable to resolve [macaroni]: CRD cheese required by macaroni: CSV cheese-1 from default/a rejected: conflict; " +
+				"CRD cheese required by macaroni: CSV cheese-2 from default/a rejected: conflict; " +
+				"CRD cheese required by macaroni: CSV cheese-3 from default/a rejected: conflict; " +
+				"CRD cheese required by macaroni: CSV cheese-4 from default/a rejected: conflict; " +
+				"CRD cheese required by macaroni: CSV cheese-5 from default/a rejected: conflict; and 2 more",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := &ResolutionError{CSVNames: []string{"macaroni"}, Trace: tt.trace, Err: errors.New("not found")}
+			require.Equal(t, tt.expected, err.Error())
+		})
+	}
+}
@@ -92,7 +92,8 @@ func resolveInstallPlan(t *testing.T, resolver DependencyResolver) {
 			if tt.expectedErr == nil {
 				require.Nil(t, err)
 			} else {
-				require.Equal(t, tt.expectedErr, err)
+				require.IsType(t, &ResolutionError{}, err)
+				require.Equal(t, tt.expectedErr, err.(*ResolutionError).Err)
 			}
 
 			// Assert the number of items in the plan are equal
@@ -179,8 +180,14 @@ func multiSourceResolveInstallPlan(t *testing.T, resolver DependencyResolver) {
 			},
 			[]crdName{{"CRD", sourceC}},
 			[]registry.ResourceKey{sourceA, sourceB, sourceC},
-			errors.New("not found: CRD CRD/CRD/v1"),
 			nil,
+			map[resourceKey]registry.ResourceKey{
+				resourceKey{"main", csvKind}:              sourceA,
+				resourceKey{"crdOwner", csvKind}:          sourceB,
+				resourceKey{"CRD", crdKind}:               sourceC,
+				resourceKey{"edit-CRD-v1", "ClusterRole"}: sourceC,
+				resourceKey{"view-CRD-v1", "ClusterRole"}: sourceC,
+			},
 		},
 		{
 			"MultipleTransitiveDependenciesInDifferentCatalogs",
@@ -262,7 +269,8 @@ func multiSourceResolveInstallPlan(t *testing.T, resolver DependencyResolver) {
 			if tt.expectedErr == nil {
 				require.Nil(t, err)
 			} else {
-				require.Equal(t, tt.expectedErr, err)
+				require.IsType(t, &ResolutionError{}, err)
+				require.Equal(t, tt.expectedErr, err.(*ResolutionError).Err)
 			}
 
 			require.Equal(t, len(tt.expectedResources), len(plan.Status.Plan))
@@ -397,15 +405,12 @@ func namespaceAndChannelAwareResolveInstallPlan(t *testing.T, resolver Dependenc
 				"cheese": {"cheese-alpha"},
 			},
 			nil,
+			// The existing owner provides cheese, so it isn't installed again.
 			map[registry.ResourceKey]struct{}{
 				registry.ResourceKey{Name: "macaroni-stable", Kind: csvKind}:        {},
 				registry.ResourceKey{Name: "macaroni", Kind: crdKind}:               {},
 				registry.ResourceKey{Name: "edit-macaroni-v1", Kind: "ClusterRole"}: {},
 				registry.ResourceKey{Name: "view-macaroni-v1", Kind: "ClusterRole"}: {},
-				registry.ResourceKey{Name: "cheese-alpha", Kind: csvKind}:           {},
-				registry.ResourceKey{Name: "cheese", Kind: crdKind}:                 {},
-				registry.ResourceKey{Name: "edit-cheese-v1", Kind: "ClusterRole"}:   {},
-				registry.ResourceKey{Name: "view-cheese-v1", Kind: "ClusterRole"}:   {},
 			},
 		},
 		{
@@ -503,7 +508,8 @@ func namespaceAndChannelAwareResolveInstallPlan(t *testing.T, resolver Dependenc
 			if tt.expectedErr == nil {
 				require.Nil(t, err)
 			} else {
-				require.Equal(t, tt.expectedErr, err)
+				require.IsType(t, &ResolutionError{}, err)
+				require.Equal(t, tt.expectedErr, err.(*ResolutionError).Err)
 			}
 
 			require.Equal(t, len(tt.expectedResources), len(plan.Status.Plan))
@@ -519,8 +525,8 @@ func namespaceAndChannelAwareResolveInstallPlan(t *testing.T, resolver Dependenc
 
 }
 
-func TestMultiSourceResolveInstallPlan(t *testing.T) {
-	resolver := &MultiSourceResolver{}
+func TestConstraintResolveInstallPlan(t *testing.T) {
+	resolver := &ConstraintResolver{}
 
 	// Test single catalog source resolution
 	resolveInstallPlan(t, resolver)
@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "* * * * *"},
+		{expr: "0 2 * * 6"},
+		{expr: "*/15 1-5 1,15 */2 mon", err: `invalid cron expression "*/15 1-5 1,15 */2 mon": invalid day of week "mon"`},
+		{expr: "0 0 * *", err: `invalid cron expression "0 0 * *": expected 5 fields, found 4`},
+		{expr: "60 0 * * *", err: `invalid cron expression "60 0 * * *": minute 60 out of range [0, 59]`},
+		{expr: "0 5-1 * * *", err: `invalid cron expression "0 5-1 * * *": invalid range in hour "5-1"`},
+		{expr: "*/0 * * * *", err: `invalid cron expression "*/0 * * * *": invalid step in minute "*/0"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expr, s.String())
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Friday
+	from := time.Date(2018, time.January, 26, 20, 40, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{expr: "* * * * *", from: from, expected: time.Date(2018, time.January, 26, 20, 41, 0, 0, time.UTC)},
+		{expr: "40 20 * * *", from: from, expected: time.Date(2018, time.January, 27, 20, 40, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", from: from, expected: time.Date(2018, time.January, 26, 20, 45, 0, 0, time.UTC)},
+		{expr: "0 2 * * 6", from: from, expected: time.Date(2018, time.January, 27, 2, 0, 0, 0, time.UTC)},
+		{expr: "0 2 * * 7", from: from, expected: time.Date(2018, time.January, 28, 2, 0, 0, 0, time.UTC)},
+		{expr: "30 4 1 * *", from: from, expected: time.Date(2018, time.February, 1, 4, 30, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", from: from, expected: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week when both are restricted
+		{expr: "0 0 1 * 1", from: from, expected: time.Date(2018, time.January, 29, 0, 0, 0, 0, time.UTC)},
+		// a step over `*` isn't a restriction, so both fields must match
+		{expr: "0 0 1 * */2", from: from, expected: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 */2 * 1", from: from, expected: time.Date(2018, time.January, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 31 2 *", from: from, expected: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, s.Next(tt.from))
+		})
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*60*60)
+	s, err := Parse("0 2 * * *")
+	require.NoError(t, err)
+
+	next := s.Next(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2018, time.January, 27, 2, 0, 0, 0, time.UTC), next)
+
+	next = s.Next(time.Date(2018, time.January, 26, 20, 40, 0, 0, time.UTC).In(loc))
+	require.Equal(t, time.Date(2018, time.January, 28, 2, 0, 0, 0, loc), next)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ghodss/yaml"
 
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/semverrange"
 )
 
 // Files is a map of files.
@@ -33,7 +34,8 @@ func Glob(pattern string) (Files, error) {
 	return matching, nil
 }
 
-// CheckUpgradePath checks that every ClusterServiceVersion in a package directory has a valid `spec.replaces` field.
+// CheckUpgradePath checks that every ClusterServiceVersion in a package directory has a valid `spec.replaces` field,
+// and that its `spec.skips` and `spec.skipRange` fields never skip the ClusterServiceVersion itself.
 func CheckUpgradePath(packageDir string) error {
 	replaces := map[string]string{}
 	csvFiles, err := Glob(filepath.Join(packageDir, "**.clusterserviceversion.yaml"))
@@ -52,6 +54,10 @@ func CheckUpgradePath(packageDir string) error {
 			return err
 		}
 		replaces[csv.ObjectMeta.Name] = csv.Spec.Replaces
+
+		if err := checkSkips(csv); err != nil {
+			return err
+		}
 	}
 
 	for replacing, replaced := range replaces {
@@ -64,3 +70,30 @@ func CheckUpgradePath(packageDir string) error {
 	}
 	return nil
 }
+
+// checkSkips checks that a ClusterServiceVersion's `spec.skips` and `spec.skipRange` are valid. Skipped
+// ClusterServiceVersions need not exist in the package, since skipping allows them to be pruned.
+func checkSkips(csv v1alpha1.ClusterServiceVersion) error {
+	name := csv.GetName()
+	for _, skipped := range csv.Spec.Skips {
+		if skipped == "" {
+			return fmt.Errorf("%s has an empty entry in skips", name)
+		}
+		if skipped == name {
+			return fmt.Errorf("%s should not skip itself", name)
+		}
+	}
+
+	if csv.Spec.SkipRange == "" {
+		return nil
+	}
+
+	skipRange, err := semverrange.Parse(csv.Spec.SkipRange)
+	if err != nil {
+		return fmt.Errorf("%s has an invalid skipRange: %v", name, err)
+	}
+	if skipRange.Contains(csv.Spec.Version) {
+		return fmt.Errorf("%s has skipRange %s, which contains its own version %s", name, csv.Spec.SkipRange, csv.Spec.Version.String())
+	}
+	return nil
+}
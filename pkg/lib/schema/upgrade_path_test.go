@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+)
+
+func TestCheckUpgradePath(t *testing.T) {
+	type csvSpec struct {
+		name      string
+		version   string
+		replaces  string
+		skips     []string
+		skipRange string
+	}
+
+	tests := []struct {
+		name string
+		csvs []csvSpec
+		err  string
+	}{
+		{
+			name: "ValidSkips",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0"},
+				{name: "etcd.v2", version: "2.0.0", replaces: "etcd.v1"},
+				{name: "etcd.v3", version: "3.0.0", replaces: "etcd.v2", skips: []string{"etcd.v1"}},
+			},
+		},
+		{
+			name: "ValidSkipRange",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0"},
+				{name: "etcd.v3", version: "3.0.0", replaces: "etcd.v1", skipRange: ">=1.0.0 <3.0.0"},
+			},
+		},
+		{
+			// Skipped CSVs may have been pruned from the package
+			name: "SkipsMissingCSV",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0"},
+				{name: "etcd.v3", version: "3.0.0", replaces: "etcd.v1", skips: []string{"etcd.v2"}},
+			},
+		},
+		{
+			name: "ReplacesMissingCSV",
+			csvs: []csvSpec{
+				{name: "etcd.v3", version: "3.0.0", replaces: "etcd.v2"},
+			},
+			err: "etcd.v3 should replace etcd.v2, which does not exist",
+		},
+		{
+			name: "EmptySkipsEntry",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0", skips: []string{""}},
+			},
+			err: "etcd.v1 has an empty entry in skips",
+		},
+		{
+			name: "SkipsItself",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0", skips: []string{"etcd.v1"}},
+			},
+			err: "etcd.v1 should not skip itself",
+		},
+		{
+			name: "InvalidSkipRange",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0", skipRange: ">=one"},
+			},
+			err: `etcd.v1 has an invalid skipRange: invalid range ">=one": one is not in dotted-tri format`,
+		},
+		{
+			name: "SkipRangeContainsItself",
+			csvs: []csvSpec{
+				{name: "etcd.v1", version: "1.0.0", skipRange: "<2.0.0"},
+			},
+			err: "etcd.v1 has skipRange <2.0.0, which contains its own version 1.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "upgrade-path-")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			for _, c := range tt.csvs {
+				csv := v1alpha1.ClusterServiceVersion{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       v1alpha1.ClusterServiceVersionKind,
+						APIVersion: v1alpha1.ClusterServiceVersionAPIVersion,
+					},
+					ObjectMeta: metav1.ObjectMeta{Name: c.name},
+					Spec: v1alpha1.ClusterServiceVersionSpec{
+						Version:   *semver.New(c.version),
+						Replaces:  c.replaces,
+						Skips:     c.skips,
+						SkipRange: c.skipRange,
+					},
+				}
+				manifest, err := yaml.Marshal(csv)
+				require.NoError(t, err)
+				require.NoError(t, ioutil.WriteFile(filepath.Join(dir, c.name+".clusterserviceversion.yaml"), manifest, 0644))
+			}
+
+			err = CheckUpgradePath(dir)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
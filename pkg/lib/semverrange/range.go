@@ -0,0 +1,120 @@
+// Package semverrange parses and evaluates semver version ranges such as `>=1.2.0 <2.0.0`.
+//
+// A range is a set of comparators separated by whitespace, all of which must match. Multiple ranges may be joined
+// with `||`, in which case any of them may match. Supported operators are `=`, `!=`, `>`, `>=`, `<` and `<=`; a
+// version without an operator must match exactly.
+package semverrange
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+type operator string
+
+const (
+	opEQ  operator = "="
+	opNEQ operator = "!="
+	opGT  operator = ">"
+	opGTE operator = ">="
+	opLT  operator = "<"
+	opLTE operator = "<="
+)
+
+// operators are ordered so that two character operators are matched before their one character prefixes
+var operators = []operator{opGTE, opLTE, opNEQ, opGT, opLT, opEQ}
+
+type comparator struct {
+	op      operator
+	version semver.Version
+}
+
+func (c comparator) matches(v semver.Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case opEQ:
+		return cmp == 0
+	case opNEQ:
+		return cmp != 0
+	case opGT:
+		return cmp > 0
+	case opGTE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLTE:
+		return cmp <= 0
+	}
+	return false
+}
+
+// Range is a parsed semver range
+type Range struct {
+	raw string
+
+	// alternatives are OR'ed together, the comparators within each are AND'ed together
+	alternatives [][]comparator
+}
+
+// Parse parses a semver range
+func Parse(s string) (*Range, error) {
+	r := &Range{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid range %q: empty comparator set", s)
+		}
+
+		comparators := make([]comparator, 0, len(fields))
+		for _, field := range fields {
+			c, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", s, err)
+			}
+			comparators = append(comparators, c)
+		}
+		r.alternatives = append(r.alternatives, comparators)
+	}
+	return r, nil
+}
+
+func parseComparator(s string) (comparator, error) {
+	c := comparator{op: opEQ}
+	for _, op := range operators {
+		if strings.HasPrefix(s, string(op)) {
+			c.op = op
+			s = strings.TrimPrefix(s, string(op))
+			break
+		}
+	}
+
+	v, err := semver.NewVersion(strings.TrimPrefix(s, "v"))
+	if err != nil {
+		return c, err
+	}
+	c.version = *v
+	return c, nil
+}
+
+// Contains returns true if the given version is within the range
+func (r *Range) Contains(v semver.Version) bool {
+	for _, comparators := range r.alternatives {
+		matches := true
+		for _, c := range comparators {
+			if !c.matches(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Range) String() string {
+	return r.raw
+}
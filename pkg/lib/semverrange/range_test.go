@@ -0,0 +1,67 @@
+package semverrange
+
+import (
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{in: "1.0.0"},
+		{in: ">=1.2.0 <2.0.0"},
+		{in: "<1.0.0 || >=2.0.0"},
+		{in: "!=1.0.1"},
+		{in: ">=v1.0.0"},
+		{in: "", err: true},
+		{in: ">=1.0.0 ||", err: true},
+		{in: ">=1.0", err: true},
+		{in: "~1.0.0", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := Parse(tt.in)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.in, r.String())
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		r        string
+		v        string
+		contains bool
+	}{
+		{r: "1.0.0", v: "1.0.0", contains: true},
+		{r: "1.0.0", v: "1.0.1", contains: false},
+		{r: "=1.0.0", v: "1.0.0", contains: true},
+		{r: "!=1.0.0", v: "1.0.0", contains: false},
+		{r: "!=1.0.0", v: "1.0.1", contains: true},
+		{r: ">=1.2.0 <2.0.0", v: "1.2.0", contains: true},
+		{r: ">=1.2.0 <2.0.0", v: "1.9.9", contains: true},
+		{r: ">=1.2.0 <2.0.0", v: "1.1.9", contains: false},
+		{r: ">=1.2.0 <2.0.0", v: "2.0.0", contains: false},
+		{r: ">=1.2.0 <2.0.0", v: "2.0.0-alpha", contains: true},
+		{r: ">1.0.0 <=1.5.0", v: "1.0.0", contains: false},
+		{r: ">1.0.0 <=1.5.0", v: "1.5.0", contains: true},
+		{r: "<1.0.0 || >=2.0.0", v: "0.9.0", contains: true},
+		{r: "<1.0.0 || >=2.0.0", v: "1.5.0", contains: false},
+		{r: "<1.0.0 || >=2.0.0", v: "2.1.0", contains: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.r+"/"+tt.v, func(t *testing.T) {
+			r, err := Parse(tt.r)
+			require.NoError(t, err)
+			require.Equal(t, tt.contains, r.Contains(*semver.New(tt.v)))
+		})
+	}
+}
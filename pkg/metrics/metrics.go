@@ -129,6 +129,14 @@ var (
 			Help: "Monotonic count of catalog sources",
 		},
 	)
+
+	// exported since it's not handled by HandleMetrics
+	InstallPlanPrunedCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "install_plan_pruned_count",
+			Help: "Monotonic count of Complete install plans deleted past their subscription's history limit",
+		},
+	)
 )
 
 func Register() {
@@ -137,4 +145,5 @@ func Register() {
 	prometheus.MustRegister(subscriptionCount)
 	prometheus.MustRegister(catalogSourceCount)
 	prometheus.MustRegister(CSVUpgradeCount)
+	prometheus.MustRegister(InstallPlanPrunedCount)
 }
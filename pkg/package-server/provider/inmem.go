@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"k8s.io/client-go/util/workqueue"
 
 	operatorsv1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/queueinformer"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/metrics"
 	packagev1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/package-server/apis/packagemanifest/v1alpha1"
@@ -32,6 +35,13 @@ type packageKey struct {
 	packageName            string
 }
 
+// loadedArchive identifies the archive an http catalog source's packages were loaded from
+type loadedArchive struct {
+	url    string
+	etag   string
+	digest string
+}
+
 type eventChan struct {
 	namespace string
 	ch        chan packagev1alpha1.PackageManifest
@@ -44,6 +54,15 @@ type InMemoryProvider struct {
 	mu              sync.RWMutex
 	globalNamespace string
 
+	catsrcInformers []cache.SharedIndexInformer
+	catsrcQueue     workqueue.RateLimitingInterface
+
+	// registries holds the connections to the registries of grpc catalog sources, which are reused across syncs.
+	// archives records the last archive loaded for each http catalog source, so that unchanged archives are skipped.
+	registries  map[registry.ResourceKey]*registry.GRPCSource
+	archives    map[registry.ResourceKey]loadedArchive
+	sourcesLock sync.Mutex
+
 	manifests map[packageKey]packagev1alpha1.PackageManifest
 
 	add    []eventChan
@@ -51,20 +70,24 @@ type InMemoryProvider struct {
 	delete []eventChan
 }
 
-// NewInMemoryProvider returns a pointer to a new InMemoryProvider instance
-func NewInMemoryProvider(informers []cache.SharedIndexInformer, queueOperator *queueinformer.Operator, globalNS string) *InMemoryProvider {
+// NewInMemoryProvider returns a pointer to a new InMemoryProvider instance. ConfigMap informers let the provider
+// reload catalog sources as soon as their ConfigMap changes.
+func NewInMemoryProvider(informers []cache.SharedIndexInformer, configMapInformers []cache.SharedIndexInformer, queueOperator *queueinformer.Operator, globalNS string) *InMemoryProvider {
 	prov := &InMemoryProvider{
 		Operator:        queueOperator,
 		globalNamespace: globalNS,
+		catsrcInformers: informers,
+		catsrcQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources"),
 		manifests:       make(map[packageKey]packagev1alpha1.PackageManifest),
+		registries:      make(map[registry.ResourceKey]*registry.GRPCSource),
+		archives:        make(map[registry.ResourceKey]loadedArchive),
 	}
 
-	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources")
 	queueInformers := queueinformer.New(
-		queue,
+		prov.catsrcQueue,
 		informers,
 		prov.syncCatalogSource,
-		nil,
+		prov.catalogSourceEventHandlers(prov.catsrcQueue),
 		"catsrc",
 		metrics.NewMetricsNil(),
 	)
@@ -72,6 +95,19 @@ func NewInMemoryProvider(informers []cache.SharedIndexInformer, queueOperator *q
 		prov.RegisterQueueInformer(informer)
 	}
 
+	configMapQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmaps")
+	configMapQueueInformers := queueinformer.New(
+		configMapQueue,
+		configMapInformers,
+		prov.syncConfigMap,
+		prov.configMapEventHandlers(configMapQueue),
+		"configmap",
+		metrics.NewMetricsNil(),
+	)
+	for _, informer := range configMapQueueInformers {
+		prov.RegisterQueueInformer(informer)
+	}
+
 	return prov
 }
 
@@ -127,59 +163,320 @@ func parsePackageManifestsFromConfigMap(cm *corev1.ConfigMap, catalogSourceName,
 			return nil, fmt.Errorf("error parsing package list (json) from ConfigMap %s: %s", cmName, err)
 		}
 
-		for _, status := range parsedStatuses {
+		if len(parsedStatuses) > 0 {
 			found = true
+		}
+		manifests, err = newPackageManifests(parsedStatuses, csvs, cm.GetNamespace(), catalogSourceName, catalogSourceNamespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !found {
+		logger.Debug("ERROR: No valid resource found")
+		return nil, fmt.Errorf("error parsing ConfigMap %s: no valid resources found", cmName)
+	}
+
+	return manifests, nil
+}
+
+// packageManifestsFromRegistry returns a list of PackageManifests served by a grpc catalog source's registry
+func (m *InMemoryProvider) packageManifestsFromRegistry(catsrc *operatorsv1alpha1.CatalogSource) ([]packagev1alpha1.PackageManifest, error) {
+	source, err := m.registrySource(catsrc)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := source.ListPackages()
+	if err != nil {
+		return nil, err
+	}
+	services, err := source.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	return packageManifestsFromCatalog(catsrc, packages, services)
+}
+
+// registrySource returns the connection to a grpc catalog source's registry. The connection is kept until the
+// catalog source's address changes or the catalog source is removed.
+func (m *InMemoryProvider) registrySource(catsrc *operatorsv1alpha1.CatalogSource) (*registry.GRPCSource, error) {
+	key := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
+	m.sourcesLock.Lock()
+	defer m.sourcesLock.Unlock()
+
+	if source, ok := m.registries[key]; ok {
+		if source.Address() == catsrc.Spec.Address {
+			return source, nil
+		}
+		source.Close()
+		delete(m.registries, key)
+	}
+
+	source, err := registry.NewGRPCSource(catsrc.Spec.Address)
+	if err != nil {
+		return nil, err
+	}
+	if m.registries == nil {
+		m.registries = make(map[registry.ResourceKey]*registry.GRPCSource)
+	}
+	m.registries[key] = source
+	return source, nil
+}
+
+// releaseCatalogSource closes the registry connection and forgets the archive kept for a catalog source, except for
+// what its current source type uses. A removed catalog source passes an empty source type to release both.
+func (m *InMemoryProvider) releaseCatalogSource(key registry.ResourceKey, sourceType string) {
+	m.sourcesLock.Lock()
+	defer m.sourcesLock.Unlock()
+
+	if source, ok := m.registries[key]; ok && sourceType != operatorsv1alpha1.SourceTypeGRPC {
+		source.Close()
+		delete(m.registries, key)
+	}
+	if sourceType != operatorsv1alpha1.SourceTypeHTTP {
+		delete(m.archives, key)
+	}
+}
+
+// packageManifestsFromArchive returns a list of PackageManifests from an http catalog source's archive. The archive is
+// only downloaded again if the server reports a new ETag, and false is returned if it didn't change since it was
+// last loaded.
+func (m *InMemoryProvider) packageManifestsFromArchive(catsrc *operatorsv1alpha1.CatalogSource) ([]packagev1alpha1.PackageManifest, bool, error) {
+	key := registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}
+	m.sourcesLock.Lock()
+	previous, ok := m.archives[key]
+	m.sourcesLock.Unlock()
+	if !ok || previous.url != catsrc.Spec.URL {
+		previous = loadedArchive{}
+	}
+
+	archive, err := registry.FetchArchive(catsrc.Spec.URL, previous.etag)
+	if err != nil {
+		return nil, false, err
+	}
+	if archive.NotModified {
+		return nil, false, nil
+	}
+	loaded := loadedArchive{url: catsrc.Spec.URL, etag: archive.ETag, digest: archive.Digest}
+	if previous.digest != "" && archive.Digest == previous.digest {
+		// Keep the new ETag, so that the next poll can skip the download
+		m.recordArchive(key, loaded)
+		return nil, false, nil
+	}
+
+	source, err := registry.NewInMemoryFromArchive(bytes.NewReader(archive.Body))
+	if err != nil {
+		return nil, false, err
+	}
+	services, err := source.ListServices()
+	if err != nil {
+		return nil, false, err
+	}
+	manifests, err := packageManifestsFromCatalog(catsrc, source.AllPackages(), services)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.recordArchive(key, loaded)
+	return manifests, true, nil
+}
+
+func (m *InMemoryProvider) recordArchive(key registry.ResourceKey, archive loadedArchive) {
+	m.sourcesLock.Lock()
+	defer m.sourcesLock.Unlock()
+	if m.archives == nil {
+		m.archives = make(map[registry.ResourceKey]loadedArchive)
+	}
+	m.archives[key] = archive
+}
+
+// packageManifestsFromCatalog returns a list of PackageManifests for a catalog source's packages and CSVs
+func packageManifestsFromCatalog(catsrc *operatorsv1alpha1.CatalogSource, packages map[string]registry.PackageManifest, services []operatorsv1alpha1.ClusterServiceVersion) ([]packagev1alpha1.PackageManifest, error) {
+	csvs := make(map[string]operatorsv1alpha1.ClusterServiceVersion, len(services))
+	for _, csv := range services {
+		csvs[csv.GetName()] = csv
+	}
+	statuses := make([]packagev1alpha1.PackageManifestStatus, 0, len(packages))
+	for _, pkg := range packages {
+		status := packagev1alpha1.PackageManifestStatus{
+			PackageName:        pkg.PackageName,
+			DefaultChannelName: pkg.DefaultChannelName,
+		}
+		for _, channel := range pkg.Channels {
+			status.Channels = append(status.Channels, packagev1alpha1.PackageChannel{
+				Name:           channel.Name,
+				CurrentCSVName: channel.CurrentCSVName,
+			})
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].PackageName < statuses[j].PackageName
+	})
 
-			// add the name and namespace of the CatalogSource
-			manifest := packagev1alpha1.PackageManifest{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      status.PackageName,
-					Namespace: cm.GetNamespace(),
-					Labels:    map[string]string{},
-				},
-				Status: status,
+	return newPackageManifests(statuses, csvs, catsrc.GetNamespace(), catsrc.GetName(), catsrc.GetNamespace())
+}
+
+// newPackageManifests returns PackageManifests for the given package statuses, describing each channel with its
+// current CSV from csvs
+func newPackageManifests(statuses []packagev1alpha1.PackageManifestStatus, csvs map[string]operatorsv1alpha1.ClusterServiceVersion, namespace, catalogSourceName, catalogSourceNamespace string) ([]packagev1alpha1.PackageManifest, error) {
+	manifests := []packagev1alpha1.PackageManifest{}
+	for _, status := range statuses {
+		// add the name and namespace of the CatalogSource
+		manifest := packagev1alpha1.PackageManifest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      status.PackageName,
+				Namespace: namespace,
+				Labels:    map[string]string{},
+			},
+			Status: status,
+		}
+
+		manifest.Status.CatalogSourceName = catalogSourceName
+		manifest.Status.CatalogSourceNamespace = catalogSourceNamespace
+
+		// add all PackageChannel CSVDescriptions
+		for i, channel := range manifest.Status.Channels {
+			csv, ok := csvs[channel.CurrentCSVName]
+			if !ok {
+				return nil, fmt.Errorf("packagemanifest %s references non-existent csv %s", manifest.Status.PackageName, channel.CurrentCSVName)
 			}
 
-			manifest.Status.CatalogSourceName = catalogSourceName
-			manifest.Status.CatalogSourceNamespace = catalogSourceNamespace
+			manifest.Status.Channels[i].CurrentCSVDesc = packagev1alpha1.CreateCSVDescription(&csv)
 
-			// add all PackageChannel CSVDescriptions
-			for i, channel := range manifest.Status.Channels {
-				csv, ok := csvs[channel.CurrentCSVName]
-				if !ok {
-					return nil, fmt.Errorf("packagemanifest %s references non-existent csv %s", manifest.Status.PackageName, channel.CurrentCSVName)
+			// set the Provider
+			if manifest.Status.DefaultChannelName != "" && csv.GetName() == manifest.Status.DefaultChannelName || i == 0 {
+				manifest.Status.Provider = packagev1alpha1.AppLink{
+					Name: csv.Spec.Provider.Name,
+					URL:  csv.Spec.Provider.URL,
 				}
 
-				manifest.Status.Channels[i].CurrentCSVDesc = packagev1alpha1.CreateCSVDescription(&csv)
+				// add Provider as a label
+				manifest.ObjectMeta.Labels["provider"] = manifest.Status.Provider.Name
+				manifest.ObjectMeta.Labels["provider-url"] = manifest.Status.Provider.URL
+			}
+		}
 
-				// set the Provider
-				if manifest.Status.DefaultChannelName != "" && csv.GetName() == manifest.Status.DefaultChannelName || i == 0 {
-					manifest.Status.Provider = packagev1alpha1.AppLink{
-						Name: csv.Spec.Provider.Name,
-						URL:  csv.Spec.Provider.URL,
-					}
+		// set CatalogSource labels
+		manifest.ObjectMeta.Labels["catalog"] = manifest.Status.CatalogSourceName
+		manifest.ObjectMeta.Labels["catalog-namespace"] = manifest.Status.CatalogSourceNamespace
 
-					// add Provider as a label
-					manifest.ObjectMeta.Labels["provider"] = manifest.Status.Provider.Name
-					manifest.ObjectMeta.Labels["provider-url"] = manifest.Status.Provider.URL
-				}
+		log.Debugf("retrieved packagemanifest %s", manifest.GetName())
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// enqueueFunc returns a func that adds an object's key to the queue
+func enqueueFunc(queue workqueue.RateLimitingInterface) func(obj interface{}) {
+	return func(obj interface{}) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			log.Infof("creating key failed: %s", err)
+			return
+		}
+		queue.Add(key)
+	}
+}
+
+// catalogSourceEventHandlers queues catalog sources like the default handlers. A deleted catalog source can't be
+// synced from the queue, so its packages are removed right away.
+func (m *InMemoryProvider) catalogSourceEventHandlers(queue workqueue.RateLimitingInterface) *cache.ResourceEventHandlerFuncs {
+	enqueue := enqueueFunc(queue)
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enqueue(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			catsrc, ok := obj.(*operatorsv1alpha1.CatalogSource)
+			if !ok {
+				log.Debugf("wrong type: %#v", obj)
+				return
 			}
+			queue.Forget(fmt.Sprintf("%s/%s", catsrc.GetNamespace(), catsrc.GetName()))
+			m.removeCatalogSource(catsrc)
+		},
+	}
+}
 
-			// set CatalogSource labels
-			manifest.ObjectMeta.Labels["catalog"] = manifest.Status.CatalogSourceName
-			manifest.ObjectMeta.Labels["catalog-namespace"] = manifest.Status.CatalogSourceNamespace
+// removeCatalogSource removes the packages of a deleted catalog source and notifies watchers
+func (m *InMemoryProvider) removeCatalogSource(catsrc *operatorsv1alpha1.CatalogSource) {
+	m.releaseCatalogSource(registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}, "")
 
-			log.Debugf("retrieved packagemanifest %s", manifest.GetName())
-			manifests = append(manifests, manifest)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, manifest := range m.manifests {
+		if key.catalogSourceName != catsrc.GetName() || key.catalogSourceNamespace != catsrc.GetNamespace() {
+			continue
+		}
+		delete(m.manifests, key)
+		log.Debugf("removed packagemanifest %s of deleted catalog source %s", manifest.GetName(), catsrc.GetName())
+		for _, del := range m.delete {
+			if del.namespace == manifest.Status.CatalogSourceNamespace || del.namespace == metav1.NamespaceAll || manifest.Status.CatalogSourceNamespace == m.globalNamespace {
+				del.ch <- manifest
+			}
 		}
 	}
+}
 
-	if !found {
-		logger.Debug("ERROR: No valid resource found")
-		return nil, fmt.Errorf("error parsing ConfigMap %s: no valid resources found", cmName)
+// configMapEventHandlers queues changed ConfigMaps, skipping resyncs. A deleted ConfigMap can't be synced from the
+// queue, so the catalog sources that load it are requeued right away.
+func (m *InMemoryProvider) configMapEventHandlers(queue workqueue.RateLimitingInterface) *cache.ResourceEventHandlerFuncs {
+	enqueue := enqueueFunc(queue)
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldConfigMap, ok := oldObj.(*corev1.ConfigMap)
+			if ok && oldConfigMap.GetResourceVersion() == newObj.(*corev1.ConfigMap).GetResourceVersion() {
+				return
+			}
+			enqueue(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				log.Debugf("wrong type: %#v", obj)
+				return
+			}
+			m.requeueCatalogSourcesForConfigMap(cm)
+		},
 	}
+}
 
-	return manifests, nil
+func (m *InMemoryProvider) syncConfigMap(obj interface{}) error {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		log.Debugf("wrong type: %#v", obj)
+		return fmt.Errorf("casting config map failed")
+	}
+	m.requeueCatalogSourcesForConfigMap(cm)
+	return nil
+}
+
+// requeueCatalogSourcesForConfigMap requeues the internal catalog sources that are loaded from the given ConfigMap
+func (m *InMemoryProvider) requeueCatalogSourcesForConfigMap(cm *corev1.ConfigMap) {
+	for _, informer := range m.catsrcInformers {
+		for _, obj := range informer.GetStore().List() {
+			catsrc, ok := obj.(*operatorsv1alpha1.CatalogSource)
+			if !ok || catsrc.GetNamespace() != cm.GetNamespace() {
+				continue
+			}
+			if catsrc.Spec.SourceType != operatorsv1alpha1.SourceTypeInternal || catsrc.Spec.ConfigMap != cm.GetName() {
+				continue
+			}
+			log.Debugf("requeueing catalog source %s for changes to config map %s", catsrc.GetName(), cm.GetName())
+			m.catsrcQueue.Add(fmt.Sprintf("%s/%s", catsrc.GetNamespace(), catsrc.GetName()))
+		}
+	}
 }
 
 func (m *InMemoryProvider) syncCatalogSource(obj interface{}) error {
@@ -192,9 +489,12 @@ func (m *InMemoryProvider) syncCatalogSource(obj interface{}) error {
 
 	var manifests []packagev1alpha1.PackageManifest
 
+	// Drop the connection or archive of a catalog source whose type changed
+	m.releaseCatalogSource(registry.ResourceKey{Name: catsrc.GetName(), Namespace: catsrc.GetNamespace()}, catsrc.Spec.SourceType)
+
 	// handle by sourceType
 	switch catsrc.Spec.SourceType {
-	case "internal":
+	case operatorsv1alpha1.SourceTypeInternal:
 		// get the CatalogSource's ConfigMap
 		cm, err := m.OpClient.KubernetesInterface().CoreV1().ConfigMaps(catsrc.GetNamespace()).Get(catsrc.Spec.ConfigMap, metav1.GetOptions{})
 		if err != nil {
@@ -207,6 +507,24 @@ func (m *InMemoryProvider) syncCatalogSource(obj interface{}) error {
 			return fmt.Errorf("failed to load package manifest from config map %s", cm.GetName())
 		}
 
+	case operatorsv1alpha1.SourceTypeGRPC:
+		var err error
+		manifests, err = m.packageManifestsFromRegistry(catsrc)
+		if err != nil {
+			return fmt.Errorf("failed to load package manifests from registry at %s: %s", catsrc.Spec.Address, err)
+		}
+
+	case operatorsv1alpha1.SourceTypeHTTP:
+		var changed bool
+		var err error
+		manifests, changed, err = m.packageManifestsFromArchive(catsrc)
+		if err != nil {
+			return fmt.Errorf("failed to load package manifests from archive %s: %s", catsrc.Spec.URL, err)
+		}
+		if !changed {
+			return nil
+		}
+
 	default:
 		return fmt.Errorf("catalog source %s in namespace %s source type %s not recognized", catsrc.GetName(), catsrc.GetNamespace(), catsrc.Spec.SourceType)
 	}
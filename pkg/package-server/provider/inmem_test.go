@@ -1,11 +1,28 @@
 package provider
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 
+	operatorsv1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/clientset/versioned/fake"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/informers/externalversions"
+	"github.com/operator-framework/operator-lifecycle-manager/pkg/controller/registry"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/queueinformer"
 	packagev1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/package-server/apis/packagemanifest/v1alpha1"
 )
@@ -208,3 +225,270 @@ func TestSubscribe(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveCatalogSource(t *testing.T) {
+	manifest := func(name, catalogSourceName, catalogSourceNamespace string) packagev1alpha1.PackageManifest {
+		return packagev1alpha1.PackageManifest{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: catalogSourceNamespace},
+			Status: packagev1alpha1.PackageManifestStatus{
+				CatalogSourceName:      catalogSourceName,
+				CatalogSourceNamespace: catalogSourceNamespace,
+				PackageName:            name,
+			},
+		}
+	}
+	storedPackages := map[packageKey]packagev1alpha1.PackageManifest{}
+	for _, m := range []packagev1alpha1.PackageManifest{
+		manifest("etcd", "deleted", "default"),
+		manifest("prometheus", "deleted", "default"),
+		manifest("vault", "kept", "default"),
+		manifest("etcd", "deleted", "local"),
+	} {
+		storedPackages[packageKey{catalogSourceName: m.Status.CatalogSourceName, catalogSourceNamespace: m.Status.CatalogSourceNamespace, packageName: m.GetName()}] = m
+	}
+	prov := &InMemoryProvider{
+		Operator:        &queueinformer.Operator{},
+		manifests:       storedPackages,
+		globalNamespace: "global",
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	_, _, deleted, err := prov.Subscribe("default", stopCh)
+	require.NoError(t, err)
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources")
+	catsrc := &operatorsv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}}
+	go prov.catalogSourceEventHandlers(queue).OnDelete(cache.DeletedFinalStateUnknown{Key: "default/deleted", Obj: catsrc})
+
+	events := []packagev1alpha1.PackageManifest{<-deleted, <-deleted}
+	require.ElementsMatch(t, []packagev1alpha1.PackageManifest{
+		manifest("etcd", "deleted", "default"),
+		manifest("prometheus", "deleted", "default"),
+	}, events)
+
+	manifests, err := prov.List(metav1.NamespaceAll)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []packagev1alpha1.PackageManifest{
+		manifest("vault", "kept", "default"),
+		manifest("etcd", "deleted", "local"),
+	}, manifests.Items)
+}
+
+func TestConfigMapEventHandlers(t *testing.T) {
+	catalogSource := func(name, namespace, sourceType, configMap string) *operatorsv1alpha1.CatalogSource {
+		return &operatorsv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       operatorsv1alpha1.CatalogSourceSpec{SourceType: sourceType, ConfigMap: configMap},
+		}
+	}
+	informer := externalversions.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Operators().V1alpha1().CatalogSources().Informer()
+	for _, catsrc := range []*operatorsv1alpha1.CatalogSource{
+		catalogSource("internal", "default", operatorsv1alpha1.SourceTypeInternal, "catalog"),
+		catalogSource("grpc", "default", operatorsv1alpha1.SourceTypeGRPC, "catalog"),
+		catalogSource("other", "default", operatorsv1alpha1.SourceTypeInternal, "other"),
+		catalogSource("elsewhere", "local", operatorsv1alpha1.SourceTypeInternal, "catalog"),
+	} {
+		require.NoError(t, informer.GetStore().Add(catsrc))
+	}
+
+	prov := &InMemoryProvider{
+		Operator:        &queueinformer.Operator{},
+		catsrcInformers: []cache.SharedIndexInformer{informer},
+		catsrcQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "catalogsources"),
+	}
+	requeued := func() []string {
+		keys := []string{}
+		for prov.catsrcQueue.Len() > 0 {
+			key, _ := prov.catsrcQueue.Get()
+			keys = append(keys, key.(string))
+			prov.catsrcQueue.Done(key)
+		}
+		return keys
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: "default", ResourceVersion: "1"}}
+
+	require.NoError(t, prov.syncConfigMap(cm))
+	require.Equal(t, []string{"default/internal"}, requeued())
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmaps")
+	handlers := prov.configMapEventHandlers(queue)
+	handlers.OnDelete(cm)
+	require.Equal(t, []string{"default/internal"}, requeued())
+
+	handlers.OnUpdate(cm, cm.DeepCopy())
+	require.Equal(t, 0, queue.Len())
+	changed := cm.DeepCopy()
+	changed.ResourceVersion = "2"
+	handlers.OnUpdate(cm, changed)
+	require.Equal(t, 1, queue.Len())
+}
+
+func TestPackageManifestsFromRegistry(t *testing.T) {
+	catalog, err := registry.NewInMemoryFromDirectory("../../../deploy/chart/catalog_resources/rh-operators")
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	registry.RegisterRegistryServer(server, catalog)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	catsrc := &operatorsv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+		Spec: operatorsv1alpha1.CatalogSourceSpec{
+			SourceType: operatorsv1alpha1.SourceTypeGRPC,
+			Address:    lis.Addr().String(),
+		},
+	}
+	prov := &InMemoryProvider{
+		Operator: &queueinformer.Operator{},
+	}
+	manifests, err := prov.packageManifestsFromRegistry(catsrc)
+	require.NoError(t, err)
+	require.Len(t, manifests, len(catalog.AllPackages()))
+
+	var etcd *packagev1alpha1.PackageManifest
+	for i, manifest := range manifests {
+		require.Equal(t, "ns", manifest.GetNamespace())
+		require.Equal(t, "registry", manifest.Labels["catalog"])
+		if manifest.GetName() == "etcd" {
+			etcd = &manifests[i]
+		}
+	}
+	require.NotNil(t, etcd, "etcd package not found")
+	require.Equal(t, "registry", etcd.Status.CatalogSourceName)
+	require.Equal(t, "ns", etcd.Status.CatalogSourceNamespace)
+	require.Len(t, etcd.Status.Channels, 1)
+	require.Equal(t, "etcdoperator.v0.9.2", etcd.Status.Channels[0].CurrentCSVName)
+	require.Equal(t, "0.9.2", etcd.Status.Channels[0].CurrentCSVDesc.Version.String())
+
+	// The connection is reused by later syncs, and closed when the catalog source is removed
+	key := registry.ResourceKey{Name: "registry", Namespace: "ns"}
+	source := prov.registries[key]
+	require.NotNil(t, source)
+	_, err = prov.packageManifestsFromRegistry(catsrc)
+	require.NoError(t, err)
+	require.True(t, source == prov.registries[key], "registry connection not reused")
+
+	prov.removeCatalogSource(catsrc)
+	require.Empty(t, prov.registries)
+}
+
+// archiveDirectory returns a gzipped tar archive of a catalog directory
+func archiveDirectory(t *testing.T, directory string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(directory, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tw.Write(contents)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestPackageManifestsFromArchive(t *testing.T) {
+	rhOperators := archiveDirectory(t, "../../../deploy/chart/catalog_resources/rh-operators")
+	certifiedOperators := archiveDirectory(t, "../../../deploy/chart/catalog_resources/certified-operators")
+
+	var archive []byte
+	var etag string
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	catsrc := &operatorsv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: "ns"},
+		Spec: operatorsv1alpha1.CatalogSourceSpec{
+			SourceType: operatorsv1alpha1.SourceTypeHTTP,
+			URL:        server.URL,
+		},
+	}
+	prov := &InMemoryProvider{
+		Operator: &queueinformer.Operator{},
+	}
+
+	tests := []struct {
+		name              string
+		archive           []byte
+		etag              string
+		expectedChanged   bool
+		expectedDownloads int
+	}{
+		{
+			name:              "FirstLoad",
+			archive:           rhOperators,
+			etag:              "v1",
+			expectedChanged:   true,
+			expectedDownloads: 1,
+		},
+		{
+			name:              "NotModified",
+			archive:           rhOperators,
+			etag:              "v1",
+			expectedChanged:   false,
+			expectedDownloads: 1,
+		},
+		{
+			name:              "NewETagSameContent",
+			archive:           rhOperators,
+			etag:              "v2",
+			expectedChanged:   false,
+			expectedDownloads: 2,
+		},
+		{
+			name:              "NewETagKeptAfterSameContent",
+			archive:           rhOperators,
+			etag:              "v2",
+			expectedChanged:   false,
+			expectedDownloads: 2,
+		},
+		{
+			name:              "ContentChanged",
+			archive:           certifiedOperators,
+			etag:              "v3",
+			expectedChanged:   true,
+			expectedDownloads: 3,
+		},
+	}
+	// The cases run in order against the same catalog source
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, etag = tt.archive, tt.etag
+			manifests, changed, err := prov.packageManifestsFromArchive(catsrc)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedChanged, changed)
+			require.Equal(t, tt.expectedChanged, len(manifests) > 0)
+			require.Equal(t, tt.expectedDownloads, downloads)
+		})
+	}
+
+	prov.removeCatalogSource(catsrc)
+	require.Empty(t, prov.archives)
+}
@@ -178,7 +178,13 @@ func (o *PackageServerOptions) Run(stopCh <-chan struct{}) error {
 		return err
 	}
 
-	sourceProvider := provider.NewInMemoryProvider(catsrcSharedIndexInformers, queueOperator, o.GlobalNamespace)
+	configMapSharedIndexInformers := []cache.SharedIndexInformer{}
+	for _, namespace := range o.WatchedNamespaces {
+		nsInformerFactory := informers.NewSharedInformerFactoryWithOptions(queueOperator.OpClient.KubernetesInterface(), o.WakeupInterval, informers.WithNamespace(namespace))
+		configMapSharedIndexInformers = append(configMapSharedIndexInformers, nsInformerFactory.Core().V1().ConfigMaps().Informer())
+	}
+
+	sourceProvider := provider.NewInMemoryProvider(catsrcSharedIndexInformers, configMapSharedIndexInformers, queueOperator, o.GlobalNamespace)
 	config.ProviderConfig.Provider = sourceProvider
 	// we should never need to resync, since we're not worried about missing events,
 	// and resync is actually for regular interval-based reconciliation these days,
@@ -195,7 +195,7 @@ func VerifyCatalogVersions(t *testing.T, manifestDir string) {
 	}
 
 	// Ensure all resources are resolvable for all catalogs of each version
-	multiSourceResolver := resolver.MultiSourceResolver{}
+	constraintResolver := resolver.ConstraintResolver{}
 	for version, catalogs := range catalogVersionBundles {
 		// capture range variables in lexical scope
 		c := catalogs
@@ -204,7 +204,7 @@ func VerifyCatalogVersions(t *testing.T, manifestDir string) {
 		t.Run(testName, func(t *testing.T) {
 			t.Parallel()
 			t.Logf("Resolving resources for catalogs in version %s...", v)
-			err := resolveCatalogs(t, c, &multiSourceResolver)
+			err := resolveCatalogs(t, c, &constraintResolver)
 			require.NoError(t, err)
 		})
 	}